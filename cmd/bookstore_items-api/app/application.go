@@ -5,26 +5,119 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/blob"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/publisher"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/config"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/controllers"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/reservations"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/gorilla/mux"
 )
 
+const timeoutMessage = `{"message": "request timeout", "status": 503, "error": "service_unavailable"}`
+
 type Application struct {
-	config *config.Config
-	router *mux.Router
-	items  controllers.ItemControllerInterface
+	config              *config.Config
+	router              *mux.Router
+	items               controllers.ItemControllerInterface
+	reservations        controllers.ReservationControllerInterface
+	pictures            controllers.PictureControllerInterface
+	reservationsService services.ReservationsServiceInterface
+	eventsService       services.EventsServiceInterface
 }
 
 func NewApp(appConf *config.Config,
-	itemsController controllers.ItemControllerInterface) *Application {
+	itemsController controllers.ItemControllerInterface,
+	reservationsController controllers.ReservationControllerInterface,
+	picturesController controllers.PictureControllerInterface,
+	reservationsService services.ReservationsServiceInterface,
+	eventsService services.EventsServiceInterface) *Application {
 	return &Application{
-		config: appConf,
-		router: mux.NewRouter(),
-		items:  itemsController,
+		config:              appConf,
+		router:              mux.NewRouter(),
+		items:               itemsController,
+		reservations:        reservationsController,
+		pictures:            picturesController,
+		reservationsService: reservationsService,
+		eventsService:       eventsService,
+	}
+}
+
+func ProvideItemsPersister(appConf *config.Config) items.ItemsPersistInterface {
+	if appConf.Storage.Type == config.StorageMemory {
+		return items.NewMemoryPersister()
+	}
+	return items.NewItemPersister(appConf.Search.SuggestTimeout)
+}
+
+func ProvideItemsService(appConf *config.Config, itemsPersist items.ItemsPersistInterface,
+	outbox events.OutboxPersistInterface, store blob.StoreInterface) services.ItemsServiceInterface {
+	boosts := queries.TextBoosts{
+		Title:       appConf.Search.TitleBoost,
+		Description: appConf.Search.DescriptionBoost,
+		Authors:     appConf.Search.AuthorsBoost,
+	}
+	return services.NewItemsService(itemsPersist, outbox, store, boosts, appConf.Search.RelatedSize)
+}
+
+func ProvideReservationsPersister(appConf *config.Config) reservations.ReservationsPersistInterface {
+	if appConf.Storage.Type == config.StorageMemory {
+		return reservations.NewMemoryPersister()
+	}
+	return reservations.NewReservationPersister()
+}
+
+func ProvideReservationsService(appConf *config.Config,
+	itemsPersist items.ItemsPersistInterface,
+	reservationsPersist reservations.ReservationsPersistInterface) services.ReservationsServiceInterface {
+	return services.NewReservationsService(itemsPersist, reservationsPersist, appConf.Reservations.TTL)
+}
+
+func ProvideBlobStore(appConf *config.Config) blob.StoreInterface {
+	store, err := blob.NewLocalStore(appConf.Pictures.Dir)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+func ProvidePicturesService(appConf *config.Config,
+	itemsPersist items.ItemsPersistInterface,
+	store blob.StoreInterface) services.PicturesServiceInterface {
+	return services.NewPicturesService(itemsPersist, store,
+		appConf.Pictures.BaseURL, appConf.Pictures.MaxSize, appConf.Pictures.ThumbnailSize)
+}
+
+func ProvideOutbox(appConf *config.Config) events.OutboxPersistInterface {
+	if appConf.Storage.Type == config.StorageMemory {
+		return events.NewMemoryOutbox()
+	}
+	return events.NewOutboxPersister()
+}
+
+func ProvidePublisher(appConf *config.Config) publisher.PublisherInterface {
+	if appConf.Events.Publisher == config.PublisherWebhook {
+		client := &http.Client{Timeout: appConf.Events.WebhookTimeout}
+		return publisher.NewWebhookPublisher(client, appConf.Events.WebhookURL, appConf.Events.WebhookSecret)
+	}
+	p, err := publisher.NewFilePublisher(appConf.Events.File)
+	if err != nil {
+		panic(err)
 	}
+	return p
+}
+
+func ProvideEventsService(appConf *config.Config,
+	outbox events.OutboxPersistInterface,
+	itemsPersist items.ItemsPersistInterface,
+	p publisher.PublisherInterface) services.EventsServiceInterface {
+	return services.NewEventsService(outbox, itemsPersist, p, appConf.Events.BatchSize)
 }
 
 func ProvideOAuthClient(httpClient oauth.HttpClientInterface, appConf *config.Config) *oauth.OAuthClient {
@@ -33,20 +126,56 @@ func ProvideOAuthClient(httpClient oauth.HttpClientInterface, appConf *config.Co
 
 func (app *Application) StartApp() {
 
-	es.Init()
+	if app.config.Storage.Type != config.StorageMemory {
+		es.Init()
+		if err := items.EnsureIndex(); err != nil {
+			panic(err)
+		}
+		if err := items.EnsureClaimsIndex(); err != nil {
+			panic(err)
+		}
+		if err := reservations.EnsureIndex(); err != nil {
+			panic(err)
+		}
+		if err := events.EnsureIndex(); err != nil {
+			panic(err)
+		}
+	}
+
+	go app.releaseExpiredReservations()
+	go app.dispatchEvents()
 
-	app.router.HandleFunc("/ping", app.items.Ping).Methods(http.MethodGet)
+	app.router.Handle("/ping", app.timeout(app.items.Ping)).Methods(http.MethodGet)
 	app.router.HandleFunc("/items", app.items.Create).Methods(http.MethodPost)
-	app.router.HandleFunc("/items/{id}", app.items.Get).Methods(http.MethodGet)
-	app.router.HandleFunc("/items/search", app.items.Search).Methods(http.MethodPost)
+	app.router.HandleFunc("/items/import", app.items.Import).Methods(http.MethodPost)
+	app.router.HandleFunc("/items/export", app.items.Export).Methods(http.MethodGet)
+	app.router.Handle("/items/suggest", app.timeout(app.items.Suggest)).Methods(http.MethodGet)
+	app.router.Handle("/items/{id}", app.timeout(app.items.Get)).Methods(http.MethodGet)
+	app.router.HandleFunc("/items/{id}", app.items.Update).Methods(http.MethodPut, http.MethodPatch)
+	app.router.HandleFunc("/items/{id}", app.items.Delete).Methods(http.MethodDelete)
+	app.router.HandleFunc("/items/{id}/status", app.items.Transition).Methods(http.MethodPost)
+	app.router.Handle("/items/{id}/related", app.timeout(app.items.Related)).Methods(http.MethodGet)
+	app.router.HandleFunc("/items/{id}/pictures", app.pictures.Upload).Methods(http.MethodPost)
+	app.router.HandleFunc("/items/{id}/pictures", app.pictures.Reorder).Methods(http.MethodPut)
+	app.router.Handle("/items/{id}/pictures/{picture_id}", app.timeout(app.pictures.Get)).Methods(http.MethodGet)
+	app.router.HandleFunc("/items/{id}/pictures/{picture_id}", app.pictures.Delete).Methods(http.MethodDelete)
+	app.router.Handle("/items/{id}/pictures/{picture_id}/thumbnail", app.timeout(app.pictures.Thumbnail)).Methods(http.MethodGet)
+	app.router.Handle("/items/search", app.timeout(app.items.Search)).Methods(http.MethodPost)
+	app.router.Handle("/sellers/{id}/items", app.timeout(app.items.SellerItems)).Methods(http.MethodGet)
+	app.router.HandleFunc("/items/{id}/reservations", app.reservations.Reserve).Methods(http.MethodPost)
+	app.router.HandleFunc("/items/{id}/reservations/{reservation_id}/commit", app.reservations.Commit).Methods(http.MethodPost)
+	app.router.HandleFunc("/items/{id}/reservations/{reservation_id}", app.reservations.Release).Methods(http.MethodDelete)
 
+	// the server timeouts leave minutes to the bulk routes and the picture uploads. The reads are bounded
+	// by app.timeout, the writes are not : a 503 would not stop the write going on behind it
 	listenAddress := fmt.Sprintf("%s:%s", app.config.Server.Host, app.config.Server.Port)
 	srv := http.Server{
-		Addr:         listenAddress,
-		Handler:      app.router,
-		WriteTimeout: 200 * time.Millisecond,
-		ReadTimeout:  20 * time.Millisecond,
-		IdleTimeout:  10 * time.Millisecond,
+		Addr:              listenAddress,
+		Handler:           app.router,
+		ReadHeaderTimeout: app.config.Server.ReadTimeout,
+		ReadTimeout:       app.config.Server.BulkTimeout,
+		WriteTimeout:      app.config.Server.BulkTimeout,
+		IdleTimeout:       app.config.Server.IdleTimeout,
 	}
 
 	fmt.Println("listening on ", listenAddress)
@@ -54,3 +183,38 @@ func (app *Application) StartApp() {
 		panic(err)
 	}
 }
+
+// timeout answers 503 when the handler runs longer than the write timeout, the response is buffered meanwhile.
+// The handler keeps running after the 503, only the routes without side effects are wrapped
+func (app *Application) timeout(handler http.HandlerFunc) http.Handler {
+	return http.TimeoutHandler(handler, app.config.Server.WriteTimeout, timeoutMessage)
+}
+
+// releaseExpiredReservations returns the stock of abandoned reservations
+func (app *Application) releaseExpiredReservations() {
+	ticker := time.NewTicker(app.config.Reservations.SweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		released, err := app.reservationsService.ReleaseExpired()
+		if err != nil {
+			logger.Error("release expired reservations error", err)
+			continue
+		}
+		if released > 0 {
+			logger.Info(fmt.Sprintf("released %d expired reservations", released))
+		}
+	}
+}
+
+// dispatchEvents publishes the recorded item events
+func (app *Application) dispatchEvents() {
+	ticker := time.NewTicker(app.config.Events.DispatchInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := app.eventsService.Dispatch(); err != nil {
+			logger.Error("dispatch item events error", err)
+		}
+	}
+}
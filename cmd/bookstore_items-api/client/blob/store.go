@@ -0,0 +1,109 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+)
+
+// ErrNotFound is returned when there is no blob with the key
+var ErrNotFound = errors.New("blob not found")
+
+// StoreInterface keeps binary objects by slash separated keys
+type StoreInterface interface {
+	Put(string, io.Reader) error
+	Get(string) (io.ReadCloser, error)
+	Delete(string) error
+}
+
+// localStore keeps blobs as files under the root directory
+type localStore struct {
+	root string
+}
+
+func NewLocalStore(root string) (StoreInterface, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &localStore{root: root}, nil
+}
+
+func (s *localStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", errors.New("invalid blob key")
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", errors.New("invalid blob key")
+		}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes into a temporary file renamed at the end, readers never see a partial blob
+func (s *localStore) Put(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.Error("create blob directory error", err)
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		logger.Error("create blob error", err)
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		logger.Error("write blob error", err)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		logger.Error("write blob error", err)
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		logger.Error("write blob error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *localStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		logger.Error("read blob error", err)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete removes the blob, a missing blob is not an error
+func (s *localStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Error("delete blob error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package blob
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Put("items/1/2", strings.NewReader("picture")))
+
+	r, err := store.Get("items/1/2")
+	assert.Nil(t, err)
+	content, _ := ioutil.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "picture", string(content))
+
+	assert.Nil(t, store.Delete("items/1/2"))
+	assert.Nil(t, store.Delete("items/1/2"))
+
+	_, err = store.Get("items/1/2")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", "/etc/passwd", "../x", "items/../../x", "items//x"} {
+		assert.NotNil(t, store.Put(key, strings.NewReader("x")), key)
+		_, err := store.Get(key)
+		assert.NotNil(t, err, key)
+	}
+}
@@ -2,6 +2,7 @@ package es
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
@@ -12,11 +13,35 @@ var (
 	Client esClientInterface = &esClient{}
 )
 
+// time a scroll context is kept between pages
+const scrollKeepAlive = "1m"
+
+// DocVersion is a document revision for optimistic concurrency control,
+// a write with a stale version fails with a version conflict
+type DocVersion struct {
+	SeqNo       int64
+	PrimaryTerm int64
+}
+
 type esClientInterface interface {
 	SetClient(*elastic.Client)
 	Index(string, string, interface{}) (*elastic.IndexResponse, error)
+	Create(string, string, string, interface{}) (*elastic.IndexResponse, error)
 	Get(string, string, string) (*elastic.GetResult, error)
-	Search(string, elastic.Query) (*elastic.SearchResult, error)
+	Search(string, *elastic.SearchSource) (*elastic.SearchResult, error)
+	Update(string, string, string, interface{}, *DocVersion) (*elastic.IndexResponse, error)
+	Delete(string, string, string, *DocVersion) (*elastic.DeleteResponse, error)
+	IndexExists(string) (bool, error)
+	CreateIndex(string, string) error
+	MappingMeta(string, string) (map[string]interface{}, error)
+	AliasIndices(string) ([]string, error)
+	UpdateAliases(...elastic.AliasAction) error
+	BlockWrites([]string, bool) error
+	Reindex(string, string, *elastic.Script) (*elastic.BulkIndexByScrollResponse, error)
+	Bulk(...elastic.BulkableRequest) (*elastic.BulkResponse, error)
+	Scroll(string, *elastic.SearchSource, string) (*elastic.SearchResult, error)
+	ClearScroll(string) error
+	Suggest(string, time.Duration, ...elastic.Suggester) (elastic.SearchSuggest, error)
 }
 
 type esClient struct {
@@ -53,6 +78,22 @@ func (es *esClient) Index(index string, docType string, doc interface{}) (*elast
 	return result, nil
 }
 
+// Create indexes the document under the id, it fails with a conflict when the id is taken
+func (es *esClient) Create(index string, docType string, id string, doc interface{}) (*elastic.IndexResponse, error) {
+	result, err := es.client.Index().
+		Index(index).
+		Type(docType).
+		Id(id).
+		OpType("create").
+		BodyJson(doc).
+		Do(context.Background())
+	if err != nil {
+		logger.Error("create document error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
 func (es esClient) Get(index string, docType string, id string) (*elastic.GetResult, error) {
 	ctx := context.Background()
 	result, err := es.client.Get().
@@ -67,10 +108,10 @@ func (es esClient) Get(index string, docType string, id string) (*elastic.GetRes
 	return result, nil
 }
 
-func (es esClient) Search(index string, q elastic.Query) (*elastic.SearchResult, error) {
+func (es esClient) Search(index string, source *elastic.SearchSource) (*elastic.SearchResult, error) {
 	ctx := context.Background()
 	result, err := es.client.Search(index).
-		Query(q).
+		SearchSource(source).
 		RestTotalHitsAsInt(true).
 		Do(ctx)
 	if err != nil {
@@ -79,3 +120,195 @@ func (es esClient) Search(index string, q elastic.Query) (*elastic.SearchResult,
 	}
 	return result, nil
 }
+
+// Update replaces the document, a nil version overwrites unconditionally
+func (es *esClient) Update(index string, docType string, id string, doc interface{}, version *DocVersion) (*elastic.IndexResponse, error) {
+	service := es.client.Index().
+		Index(index).
+		Type(docType).
+		Id(id).
+		BodyJson(doc)
+	if version != nil {
+		service = service.IfSeqNo(version.SeqNo).IfPrimaryTerm(version.PrimaryTerm)
+	}
+	result, err := service.Do(context.Background())
+	if err != nil {
+		logger.Error("update document error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (es *esClient) Delete(index string, docType string, id string, version *DocVersion) (*elastic.DeleteResponse, error) {
+	service := es.client.Delete().
+		Index(index).
+		Type(docType).
+		Id(id)
+	if version != nil {
+		service = service.IfSeqNo(version.SeqNo).IfPrimaryTerm(version.PrimaryTerm)
+	}
+	result, err := service.Do(context.Background())
+	if err != nil {
+		logger.Error("delete document error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (es *esClient) IndexExists(index string) (bool, error) {
+	exists, err := es.client.IndexExists(index).Do(context.Background())
+	if err != nil {
+		logger.Error("index exists error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+func (es *esClient) CreateIndex(index string, body string) error {
+	if _, err := es.client.CreateIndex(index).BodyString(body).Do(context.Background()); err != nil {
+		logger.Error("create index error", err)
+		return err
+	}
+	return nil
+}
+
+// MappingMeta returns the _meta of the index mapping, nil when the mapping has none
+func (es *esClient) MappingMeta(index string, docType string) (map[string]interface{}, error) {
+	result, err := es.client.GetMapping().Index(index).Type(docType).Do(context.Background())
+	if err != nil {
+		logger.Error("get mapping error", err)
+		return nil, err
+	}
+	// the result is keyed by the index name
+	for _, v := range result {
+		idx, _ := v.(map[string]interface{})
+		mappings, _ := idx["mappings"].(map[string]interface{})
+		doc, _ := mappings[docType].(map[string]interface{})
+		meta, _ := doc["_meta"].(map[string]interface{})
+		return meta, nil
+	}
+	return nil, nil
+}
+
+// AliasIndices returns indices behind the alias, empty when there is no such alias
+func (es *esClient) AliasIndices(alias string) ([]string, error) {
+	result, err := es.client.Aliases().Alias(alias).Do(context.Background())
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, nil
+		}
+		logger.Error("get aliases error", err)
+		return nil, err
+	}
+	return result.IndicesByAlias(alias), nil
+}
+
+// UpdateAliases applies alias actions atomically
+func (es *esClient) UpdateAliases(actions ...elastic.AliasAction) error {
+	if _, err := es.client.Alias().Action(actions...).Do(context.Background()); err != nil {
+		logger.Error("update aliases error", err)
+		return err
+	}
+	return nil
+}
+
+// BlockWrites makes the indices read only or writable again, searches and reads go on
+func (es *esClient) BlockWrites(indices []string, block bool) error {
+	_, err := es.client.IndexPutSettings(indices...).
+		BodyJson(map[string]interface{}{"index.blocks.write": block}).
+		Do(context.Background())
+	if err != nil {
+		logger.Error("block writes error", err)
+		return err
+	}
+	return nil
+}
+
+// Reindex copies documents keeping their versions, so a repeated run only copies documents changed since.
+// The optional script migrates every copied document
+func (es *esClient) Reindex(source string, dest string, script *elastic.Script) (*elastic.BulkIndexByScrollResponse, error) {
+	service := es.client.Reindex().
+		SourceIndex(source).
+		Destination(elastic.NewReindexDestination().Index(dest).VersionType("external"))
+	if script != nil {
+		service = service.Script(script)
+	}
+	result, err := service.
+		ProceedOnVersionConflict().
+		WaitForCompletion(true).
+		Refresh("true").
+		Do(context.Background())
+	if err != nil {
+		logger.Error("reindex error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// Bulk executes the requests in one round trip, failures are reported per request in the response
+func (es *esClient) Bulk(requests ...elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+	result, err := es.client.Bulk().Add(requests...).Do(context.Background())
+	if err != nil {
+		logger.Error("bulk request error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// Scroll starts a scroll over the search when scrollId is empty and continues it otherwise.
+// io.EOF is returned after the last page
+func (es *esClient) Scroll(index string, source *elastic.SearchSource, scrollId string) (*elastic.SearchResult, error) {
+	result, err := es.client.Scroll(index).
+		SearchSource(source).
+		ScrollId(scrollId).
+		KeepAlive(scrollKeepAlive).
+		Do(context.Background())
+	if err != nil && err != io.EOF {
+		logger.Error("scroll error", err)
+	}
+	return result, err
+}
+
+func (es *esClient) ClearScroll(scrollId string) error {
+	if _, err := es.client.ClearScroll(scrollId).Do(context.Background()); err != nil {
+		logger.Error("clear scroll error", err)
+		return err
+	}
+	return nil
+}
+
+// Suggest runs the suggesters without hits, the request is abandoned once the timeout is over
+func (es *esClient) Suggest(index string, timeout time.Duration, suggesters ...elastic.Suggester) (elastic.SearchSuggest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	source := elastic.NewSearchSource().
+		Size(0).
+		FetchSource(false).
+		TimeoutInMillis(int(timeout / time.Millisecond))
+	for _, suggester := range suggesters {
+		source = source.Suggester(suggester)
+	}
+	result, err := es.client.Search(index).SearchSource(source).Do(ctx)
+	if err != nil {
+		logger.Error("suggest error", err)
+		return nil, err
+	}
+	return result.Suggest, nil
+}
+
+// VersionOf returns the revision of a fetched document, nil when elasticsearch did not report it
+func VersionOf(result *elastic.GetResult) *DocVersion {
+	if result.SeqNo == nil || result.PrimaryTerm == nil {
+		return nil
+	}
+	return &DocVersion{SeqNo: *result.SeqNo, PrimaryTerm: *result.PrimaryTerm}
+}
+
+// HitVersion returns the revision of a search hit, the search must ask for seq_no_primary_term
+func HitVersion(hit *elastic.SearchHit) *DocVersion {
+	if hit.SeqNo == nil || hit.PrimaryTerm == nil {
+		return nil
+	}
+	return &DocVersion{SeqNo: *hit.SeqNo, PrimaryTerm: *hit.PrimaryTerm}
+}
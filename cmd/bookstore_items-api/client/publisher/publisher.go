@@ -0,0 +1,67 @@
+package publisher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PublisherInterface delivers messages to subscribers, a nil error means the message is accepted.
+// A message may be published again after a failure, id tells subscribers it is the same message
+type PublisherInterface interface {
+	Publish(id string, payload []byte) error
+}
+
+// MemoryPublisher keeps the messages in the process memory, tests read them back
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(id string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, append([]byte{}, payload...))
+	return nil
+}
+
+// Messages returns the published messages in the order of publishing
+func (p *MemoryPublisher) Messages() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]byte{}, p.messages...)
+}
+
+// filePublisher appends the messages to a file, one line each
+type filePublisher struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFilePublisher(path string) (PublisherInterface, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &filePublisher{path: path}, nil
+}
+
+func (p *filePublisher) Publish(id string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(append([]byte{}, payload...), '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
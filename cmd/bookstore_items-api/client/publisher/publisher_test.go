@@ -0,0 +1,71 @@
+package publisher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPublisher(t *testing.T) {
+	p := NewMemoryPublisher()
+	payload := []byte(`{"id":"1"}`)
+	assert.Nil(t, p.Publish("1", payload))
+	payload[2] = 'x'
+	assert.Nil(t, p.Publish("2", []byte(`{"id":"2"}`)))
+
+	assert.Equal(t, [][]byte{[]byte(`{"id":"1"}`), []byte(`{"id":"2"}`)}, p.Messages())
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "items.ndjson")
+	p, err := NewFilePublisher(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.Publish("1", []byte(`{"id":"1"}`)))
+	assert.Nil(t, p.Publish("2", []byte(`{"id":"2"}`)))
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", string(content))
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		received = rq
+		body, _ = ioutil.ReadAll(rq.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	p := NewWebhookPublisher(server.Client(), server.URL+"/hooks/items", "secret")
+	payload := []byte(`{"id":"1"}`)
+	assert.Nil(t, p.Publish("1", payload))
+
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "/hooks/items", received.URL.Path)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, "1", received.Header.Get("X-Message-Id"))
+	assert.Equal(t, Sign([]byte("secret"), payload), received.Header.Get("X-Signature"))
+	assert.Equal(t, payload, body)
+
+	status = http.StatusServiceUnavailable
+	assert.NotNil(t, p.Publish("1", payload))
+}
+
+func TestWebhookPublisherUnsigned(t *testing.T) {
+	var signature []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		signature = rq.Header.Values("X-Signature")
+	}))
+	defer server.Close()
+
+	assert.Nil(t, NewWebhookPublisher(server.Client(), server.URL, "").Publish("1", []byte(`{}`)))
+	assert.Empty(t, signature)
+}
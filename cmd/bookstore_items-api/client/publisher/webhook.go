@@ -0,0 +1,71 @@
+package publisher
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	headerMessageId = "X-Message-Id"
+	// hex hmac-sha256 of the body with the shared secret
+	headerSignature = "X-Signature"
+)
+
+type HttpClientInterface interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// webhookPublisher posts the messages as json to the subscriber url
+type webhookPublisher struct {
+	client HttpClientInterface
+	url    string
+	secret []byte
+}
+
+// NewWebhookPublisher posts to url, requests are signed when the secret is set
+func NewWebhookPublisher(client HttpClientInterface, url string, secret string) PublisherInterface {
+	return &webhookPublisher{
+		client: client,
+		url:    url,
+		secret: []byte(secret),
+	}
+}
+
+// Publish fails unless the subscriber answers with a 2xx status
+func (p *webhookPublisher) Publish(id string, payload []byte) error {
+	rq, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	rq.Header.Set(headerMessageId, id)
+	if len(p.secret) > 0 {
+		rq.Header.Set(headerSignature, Sign(p.secret, payload))
+	}
+
+	resp, err := p.client.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drained bodies keep the connection reusable
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %d to message %s", resp.StatusCode, id)
+	}
+	return nil
+}
+
+// Sign returns the signature subscribers compare with the X-Signature header
+func Sign(secret []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
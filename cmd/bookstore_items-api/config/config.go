@@ -1,22 +1,66 @@
 package config
 
 import (
+	"time"
+
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 // Application configuration
 // TODO consider https://github.com/spf13/viper
 
+const (
+	StorageElastic = "elastic"
+	StorageMemory  = "memory"
+
+	PublisherFile    = "file"
+	PublisherWebhook = "webhook"
+)
+
 type Config struct {
+	Storage struct {
+		Type string `yaml:"type" env:"STORAGE_TYPE" env-default:"elastic" env-description:"items storage : elastic or memory"`
+	} `yaml:"storage"`
 	Elastic struct {
 		URL string `yaml:"url" env:"ELK_URL" env-default:"http://127.0.0.1:9200"`
 	} `yaml:"elastic"`
 	OAuth struct {
 		URL string `yaml:"url" env:"OAUT_URL" env-default:"http://127.0.0.1:8082"`
 	} `yaml:"oauth"`
+	Search struct {
+		TitleBoost       float64       `yaml:"title_boost" env:"SEARCH_TITLE_BOOST" env-default:"3" env-description:"weight of the title in free text search"`
+		DescriptionBoost float64       `yaml:"description_boost" env:"SEARCH_DESCRIPTION_BOOST" env-default:"1" env-description:"weight of the description in free text search"`
+		AuthorsBoost     float64       `yaml:"authors_boost" env:"SEARCH_AUTHORS_BOOST" env-default:"2" env-description:"weight of the book authors in free text search"`
+		SuggestTimeout   time.Duration `yaml:"suggest_timeout" env:"SEARCH_SUGGEST_TIMEOUT" env-default:"100ms" env-description:"latency budget of suggestions"`
+		RelatedSize      int           `yaml:"related_size" env:"SEARCH_RELATED_SIZE" env-default:"5" env-description:"number of related items"`
+	} `yaml:"search"`
+	Reservations struct {
+		TTL           time.Duration `yaml:"ttl" env:"RESERVATION_TTL" env-default:"15m" env-description:"time to commit a reservation"`
+		SweepInterval time.Duration `yaml:"sweep_interval" env:"RESERVATION_SWEEP_INTERVAL" env-default:"1m" env-description:"how often expired reservations are released"`
+	} `yaml:"reservations"`
+	Pictures struct {
+		Dir           string `yaml:"dir" env:"PICTURES_DIR" env-default:"data/pictures" env-description:"directory of the uploaded pictures"`
+		BaseURL       string `yaml:"base_url" env:"PICTURES_BASE_URL" env-default:"http://127.0.0.1:8081" env-description:"address the pictures are served from"`
+		MaxSize       int64  `yaml:"max_size" env:"PICTURES_MAX_SIZE" env-default:"5242880" env-description:"largest picture in bytes"`
+		ThumbnailSize int    `yaml:"thumbnail_size" env:"PICTURES_THUMBNAIL_SIZE" env-default:"200" env-description:"thumbnail width and height bound in pixels"`
+	} `yaml:"pictures"`
+	Events struct {
+		Publisher        string        `yaml:"publisher" env:"EVENTS_PUBLISHER" env-default:"file" env-description:"item events publisher : file or webhook"`
+		File             string        `yaml:"file" env:"EVENTS_FILE" env-default:"data/item_events.ndjson" env-description:"file the events are appended to"`
+		WebhookURL       string        `yaml:"webhook_url" env:"EVENTS_WEBHOOK_URL" env-description:"url the events are posted to"`
+		WebhookSecret    string        `yaml:"webhook_secret" env:"EVENTS_WEBHOOK_SECRET" env-description:"key of the X-Signature hmac, unsigned when empty"`
+		WebhookTimeout   time.Duration `yaml:"webhook_timeout" env:"EVENTS_WEBHOOK_TIMEOUT" env-default:"5s" env-description:"time to deliver one event"`
+		DispatchInterval time.Duration `yaml:"dispatch_interval" env:"EVENTS_DISPATCH_INTERVAL" env-default:"1s" env-description:"how often pending events are published"`
+		BatchSize        int           `yaml:"batch_size" env:"EVENTS_BATCH_SIZE" env-default:"100" env-description:"events published per dispatch"`
+	} `yaml:"events"`
 	Server struct {
-		Host string `yaml:"host" env:"SRV_HOST,HOST" env-default:"localhost"`
-		Port string `yaml:"port" env:"SRV_PORT,PORT" env-default:"8081"`
+		Host         string        `yaml:"host" env:"SRV_HOST,HOST" env-default:"localhost"`
+		Port         string        `yaml:"port" env:"SRV_PORT,PORT" env-default:"8081"`
+		ReadTimeout  time.Duration `yaml:"read_timeout" env:"SRV_READ_TIMEOUT" env-default:"20ms" env-description:"time to read the request headers"`
+		WriteTimeout time.Duration `yaml:"write_timeout" env:"SRV_WRITE_TIMEOUT" env-default:"200ms" env-description:"time to handle a read request"`
+		IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SRV_IDLE_TIMEOUT" env-default:"10ms"`
+		// bulk import and export need minutes for large catalogs, uploads read pictures of megabytes
+		BulkTimeout time.Duration `yaml:"bulk_timeout" env:"SRV_BULK_TIMEOUT" env-default:"5m" env-description:"time to import, export items or upload a picture"`
 	} `yaml:"server"`
 }
 
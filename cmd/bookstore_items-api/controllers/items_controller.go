@@ -2,14 +2,19 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
 	"github.com/gorilla/mux"
 )
@@ -19,6 +24,22 @@ type ItemControllerInterface interface {
 	Get(http.ResponseWriter, *http.Request)
 	Ping(http.ResponseWriter, *http.Request)
 	Search(w http.ResponseWriter, r *http.Request)
+	SellerItems(http.ResponseWriter, *http.Request)
+	Suggest(http.ResponseWriter, *http.Request)
+	Related(http.ResponseWriter, *http.Request)
+	Update(http.ResponseWriter, *http.Request)
+	Transition(http.ResponseWriter, *http.Request)
+	Delete(http.ResponseWriter, *http.Request)
+	Import(http.ResponseWriter, *http.Request)
+	Export(http.ResponseWriter, *http.Request)
+}
+
+// largest accepted import body
+const maxImportBody = 64 << 20
+
+var contentTypes = map[string]string{
+	items.FormatNdjson: "application/x-ndjson",
+	items.FormatCsv:    "text/csv",
 }
 
 type itemController struct {
@@ -40,20 +61,16 @@ func (c *itemController) Ping(w http.ResponseWriter, rq *http.Request) {
 }
 
 func (c *itemController) Create(w http.ResponseWriter, rq *http.Request) {
-	if err := c.oauthService.AuthenticateRequest(rq); err != nil {
-		rest_errors.ResponseError(w, err)
-		return
-	}
-
-	callerId := c.oauthService.GetCallerId(rq)
-	if callerId == 0 {
-		rest_errors.ResponseError(w, rest_errors.NewAuthorizationError("no user info in the token"))
+	callerId, authErr := authenticateCaller(c.oauthService, rq)
+	if authErr != nil {
+		rest_errors.ResponseError(w, authErr)
 		return
 	}
 
 	buf, err := ioutil.ReadAll(rq.Body)
 	if err != nil {
 		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
 	}
 
 	defer rq.Body.Close()
@@ -62,6 +79,7 @@ func (c *itemController) Create(w http.ResponseWriter, rq *http.Request) {
 	err = json.Unmarshal(buf, &item)
 	if err != nil {
 		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
 	}
 
 	item.Seller = callerId
@@ -70,20 +88,17 @@ func (c *itemController) Create(w http.ResponseWriter, rq *http.Request) {
 		rest_errors.ResponseError(w, createErr)
 		return
 	}
+	setETag(w, result.Version)
 	rest_errors.ResponseJson(w, http.StatusCreated, result)
 }
 
 func (c *itemController) Get(w http.ResponseWriter, rq *http.Request) {
-	itemId := rq.Context().Value("id")
-	if itemId == nil {
-		itemId = strings.TrimSpace(mux.Vars(rq)["id"])
-	}
-
-	item, err := c.itemsService.Get(itemId.(string))
+	item, err := c.itemsService.Get(getItemId(rq))
 	if err != nil {
 		rest_errors.ResponseError(w, err)
 		return
 	}
+	setETag(w, item.Version)
 	rest_errors.ResponseJson(w, http.StatusOK, item)
 }
 
@@ -96,17 +111,355 @@ func (c *itemController) Search(w http.ResponseWriter, rq *http.Request) {
 	defer rq.Body.Close()
 
 	var q queries.EsQuery
-	if err := json.Unmarshal(b, &q); err != nil {
-		apiErr := rest_errors.NewBadRequestError("bad query")
-		rest_errors.ResponseError(w, apiErr)
-		return
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &q); err != nil {
+			apiErr := rest_errors.NewBadRequestError("bad query")
+			rest_errors.ResponseError(w, apiErr)
+			return
+		}
+	}
+	if q.Text == "" {
+		q.Text = strings.TrimSpace(rq.URL.Query().Get("q"))
 	}
 
-	items, searchErr := c.itemsService.Search(q)
+	// anonymous callers see active items only
+	var callerId int64
+	if err := c.oauthService.AuthenticateRequest(rq); err == nil {
+		callerId = c.oauthService.GetCallerId(rq)
+	}
+
+	result, searchErr := c.itemsService.Search(q, callerId)
 	if searchErr != nil {
 		rest_errors.ResponseError(w, searchErr)
 		return
 	}
 
-	rest_errors.ResponseJson(w, http.StatusOK, items)
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+// SellerItems lists the seller store : ?status=paused&page=2&size=20&sort=price:desc,title
+func (c *itemController) SellerItems(w http.ResponseWriter, rq *http.Request) {
+	seller, err := strconv.ParseInt(strings.TrimSpace(mux.Vars(rq)["id"]), 10, 64)
+	if err != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError("invalid seller id"))
+		return
+	}
+
+	params := rq.URL.Query()
+	page, pageErr := intParam(params, "page")
+	if pageErr != nil {
+		rest_errors.ResponseError(w, pageErr)
+		return
+	}
+	size, sizeErr := intParam(params, "size")
+	if sizeErr != nil {
+		rest_errors.ResponseError(w, sizeErr)
+		return
+	}
+	q := queries.EsQuery{Page: page, Size: size, Sort: sortParams(params["sort"])}
+
+	// anonymous callers see active items only
+	var callerId int64
+	if err := c.oauthService.AuthenticateRequest(rq); err == nil {
+		callerId = c.oauthService.GetCallerId(rq)
+	}
+
+	result, listErr := c.itemsService.SellerItems(seller, strings.TrimSpace(params.Get("status")), q, callerId)
+	if listErr != nil {
+		rest_errors.ResponseError(w, listErr)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+// intParam reads an optional number, 0 when missing
+func intParam(params url.Values, name string) (int, rest_errors.RestErr) {
+	param := strings.TrimSpace(params.Get(name))
+	if param == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, rest_errors.NewBadRequestError(fmt.Sprintf("invalid %s", name))
+	}
+	return value, nil
+}
+
+// sortParams reads field[:order] values, separated by commas or repeated
+func sortParams(values []string) []queries.SortField {
+	var sort []queries.SortField
+	for _, value := range values {
+		for _, param := range strings.Split(value, ",") {
+			if param = strings.TrimSpace(param); param == "" {
+				continue
+			}
+			field, order := param, ""
+			if idx := strings.Index(param, ":"); idx >= 0 {
+				field, order = param[:idx], param[idx+1:]
+			}
+			sort = append(sort, queries.SortField{Field: field, Order: order})
+		}
+	}
+	return sort
+}
+
+func (c *itemController) Suggest(w http.ResponseWriter, rq *http.Request) {
+	params := rq.URL.Query()
+
+	size := 0
+	if value := strings.TrimSpace(params.Get("size")); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil {
+			rest_errors.ResponseError(w, rest_errors.NewBadRequestError("invalid size"))
+			return
+		}
+	}
+
+	result, err := c.itemsService.Suggest(params.Get("prefix"), size)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *itemController) Related(w http.ResponseWriter, rq *http.Request) {
+	related, err := c.itemsService.Related(getItemId(rq))
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, map[string][]items.Item{"items": related})
+}
+
+func (c *itemController) Update(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	version, err := ifMatch(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	buf, readErr := ioutil.ReadAll(rq.Body)
+	if readErr != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(readErr.Error()))
+		return
+	}
+	defer rq.Body.Close()
+
+	var item items.Item
+	if err := json.Unmarshal(buf, &item); err != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	item.Id = getItemId(rq)
+	item.Seller = callerId
+	item.Version = version
+
+	isPartial := rq.Method == http.MethodPatch
+	if isPartial {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(buf, &fields); err != nil {
+			rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+			return
+		}
+		item.Sent = make(map[string]bool, len(fields))
+		for field := range fields {
+			item.Sent[field] = true
+		}
+	}
+
+	result, updateErr := c.itemsService.Update(isPartial, item)
+	if updateErr != nil {
+		rest_errors.ResponseError(w, updateErr)
+		return
+	}
+	setETag(w, result.Version)
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *itemController) Transition(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	version, err := ifMatch(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	buf, readErr := ioutil.ReadAll(rq.Body)
+	if readErr != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(readErr.Error()))
+		return
+	}
+	defer rq.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(buf, &body); err != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	item := items.Item{Id: getItemId(rq), Seller: callerId, Status: strings.TrimSpace(body.Status), Version: version}
+	result, transitionErr := c.itemsService.Transition(item)
+	if transitionErr != nil {
+		rest_errors.ResponseError(w, transitionErr)
+		return
+	}
+	setETag(w, result.Version)
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *itemController) Delete(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	version, err := ifMatch(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	item := items.Item{Id: getItemId(rq), Seller: callerId, Version: version}
+	if err := c.itemsService.Delete(item); err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (c *itemController) Import(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	defer rq.Body.Close()
+
+	reader, err := items.NewRowReader(bulkFormat(rq), http.MaxBytesReader(w, rq.Body, maxImportBody))
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	report, err := c.itemsService.Import(callerId, reader)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, report)
+}
+
+func (c *itemController) Export(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	format := bulkFormat(rq)
+	out := &trackingWriter{writer: w}
+	writer, err := items.NewRowWriter(format, out)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=items.%s", format))
+
+	if err := c.itemsService.Export(callerId, writer); err != nil {
+		if !out.started {
+			w.Header().Del("Content-Disposition")
+			rest_errors.ResponseError(w, err)
+			return
+		}
+		// the status is sent already, the client gets a truncated export
+		logger.Error("export items error", err)
+	}
+}
+
+// bulkFormat is the format query parameter, the content type otherwise
+func bulkFormat(rq *http.Request) string {
+	if format := strings.TrimSpace(rq.URL.Query().Get("format")); format != "" {
+		return format
+	}
+	if strings.HasPrefix(rq.Header.Get("Content-Type"), contentTypes[items.FormatCsv]) {
+		return items.FormatCsv
+	}
+	return items.FormatNdjson
+}
+
+// trackingWriter tells whether the response has been started
+type trackingWriter struct {
+	writer  http.ResponseWriter
+	started bool
+}
+
+func (t *trackingWriter) Write(b []byte) (int, error) {
+	t.started = true
+	return t.writer.Write(b)
+}
+
+func authenticateCaller(oauthService oauth.OAuthInterface, rq *http.Request) (int64, rest_errors.RestErr) {
+	if err := oauthService.AuthenticateRequest(rq); err != nil {
+		return 0, err
+	}
+
+	callerId := oauthService.GetCallerId(rq)
+	if callerId == 0 {
+		return 0, rest_errors.NewAuthorizationError("no user info in the token")
+	}
+	return callerId, nil
+}
+
+func getItemId(rq *http.Request) string {
+	if itemId, ok := rq.Context().Value("id").(string); ok {
+		return itemId
+	}
+	return strings.TrimSpace(mux.Vars(rq)["id"])
+}
+
+// setETag sends the item revision, clients send it back in If-Match to update that revision only
+func setETag(w http.ResponseWriter, version *es.DocVersion) {
+	if version != nil {
+		w.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, version.SeqNo, version.PrimaryTerm))
+	}
+}
+
+// ifMatch reads the revision the client expects, nil without the header or for If-Match: *.
+// A value no revision can match fails the precondition
+func ifMatch(rq *http.Request) (*es.DocVersion, rest_errors.RestErr) {
+	value := strings.TrimSpace(rq.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+
+	mismatch := rest_errors.NewPreconditionFailedError("If-Match does not match the item")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return nil, mismatch
+	}
+	parts := strings.Split(value[1:len(value)-1], "-")
+	if len(parts) != 2 {
+		return nil, mismatch
+	}
+	seqNo, seqErr := strconv.ParseInt(parts[0], 10, 64)
+	primaryTerm, termErr := strconv.ParseInt(parts[1], 10, 64)
+	if seqErr != nil || termErr != nil {
+		return nil, mismatch
+	}
+	return &es.DocVersion{SeqNo: seqNo, PrimaryTerm: primaryTerm}, nil
 }
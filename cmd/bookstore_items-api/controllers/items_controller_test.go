@@ -10,11 +10,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
 	oaumocks "github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/mocks"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -53,7 +55,7 @@ func (s *ItemControllerSuite) TestCreateOk() {
 	resp := httptest.NewRecorder()
 
 	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
-	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int(callerId))
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
 
 	s.mockedItemsService.On("Create", mock.IsType(item)).Return(func(item items.Item) *items.Item {
 		item.Id = "assigned"
@@ -94,7 +96,7 @@ func (s *ItemControllerSuite) TestCreateFailedCallerIdIsZero() {
 	resp := httptest.NewRecorder()
 
 	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
-	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(0)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(0))
 
 	s.itemsController.Create(resp, req)
 	restError, err := rest_errors.NewRestErrorFromBytes(resp.Body.Bytes())
@@ -118,7 +120,7 @@ func (s *ItemControllerSuite) TestCreateFailedOnSave() {
 	resp := httptest.NewRecorder()
 
 	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
-	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(1)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
 
 	s.mockedItemsService.On("Create", mock.IsType(item)).Return(nil,
 		func(item items.Item) rest_errors.RestErr {
@@ -149,21 +151,11 @@ func (s *ItemControllerSuite) TestCreateFailedBadRequest() {
 	resp := httptest.NewRecorder()
 
 	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
-	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(1)
-
-	s.mockedItemsService.On("Create", mock.IsType(item)).Return(func(item items.Item) *items.Item {
-		item.Id = "assigned"
-		return &item
-	}, nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
 
 	s.itemsController.Create(resp, req)
 
-	var itemResult items.Item
-	err := json.Unmarshal(resp.Body.Bytes(), &itemResult)
-
-	s.mockedItemsService.AssertExpectations(s.T())
-
-	assert.NotNil(s.T(), err)
+	s.mockedItemsService.AssertNotCalled(s.T(), "Create", mock.IsType(item))
 	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
 }
 
@@ -192,6 +184,19 @@ func (s *ItemControllerSuite) TestGetItemOk() {
 	assert.Equal(s.T(), http.StatusOK, resp.Code)
 }
 
+func (s *ItemControllerSuite) TestGetItemETag() {
+	ctx := context.WithValue(context.Background(), "id", "100")
+	req := httptest.NewRequest(http.MethodGet, "/items/{id}", nil).WithContext(ctx)
+	resp := httptest.NewRecorder()
+
+	s.mockedItemsService.On("Get", "100").Return(&items.Item{Id: "100", Version: &es.DocVersion{SeqNo: 7, PrimaryTerm: 2}}, nil)
+
+	s.itemsController.Get(resp, req)
+
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), `"7-2"`, resp.Header().Get("ETag"))
+}
+
 func (s *ItemControllerSuite) TestGetItemFailed() {
 	req := httptest.NewRequest(http.MethodGet, "/items/{id}", nil)
 
@@ -219,19 +224,63 @@ func (s *ItemControllerSuite) TestSearchOk() {
 	req := requestForBodyQuery(http.MethodPost, "/items/search", &q)
 	resp := httptest.NewRecorder()
 
-	s.mockedItemsService.On("Search", mock.IsType(q)).Return(
-		func(q queries.EsQuery) []items.Item { return []items.Item{{Id: "1"}} }, nil)
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(0))
+
+	s.mockedItemsService.On("Search", mock.IsType(q), int64(0)).Return(
+		func(q queries.EsQuery, callerId int64) *items.SearchResult {
+			return &items.SearchResult{Total: 1, Page: 1, Size: 10, Items: []items.Item{{Id: "1"}}}
+		}, nil)
 
 	s.itemsController.Search(resp, req)
 
-	var items []items.Item
-	err := json.Unmarshal(resp.Body.Bytes(), &items)
+	var result items.SearchResult
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
 
 	s.mockedItemsService.AssertExpectations(s.T())
 
 	assert.Nil(s.T(), err)
-	assert.Equal(s.T(), 1, len(items))
+	assert.EqualValues(s.T(), 1, result.Total)
+	assert.Equal(s.T(), 1, len(result.Items))
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestSellerItems() {
+	req := httptest.NewRequest(http.MethodGet, "/sellers/7/items?status=paused&page=2&size=20&sort=price:desc,title&sort=sold_quantity", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+
+	q := queries.EsQuery{Page: 2, Size: 20, Sort: []queries.SortField{
+		{Field: "price", Order: "desc"}, {Field: "title"}, {Field: "sold_quantity"},
+	}}
+	s.mockedItemsService.On("SellerItems", int64(7), "paused", q, int64(7)).Return(
+		&items.SearchResult{Total: 1, Page: 2, Size: 20, Items: []items.Item{{Id: "1"}}}, nil)
+
+	s.itemsController.SellerItems(resp, req)
+
+	var result items.SearchResult
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
 	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), 1, len(result.Items))
+}
+
+func (s *ItemControllerSuite) TestSellerItemsFailedBadRequest() {
+	for _, target := range []string{"/sellers/x/items", "/sellers/7/items?page=x", "/sellers/7/items?size=x"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strings.Split(target, "/")[2]})
+		resp := httptest.NewRecorder()
+
+		s.itemsController.SellerItems(resp, req)
+
+		assert.Equal(s.T(), http.StatusBadRequest, resp.Code, target)
+	}
+	s.mockedItemsService.AssertNotCalled(s.T(), "SellerItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (s *ItemControllerSuite) TestSearchFailedService() {
@@ -240,22 +289,61 @@ func (s *ItemControllerSuite) TestSearchFailedService() {
 	req := requestForBodyQuery(http.MethodPost, "/items/search", &q)
 	resp := httptest.NewRecorder()
 
-	s.mockedItemsService.On("Search", mock.IsType(q)).Return(nil,
-		func(q queries.EsQuery) rest_errors.RestErr {
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(0))
+
+	s.mockedItemsService.On("Search", mock.IsType(q), int64(0)).Return(nil,
+		func(q queries.EsQuery, callerId int64) rest_errors.RestErr {
 			return rest_errors.NewInternalServerError("search service", errors.New("failed"))
 		})
 
 	s.itemsController.Search(resp, req)
 
-	var items []items.Item
-	err := json.Unmarshal(resp.Body.Bytes(), &items)
+	var result items.SearchResult
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
 
 	s.mockedItemsService.AssertExpectations(s.T())
 
-	assert.NotNil(s.T(), err)
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), result.Items)
 	assert.Equal(s.T(), http.StatusInternalServerError, resp.Code)
 }
 
+func (s *ItemControllerSuite) TestSearchAsSeller() {
+	var (
+		callerId int64 = 100
+		q              = queries.EsQuery{}
+	)
+
+	req := requestForBodyQuery(http.MethodPost, "/items/search", &q)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
+
+	s.mockedItemsService.On("Search", mock.IsType(q), callerId).Return(&items.SearchResult{}, nil)
+
+	s.itemsController.Search(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestSearchTextParameter() {
+	req := httptest.NewRequest(http.MethodPost, "/items/search?q=tolkien+hobbit", nil)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(0))
+
+	s.mockedItemsService.On("Search", queries.EsQuery{Text: "tolkien hobbit"}, int64(0)).Return(&items.SearchResult{}, nil)
+
+	s.itemsController.Search(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
 func (s *ItemControllerSuite) TestSearchBadRequest() {
 	req := httptest.NewRequest(http.MethodPost, "/items/search", strings.NewReader("bad query"))
 	resp := httptest.NewRecorder()
@@ -270,8 +358,330 @@ func (s *ItemControllerSuite) TestSearchBadRequest() {
 	assert.Equal(s.T(), http.StatusBadRequest, re.Status())
 }
 
+func (s *ItemControllerSuite) TestSuggestOk() {
+	req := httptest.NewRequest(http.MethodGet, "/items/suggest?prefix=hob&size=3", nil)
+	resp := httptest.NewRecorder()
+
+	result := &items.SuggestResult{Prefix: "hob", Suggestions: []items.Suggestion{{Text: "The Hobbit", Id: "1", Field: "title"}}}
+	s.mockedItemsService.On("Suggest", "hob", 3).Return(result, nil)
+
+	s.itemsController.Suggest(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.JSONEq(s.T(), `{"prefix": "hob", "suggestions": [{"text": "The Hobbit", "id": "1", "field": "title"}]}`, resp.Body.String())
+}
+
+func (s *ItemControllerSuite) TestSuggestBadSize() {
+	req := httptest.NewRequest(http.MethodGet, "/items/suggest?prefix=hob&size=many", nil)
+	resp := httptest.NewRecorder()
+
+	s.itemsController.Suggest(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestRelatedOk() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/items/1/related", nil), map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+
+	s.mockedItemsService.On("Related", "1").Return([]items.Item{{Id: "2", Title: "The Hobbit"}}, nil)
+
+	s.itemsController.Related(resp, req)
+
+	var result struct {
+		Items []items.Item `json:"items"`
+	}
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), "2", result.Items[0].Id)
+}
+
+func (s *ItemControllerSuite) TestRelatedNotFound() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/items/1/related", nil), map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+
+	s.mockedItemsService.On("Related", "1").Return(nil, rest_errors.NewNotFoundError("item not found"))
+
+	s.itemsController.Related(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusNotFound, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestUpdateOk() {
+	var (
+		callerId int64 = 100
+		item           = items.Item{Title: "new title"}
+	)
+
+	ctx := context.WithValue(context.Background(), "id", "1")
+	req := requestForBodyItem(http.MethodPatch, "/items/{id}", &item).WithContext(ctx)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
+
+	s.mockedItemsService.On("Update", true, mock.IsType(item)).Return(func(isPartial bool, item items.Item) *items.Item {
+		return &item
+	}, nil)
+
+	s.itemsController.Update(resp, req)
+
+	var itemResult items.Item
+	err := json.Unmarshal(resp.Body.Bytes(), &itemResult)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), "1", itemResult.Id)
+	assert.Equal(s.T(), callerId, itemResult.Seller)
+	assert.Equal(s.T(), "new title", itemResult.Title)
+}
+
+func (s *ItemControllerSuite) TestUpdateSentFields() {
+	ctx := context.WithValue(context.Background(), "id", "1")
+	req := httptest.NewRequest(http.MethodPatch, "/items/{id}",
+		strings.NewReader(`{"title":"new title","available_quantity":0}`)).WithContext(ctx)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Update", true, mock.MatchedBy(func(it items.Item) bool {
+		return len(it.Sent) == 2 && it.Sent["title"] && it.Sent["available_quantity"]
+	})).Return(&items.Item{Id: "1"}, nil)
+
+	s.itemsController.Update(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestUpdateIfMatch() {
+	ctx := context.WithValue(context.Background(), "id", "1")
+	req := requestForBodyItem(http.MethodPatch, "/items/{id}", &items.Item{Title: "new title"}).WithContext(ctx)
+	req.Header.Set("If-Match", `"7-2"`)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Update", true, mock.MatchedBy(func(it items.Item) bool {
+		return it.Version != nil && *it.Version == es.DocVersion{SeqNo: 7, PrimaryTerm: 2}
+	})).Return(&items.Item{Id: "1", Version: &es.DocVersion{SeqNo: 8, PrimaryTerm: 2}}, nil)
+
+	s.itemsController.Update(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), `"8-2"`, resp.Header().Get("ETag"))
+}
+
+func (s *ItemControllerSuite) TestUpdateFailedPrecondition() {
+	req := requestForBodyItem(http.MethodPut, "/items/{id}", &items.Item{})
+	req.Header.Set("If-Match", `"7-2"`)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Update", false, mock.IsType(items.Item{})).Return(nil,
+		rest_errors.NewPreconditionFailedError("item has been modified since it was read"))
+
+	s.itemsController.Update(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusPreconditionFailed, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestInvalidIfMatch() {
+	for _, value := range []string{`W/"7-2"`, `7-2`, `"7"`, `"a-b"`, `"7-2", "8-2"`} {
+		req := httptest.NewRequest(http.MethodDelete, "/items/{id}", nil)
+		req.Header.Set("If-Match", value)
+		resp := httptest.NewRecorder()
+
+		s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+		s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+		s.itemsController.Delete(resp, req)
+
+		assert.Equal(s.T(), http.StatusPreconditionFailed, resp.Code, value)
+	}
+	s.mockedItemsService.AssertNotCalled(s.T(), "Delete", mock.Anything)
+}
+
+func (s *ItemControllerSuite) TestUpdateFailedForbidden() {
+	req := requestForBodyItem(http.MethodPut, "/items/{id}", &items.Item{})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Update", false, mock.IsType(items.Item{})).Return(nil,
+		rest_errors.NewForbiddenError("item belongs to another seller"))
+
+	s.itemsController.Update(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusForbidden, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestUpdateFailedBadRequest() {
+	req := httptest.NewRequest(http.MethodPut, "/items/{id}", strings.NewReader("invalid item json"))
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.itemsController.Update(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestDeleteOk() {
+	ctx := context.WithValue(context.Background(), "id", "1")
+	req := httptest.NewRequest(http.MethodDelete, "/items/{id}", nil).WithContext(ctx)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Delete", items.Item{Id: "1", Seller: 1}).Return(nil)
+
+	s.itemsController.Delete(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestDeleteFailedNotAuthenticated() {
+	req := httptest.NewRequest(http.MethodDelete, "/items/{id}", nil)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(
+		rest_errors.NewAuthorizationError("oauth error"))
+
+	s.itemsController.Delete(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.Code)
+}
+
 // helpers
 
+func (s *ItemControllerSuite) TestTransitionOk() {
+	var callerId int64 = 100
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1/status", strings.NewReader(`{"status": "paused"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
+
+	expected := items.Item{Id: "1", Seller: callerId, Status: items.StatusPaused}
+	s.mockedItemsService.On("Transition", expected).Return(&expected, nil)
+
+	s.itemsController.Transition(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestTransitionFailedConflict() {
+	req := httptest.NewRequest(http.MethodPost, "/items/1/status", strings.NewReader(`{"status": "draft"}`))
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedItemsService.On("Transition", mock.IsType(items.Item{})).Return(nil,
+		rest_errors.NewConflictError("item can not move from active to draft"))
+
+	s.itemsController.Transition(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusConflict, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestImportCsv() {
+	req := httptest.NewRequest(http.MethodPost, "/items/import", strings.NewReader("title\na\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+
+	s.mockedItemsService.On("Import", int64(7), mock.Anything).Return(
+		&items.ImportReport{Total: 1, Created: 1, Rows: []items.ImportRow{{Row: 1, Id: "1"}}}, nil)
+
+	s.itemsController.Import(resp, req)
+
+	var report items.ImportReport
+	err := json.Unmarshal(resp.Body.Bytes(), &report)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), 1, report.Created)
+}
+
+func (s *ItemControllerSuite) TestImportBadFormat() {
+	req := httptest.NewRequest(http.MethodPost, "/items/import?format=xml", strings.NewReader(""))
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+
+	s.itemsController.Import(resp, req)
+
+	s.mockedItemsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *ItemControllerSuite) TestExportNdjson() {
+	req := httptest.NewRequest(http.MethodGet, "/items/export?format=ndjson", nil)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+
+	s.mockedItemsService.On("Export", int64(7), mock.Anything).Return(
+		func(seller int64, writer items.RowWriter) rest_errors.RestErr {
+			writer.Write(items.Item{Id: "1"})
+			writer.Flush()
+			return nil
+		})
+
+	s.itemsController.Export(resp, req)
+
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), "application/x-ndjson", resp.Header().Get("Content-Type"))
+	assert.Contains(s.T(), resp.Body.String(), `"id":"1"`)
+}
+
+func (s *ItemControllerSuite) TestExportFailedBeforeStart() {
+	req := httptest.NewRequest(http.MethodGet, "/items/export", nil)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+
+	s.mockedItemsService.On("Export", int64(7), mock.Anything).Return(
+		rest_errors.NewInternalServerError("export items error", errors.New("scroll")))
+
+	s.itemsController.Export(resp, req)
+
+	assert.Equal(s.T(), http.StatusInternalServerError, resp.Code)
+}
+
 func requestForBodyItem(httpMethod, urlPath string, item *items.Item) *http.Request {
 	bytes, _ := json.Marshal(item)
 	return httptest.NewRequest(httpMethod, urlPath, strings.NewReader(string(bytes)))
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/pictures"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// largest accepted upload body, the picture size is checked by the service
+	maxPictureBody = 32 << 20
+	// multipart form field holding the picture
+	pictureField = "picture"
+)
+
+type PictureControllerInterface interface {
+	Upload(http.ResponseWriter, *http.Request)
+	Get(http.ResponseWriter, *http.Request)
+	Thumbnail(http.ResponseWriter, *http.Request)
+	Delete(http.ResponseWriter, *http.Request)
+	Reorder(http.ResponseWriter, *http.Request)
+}
+
+type pictureController struct {
+	oauthService    oauth.OAuthInterface
+	picturesService services.PicturesServiceInterface
+}
+
+func NewPictureController(oauthService oauth.OAuthInterface,
+	picturesService services.PicturesServiceInterface) PictureControllerInterface {
+	return &pictureController{
+		oauthService:    oauthService,
+		picturesService: picturesService,
+	}
+}
+
+func (c *pictureController) Upload(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	defer rq.Body.Close()
+
+	rq.Body = http.MaxBytesReader(w, rq.Body, maxPictureBody)
+	reader, readErr := rq.MultipartReader()
+	if readErr != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError("multipart/form-data body expected"))
+		return
+	}
+
+	for {
+		part, partErr := reader.NextPart()
+		if partErr == io.EOF {
+			rest_errors.ResponseError(w, rest_errors.NewBadRequestError("no picture field in the form"))
+			return
+		}
+		if partErr != nil {
+			rest_errors.ResponseError(w, rest_errors.NewBadRequestError(partErr.Error()))
+			return
+		}
+		if part.FormName() != pictureField {
+			continue
+		}
+
+		data, readErr := ioutil.ReadAll(part)
+		if readErr != nil {
+			rest_errors.ResponseError(w, rest_errors.NewBadRequestError(readErr.Error()))
+			return
+		}
+		upload := pictures.Upload{
+			ItemId:      getItemId(rq),
+			Seller:      callerId,
+			ContentType: strings.TrimSpace(part.Header.Get("Content-Type")),
+			Data:        data,
+		}
+		result, uploadErr := c.picturesService.Upload(upload)
+		if uploadErr != nil {
+			rest_errors.ResponseError(w, uploadErr)
+			return
+		}
+		rest_errors.ResponseJson(w, http.StatusCreated, result)
+		return
+	}
+}
+
+func (c *pictureController) Get(w http.ResponseWriter, rq *http.Request) {
+	c.serve(w, rq, false)
+}
+
+func (c *pictureController) Thumbnail(w http.ResponseWriter, rq *http.Request) {
+	c.serve(w, rq, true)
+}
+
+// serve streams the stored file, picture ids are never reused so the content never changes
+func (c *pictureController) serve(w http.ResponseWriter, rq *http.Request, thumbnail bool) {
+	pictureId, err := getPictureId(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	r, err := c.picturesService.Open(getItemId(rq), pictureId, thumbnail)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	defer r.Close()
+
+	content := bufio.NewReader(r)
+	head, _ := content.Peek(512)
+	w.Header().Set("Content-Type", http.DetectContentType(head))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Error("serve picture error", err)
+	}
+}
+
+func (c *pictureController) Delete(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	pictureId, err := getPictureId(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	result, err := c.picturesService.Delete(getItemId(rq), callerId, pictureId)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *pictureController) Reorder(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	buf, readErr := ioutil.ReadAll(rq.Body)
+	if readErr != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(readErr.Error()))
+		return
+	}
+	defer rq.Body.Close()
+
+	var body struct {
+		Ids []int64 `json:"ids"`
+	}
+	if err := json.Unmarshal(buf, &body); err != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := c.picturesService.Reorder(getItemId(rq), callerId, body.Ids)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func getPictureId(rq *http.Request) (int64, rest_errors.RestErr) {
+	pictureId, err := strconv.ParseInt(strings.TrimSpace(mux.Vars(rq)["picture_id"]), 10, 64)
+	if err != nil {
+		return 0, rest_errors.NewBadRequestError("invalid picture id")
+	}
+	return pictureId, nil
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/pictures"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
+	oaumocks "github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/mocks"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PictureControllerSuite struct {
+	suite.Suite
+	mockedPicturesService *mocks.PicturesServiceInterface
+	mockedOAuthService    *oaumocks.OAuthInterface
+	picturesController    PictureControllerInterface
+}
+
+func TestPictureControllerSuite(t *testing.T) {
+	suite.Run(t, new(PictureControllerSuite))
+}
+
+func (s *PictureControllerSuite) SetupTest() {
+	s.mockedOAuthService = new(oaumocks.OAuthInterface)
+	s.mockedPicturesService = new(mocks.PicturesServiceInterface)
+	s.picturesController = NewPictureController(
+		s.mockedOAuthService,
+		s.mockedPicturesService,
+	)
+}
+
+func (s *PictureControllerSuite) authenticated(req *http.Request) {
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(7))
+}
+
+func multipartRequest(field, contentType string, data []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("caption", "cover")
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="cover"`)
+	header.Set("Content-Type", contentType)
+	part, _ := form.CreatePart(header)
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1/pictures", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return mux.SetURLVars(req, map[string]string{"id": "1"})
+}
+
+func (s *PictureControllerSuite) TestUploadOk() {
+	req := multipartRequest("picture", "image/png", []byte("png"))
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	upload := pictures.Upload{ItemId: "1", Seller: 7, ContentType: "image/png", Data: []byte("png")}
+	s.mockedPicturesService.On("Upload", upload).Return(&items.Item{Id: "1", Pictures: []items.Picture{{Id: 5}}}, nil)
+
+	s.picturesController.Upload(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusCreated, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestUploadNoPicture() {
+	req := multipartRequest("file", "image/png", []byte("png"))
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	s.picturesController.Upload(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestUploadNotMultipart() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/items/1/pictures", strings.NewReader("png")), map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	s.picturesController.Upload(resp, req)
+
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestGetOk() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/items/1/pictures/5", nil),
+		map[string]string{"id": "1", "picture_id": "5"})
+	resp := httptest.NewRecorder()
+
+	content := "\x89PNG\r\n\x1a\n content"
+	s.mockedPicturesService.On("Open", "1", int64(5), false).Return(ioutil.NopCloser(strings.NewReader(content)), nil)
+
+	s.picturesController.Get(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+	assert.Equal(s.T(), "image/png", resp.Header().Get("Content-Type"))
+	assert.Equal(s.T(), content, resp.Body.String())
+}
+
+func (s *PictureControllerSuite) TestThumbnailNotFound() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/items/1/pictures/5/thumbnail", nil),
+		map[string]string{"id": "1", "picture_id": "5"})
+	resp := httptest.NewRecorder()
+
+	s.mockedPicturesService.On("Open", "1", int64(5), true).Return(nil, rest_errors.NewNotFoundError("picture not found"))
+
+	s.picturesController.Thumbnail(resp, req)
+
+	assert.Equal(s.T(), http.StatusNotFound, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestDeleteBadPictureId() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/items/1/pictures/x", nil),
+		map[string]string{"id": "1", "picture_id": "x"})
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	s.picturesController.Delete(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestDeleteOk() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/items/1/pictures/5", nil),
+		map[string]string{"id": "1", "picture_id": "5"})
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	s.mockedPicturesService.On("Delete", "1", int64(7), int64(5)).Return(&items.Item{Id: "1"}, nil)
+
+	s.picturesController.Delete(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *PictureControllerSuite) TestReorderOk() {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPut, "/items/1/pictures", strings.NewReader(`{"ids": [3, 1, 2]}`)),
+		map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+	s.authenticated(req)
+
+	s.mockedPicturesService.On("Reorder", "1", int64(7), []int64{3, 1, 2}).Return(&items.Item{Id: "1"}, nil)
+
+	s.picturesController.Reorder(resp, req)
+
+	s.mockedPicturesService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
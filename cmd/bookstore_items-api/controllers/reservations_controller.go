@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/reservations"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/gorilla/mux"
+)
+
+type ReservationControllerInterface interface {
+	Reserve(http.ResponseWriter, *http.Request)
+	Commit(http.ResponseWriter, *http.Request)
+	Release(http.ResponseWriter, *http.Request)
+}
+
+type reservationController struct {
+	oauthService        oauth.OAuthInterface
+	reservationsService services.ReservationsServiceInterface
+}
+
+func NewReservationController(oauthService oauth.OAuthInterface,
+	reservationsService services.ReservationsServiceInterface) ReservationControllerInterface {
+	return &reservationController{
+		oauthService:        oauthService,
+		reservationsService: reservationsService,
+	}
+}
+
+func (c *reservationController) Reserve(w http.ResponseWriter, rq *http.Request) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	buf, readErr := ioutil.ReadAll(rq.Body)
+	if readErr != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(readErr.Error()))
+		return
+	}
+	defer rq.Body.Close()
+
+	var reservation reservations.Reservation
+	if err := json.Unmarshal(buf, &reservation); err != nil {
+		rest_errors.ResponseError(w, rest_errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	reservation = reservations.Reservation{
+		ItemId:   getItemId(rq),
+		Buyer:    callerId,
+		Quantity: reservation.Quantity,
+	}
+
+	result, reserveErr := c.reservationsService.Reserve(reservation)
+	if reserveErr != nil {
+		rest_errors.ResponseError(w, reserveErr)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusCreated, result)
+}
+
+func (c *reservationController) Commit(w http.ResponseWriter, rq *http.Request) {
+	reservation, err := c.callerReservation(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	result, commitErr := c.reservationsService.Commit(*reservation)
+	if commitErr != nil {
+		rest_errors.ResponseError(w, commitErr)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *reservationController) Release(w http.ResponseWriter, rq *http.Request) {
+	reservation, err := c.callerReservation(rq)
+	if err != nil {
+		rest_errors.ResponseError(w, err)
+		return
+	}
+
+	result, releaseErr := c.reservationsService.Release(*reservation)
+	if releaseErr != nil {
+		rest_errors.ResponseError(w, releaseErr)
+		return
+	}
+	rest_errors.ResponseJson(w, http.StatusOK, result)
+}
+
+func (c *reservationController) callerReservation(rq *http.Request) (*reservations.Reservation, rest_errors.RestErr) {
+	callerId, err := authenticateCaller(c.oauthService, rq)
+	if err != nil {
+		return nil, err
+	}
+	return &reservations.Reservation{
+		Id:     strings.TrimSpace(mux.Vars(rq)["reservation_id"]),
+		ItemId: getItemId(rq),
+		Buyer:  callerId,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/reservations"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
+	oaumocks "github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/mocks"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReservationControllerSuite struct {
+	suite.Suite
+	mockedReservationsService *mocks.ReservationsServiceInterface
+	mockedOAuthService        *oaumocks.OAuthInterface
+	reservationsController    ReservationControllerInterface
+}
+
+func TestReservationControllerSuite(t *testing.T) {
+	suite.Run(t, new(ReservationControllerSuite))
+}
+
+func (s *ReservationControllerSuite) SetupTest() {
+	s.mockedOAuthService = new(oaumocks.OAuthInterface)
+	s.mockedReservationsService = new(mocks.ReservationsServiceInterface)
+	s.reservationsController = NewReservationController(
+		s.mockedOAuthService,
+		s.mockedReservationsService,
+	)
+}
+
+func (s *ReservationControllerSuite) TestReserveOk() {
+	var callerId int64 = 100
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1/reservations",
+		strings.NewReader(`{"quantity": 2, "status": "committed"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
+
+	expected := reservations.Reservation{ItemId: "1", Buyer: callerId, Quantity: 2}
+	s.mockedReservationsService.On("Reserve", expected).Return(func(r reservations.Reservation) *reservations.Reservation {
+		r.Id = "assigned"
+		r.Status = reservations.StatusPending
+		return &r
+	}, nil)
+
+	s.reservationsController.Reserve(resp, req)
+
+	var result reservations.Reservation
+	err := json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.mockedReservationsService.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), http.StatusCreated, resp.Code)
+	assert.Equal(s.T(), "assigned", result.Id)
+	assert.Equal(s.T(), reservations.StatusPending, result.Status)
+}
+
+func (s *ReservationControllerSuite) TestReserveFailedBadRequest() {
+	req := httptest.NewRequest(http.MethodPost, "/items/1/reservations", strings.NewReader("invalid json"))
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.reservationsController.Reserve(resp, req)
+
+	s.mockedReservationsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusBadRequest, resp.Code)
+}
+
+func (s *ReservationControllerSuite) TestCommitOk() {
+	var callerId int64 = 100
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1/reservations/r/commit", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1", "reservation_id": "r"})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(callerId)
+
+	expected := reservations.Reservation{Id: "r", ItemId: "1", Buyer: callerId}
+	s.mockedReservationsService.On("Commit", expected).Return(
+		&reservations.Reservation{Id: "r", Status: reservations.StatusCommitted}, nil)
+
+	s.reservationsController.Commit(resp, req)
+
+	s.mockedReservationsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusOK, resp.Code)
+}
+
+func (s *ReservationControllerSuite) TestReleaseFailedConflict() {
+	req := httptest.NewRequest(http.MethodDelete, "/items/1/reservations/r", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1", "reservation_id": "r"})
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(nil)
+	s.mockedOAuthService.On("GetCallerId", mock.IsType(req)).Return(int64(1))
+
+	s.mockedReservationsService.On("Release", mock.IsType(reservations.Reservation{})).Return(nil,
+		rest_errors.NewConflictError("reservation is committed"))
+
+	s.reservationsController.Release(resp, req)
+
+	s.mockedReservationsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusConflict, resp.Code)
+}
+
+func (s *ReservationControllerSuite) TestReleaseFailedNotAuthenticated() {
+	req := httptest.NewRequest(http.MethodDelete, "/items/1/reservations/r", nil)
+	resp := httptest.NewRecorder()
+
+	s.mockedOAuthService.On("AuthenticateRequest", mock.IsType(req)).Return(
+		rest_errors.NewAuthorizationError("not authenticated"))
+
+	s.reservationsController.Release(resp, req)
+
+	s.mockedReservationsService.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+)
+
+const (
+	TypeItemCreated = "item.created"
+	TypeItemUpdated = "item.updated"
+	TypeItemSoldOut = "item.sold_out"
+	TypeItemDeleted = "item.deleted"
+)
+
+// Event is a change of an item published to other services. Delivery is at-least-once,
+// consumers drop an event id seen before. Version is the item revision written by the change,
+// it grows with every change of the item so an event older than the state a consumer has is stale
+type Event struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	ItemId     string    `json:"item_id"`
+	Seller     int64     `json:"seller"`
+	Version    int64     `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// item state after the change, nil for deletes
+	Item *items.Item `json:"item,omitempty"`
+}
+
+// ItemChanged returns the events of a written item, before is nil for new items.
+// An item reaching sold_out gets a sold out event besides the update
+func ItemChanged(before *items.Item, after items.Item, now time.Time) ([]Event, error) {
+	eventType := TypeItemUpdated
+	if before == nil {
+		eventType = TypeItemCreated
+	}
+	changed, err := newEvent(eventType, after, now)
+	if err != nil {
+		return nil, err
+	}
+	changed.Item = &after
+	ls := []Event{changed}
+
+	if after.Status == items.StatusSoldOut && (before == nil || before.Status != items.StatusSoldOut) {
+		soldOut, err := newEvent(TypeItemSoldOut, after, now)
+		if err != nil {
+			return nil, err
+		}
+		ls = append(ls, soldOut)
+	}
+	return ls, nil
+}
+
+// ItemDeleted returns the event of a deleted item, the delete is the last revision of the item
+func ItemDeleted(deleted items.Item, now time.Time) (Event, error) {
+	deleted.Revision++
+	return newEvent(TypeItemDeleted, deleted, now)
+}
+
+// Stage adds the events to the pending events of the item, they are stored by the item write
+func Stage(it *items.Item, ls ...Event) error {
+	for _, e := range ls {
+		bytes, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		it.PendingEvents = append(it.PendingEvents, bytes)
+	}
+	return nil
+}
+
+// PendingOf returns the pending events of the item
+func PendingOf(it items.Item) ([]Event, error) {
+	ls := make([]Event, len(it.PendingEvents))
+	for idx, bytes := range it.PendingEvents {
+		if err := json.Unmarshal(bytes, &ls[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return ls, nil
+}
+
+func newEvent(eventType string, it items.Item, now time.Time) (Event, error) {
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Id:         base64.RawURLEncoding.EncodeToString(buf),
+		Type:       eventType,
+		ItemId:     it.Id,
+		Seller:     it.Seller,
+		Version:    it.Revision,
+		OccurredAt: now.UTC(),
+	}
+	return event, nil
+}
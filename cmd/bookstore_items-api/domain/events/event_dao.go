@@ -0,0 +1,108 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/olivere/elastic"
+)
+
+const (
+	indexName = "item_events"
+	typeEvent = "_doc"
+)
+
+// the item is kept as published, it is not searched
+const eventsMapping = `{
+	"mappings": {
+		"_doc": {
+			"dynamic": "strict",
+			"properties": {
+				"id": {"type": "keyword"},
+				"type": {"type": "keyword"},
+				"item_id": {"type": "keyword"},
+				"seller": {"type": "long"},
+				"version": {"type": "long"},
+				"occurred_at": {"type": "date"},
+				"item": {"type": "object", "enabled": false}
+			}
+		}
+	}
+}`
+
+// OutboxPersistInterface keeps the events until they are published
+type OutboxPersistInterface interface {
+	Append(...Event) rest_errors.RestErr
+	Pending(int) ([]Event, rest_errors.RestErr)
+	Remove(Event) rest_errors.RestErr
+}
+
+type persist struct {
+}
+
+func NewOutboxPersister() OutboxPersistInterface {
+	return new(persist)
+}
+
+// EnsureIndex creates the outbox index on a fresh cluster
+func EnsureIndex() error {
+	exists, err := es.Client.IndexExists(indexName)
+	if err != nil || exists {
+		return err
+	}
+	return es.Client.CreateIndex(indexName, eventsMapping)
+}
+
+// Append indexes the events under their ids, appending an event again overwrites it
+func (p *persist) Append(ls ...Event) rest_errors.RestErr {
+	if len(ls) == 0 {
+		return nil
+	}
+
+	requests := make([]elastic.BulkableRequest, len(ls))
+	for idx := range ls {
+		requests[idx] = elastic.NewBulkIndexRequest().Index(indexName).Type(typeEvent).Id(ls[idx].Id).Doc(ls[idx])
+	}
+	result, err := es.Client.Bulk(requests...)
+	if err != nil {
+		return rest_errors.NewInternalServerError("append events error", err)
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return rest_errors.NewInternalServerError("append events error", errors.New(failed[0].Error.Reason))
+	}
+	return nil
+}
+
+// Pending returns the oldest events, the events of an item come in the order of versions
+func (p *persist) Pending(limit int) ([]Event, rest_errors.RestErr) {
+	source := elastic.NewSearchSource().
+		Query(elastic.NewMatchAllQuery()).
+		Size(limit).
+		Sort("occurred_at", true).
+		Sort("version", true).
+		Sort("id", true)
+
+	result, err := es.Client.Search(indexName, source)
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("search events error", err)
+	}
+
+	ls := make([]Event, len(result.Hits.Hits))
+	for idx, hit := range result.Hits.Hits {
+		bytes, _ := hit.Source.MarshalJSON()
+		if err := json.Unmarshal(bytes, &ls[idx]); err != nil {
+			return nil, rest_errors.NewInternalServerError("elk parse search response error", err)
+		}
+	}
+	return ls, nil
+}
+
+// Remove drops a published event, removing it twice is not an error
+func (p *persist) Remove(e Event) rest_errors.RestErr {
+	if _, err := es.Client.Delete(indexName, typeEvent, e.Id, nil); err != nil && !elastic.IsNotFound(err) {
+		return rest_errors.NewInternalServerError("remove event error", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// memoryPersist keeps the outbox in the process memory in the order of appends
+type memoryPersist struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryOutbox() OutboxPersistInterface {
+	return &memoryPersist{}
+}
+
+func (p *memoryPersist) Append(ls ...Event) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range ls {
+		if idx := p.index(e.Id); idx >= 0 {
+			p.events[idx] = e
+			continue
+		}
+		p.events = append(p.events, e)
+	}
+	return nil
+}
+
+func (p *memoryPersist) Pending(limit int) ([]Event, rest_errors.RestErr) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.events) < limit {
+		limit = len(p.events)
+	}
+	return append([]Event{}, p.events[:limit]...), nil
+}
+
+func (p *memoryPersist) Remove(e Event) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if idx := p.index(e.Id); idx >= 0 {
+		p.events = append(p.events[:idx], p.events[idx+1:]...)
+	}
+	return nil
+}
+
+func (p *memoryPersist) index(id string) int {
+	for idx, e := range p.events {
+		if e.Id == id {
+			return idx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func eventTypes(ls []Event) []string {
+	types := make([]string, len(ls))
+	for idx, e := range ls {
+		types[idx] = e.Type
+	}
+	return types
+}
+
+func TestItemChanged(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	active := items.Item{Id: "1", Seller: 7, Status: items.StatusActive, Revision: 4, Version: &es.DocVersion{SeqNo: 40, PrimaryTerm: 1}}
+	soldOut := active
+	soldOut.Status = items.StatusSoldOut
+	soldOut.Revision = 5
+
+	created, err := ItemChanged(nil, active, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{TypeItemCreated}, eventTypes(created))
+	assert.Equal(t, "1", created[0].ItemId)
+	assert.EqualValues(t, 7, created[0].Seller)
+	assert.EqualValues(t, 4, created[0].Version)
+	assert.Equal(t, now.UTC(), created[0].OccurredAt)
+	assert.Equal(t, &active, created[0].Item)
+	assert.NotEmpty(t, created[0].Id)
+
+	updated, err := ItemChanged(&active, soldOut, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{TypeItemUpdated, TypeItemSoldOut}, eventTypes(updated))
+	assert.NotEqual(t, updated[0].Id, updated[1].Id)
+	assert.EqualValues(t, 5, updated[1].Version)
+
+	updated, err = ItemChanged(&soldOut, soldOut, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{TypeItemUpdated}, eventTypes(updated))
+
+	deleted, err := ItemDeleted(soldOut, now)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeItemDeleted, deleted.Type)
+	assert.EqualValues(t, 6, deleted.Version)
+	assert.Nil(t, deleted.Item)
+}
+
+func TestStage(t *testing.T) {
+	it := items.Item{Id: "1", Revision: 1}
+	ls, _ := ItemChanged(nil, it, time.Now())
+	assert.Nil(t, Stage(&it, ls...))
+	it.Revision = 2
+	updated, _ := ItemChanged(&it, it, time.Now())
+	assert.Nil(t, Stage(&it, updated...))
+
+	pending, err := PendingOf(it)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{TypeItemCreated, TypeItemUpdated}, eventTypes(pending))
+	assert.EqualValues(t, 1, pending[0].Version)
+	assert.EqualValues(t, 2, pending[1].Version)
+}
+
+func TestMemoryOutbox(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	assert.Nil(t, outbox.Append(Event{Id: "1"}, Event{Id: "2"}))
+	assert.Nil(t, outbox.Append(Event{Id: "3"}, Event{Id: "1", Version: 2}))
+
+	pending, err := outbox.Pending(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Event{{Id: "1", Version: 2}, {Id: "2"}}, pending)
+
+	assert.Nil(t, outbox.Remove(Event{Id: "1"}))
+	assert.Nil(t, outbox.Remove(Event{Id: "1"}))
+	pending, _ = outbox.Pending(10)
+	assert.Equal(t, []Event{{Id: "2"}, {Id: "3"}}, pending)
+}
@@ -0,0 +1,122 @@
+package items
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const publicationDateLayout = "2006-01-02"
+
+// Book is the catalog metadata of a book item
+type Book struct {
+	Isbn            string   `json:"isbn"`
+	Authors         []string `json:"authors"`
+	Publisher       string   `json:"publisher"`
+	Edition         string   `json:"edition"`
+	Language        string   `json:"language"`
+	PageCount       int      `json:"page_count"`
+	PublicationDate string   `json:"publication_date,omitempty"`
+}
+
+// Normalize trims the metadata and converts the isbn to ISBN-13
+func (b *Book) Normalize() rest_errors.RestErr {
+	if b.Isbn != "" {
+		isbn, err := NormalizeIsbn(b.Isbn)
+		if err != nil {
+			return rest_errors.NewBadRequestError(err.Error())
+		}
+		b.Isbn = isbn
+	}
+
+	authors := make([]string, 0, len(b.Authors))
+	for _, author := range b.Authors {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	if len(authors) > 0 || b.Authors != nil {
+		b.Authors = authors
+	}
+
+	b.Publisher = strings.TrimSpace(b.Publisher)
+	b.Edition = strings.TrimSpace(b.Edition)
+	b.Language = strings.ToLower(strings.TrimSpace(b.Language))
+
+	if b.PageCount < 0 {
+		return rest_errors.NewBadRequestError("page_count must not be negative")
+	}
+	if b.PublicationDate != "" {
+		if _, err := time.Parse(publicationDateLayout, b.PublicationDate); err != nil {
+			return rest_errors.NewBadRequestError("publication_date must be yyyy-mm-dd")
+		}
+	}
+	return nil
+}
+
+// NormalizeIsbn validates the ISBN-10 or ISBN-13 checksum and returns the ISBN-13 digits.
+// Hyphens and spaces are ignored
+func NormalizeIsbn(isbn string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(digits) {
+	case 10:
+		if !isIsbn10(digits) {
+			return "", fmt.Errorf("invalid isbn %s", isbn)
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		if !isDigits(digits) || (!strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979")) ||
+			isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", fmt.Errorf("invalid isbn %s", isbn)
+		}
+		return digits, nil
+	}
+	return "", errors.New("isbn must have 10 or 13 digits")
+}
+
+// isIsbn10 checks the weighted sum modulo 11, the check digit X stands for 10
+func isIsbn10(digits string) bool {
+	if !isDigits(digits[:9]) {
+		return false
+	}
+	sum := 0
+	for idx := 0; idx < 9; idx++ {
+		sum += (10 - idx) * int(digits[idx]-'0')
+	}
+	switch check := digits[9]; {
+	case check == 'X':
+		sum += 10
+	case check >= '0' && check <= '9':
+		sum += int(check - '0')
+	default:
+		return false
+	}
+	return sum%11 == 0
+}
+
+// isbn13CheckDigit computes the EAN-13 check digit of 12 digits
+func isbn13CheckDigit(digits string) byte {
+	sum := 0
+	for idx := 0; idx < 12; idx++ {
+		weight := 1
+		if idx%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[idx]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package items
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeIsbn(t *testing.T) {
+	for _, tc := range []struct {
+		isbn     string
+		expected string
+	}{
+		{"0-306-40615-2", "9780306406157"},
+		{"978-0-306-40615-7", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"080442957x", "9780804429573"},
+		{"979 10 90636 07 1", "9791090636071"},
+	} {
+		isbn, err := NormalizeIsbn(tc.isbn)
+		assert.Nil(t, err, tc.isbn)
+		assert.Equal(t, tc.expected, isbn)
+	}
+
+	for _, isbn := range []string{"0-306-40615-3", "9780306406158", "9770306406150", "12345", "03064X6152", ""} {
+		_, err := NormalizeIsbn(isbn)
+		assert.NotNil(t, err, isbn)
+	}
+}
+
+func TestBookNormalize(t *testing.T) {
+	book := Book{Isbn: "0-306-40615-2", Authors: []string{" A. Author ", ""}, Language: " EN "}
+	assert.Nil(t, book.Normalize())
+	assert.Equal(t, "9780306406157", book.Isbn)
+	assert.Equal(t, []string{"A. Author"}, book.Authors)
+	assert.Equal(t, "en", book.Language)
+
+	for _, book := range []Book{
+		{Isbn: "123"},
+		{PageCount: -1},
+		{PublicationDate: "31.01.2020"},
+	} {
+		err := book.Normalize()
+		assert.Equal(t, http.StatusBadRequest, err.Status())
+	}
+}
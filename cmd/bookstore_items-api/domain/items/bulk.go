@@ -0,0 +1,289 @@
+package items
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const (
+	FormatNdjson = "ndjson"
+	FormatCsv    = "csv"
+
+	// longest accepted ndjson line
+	maxNdjsonLine = 1 << 20
+)
+
+// csv columns, pictures are urls separated by spaces and authors are separated by semicolons
+var csvColumns = []string{"id", "seller", "title", "description.plain_text", "description.html",
+	"pictures", "video", "price.amount", "price.currency", "available_quantity", "sold_quantity", "status",
+	"book.isbn", "book.authors", "book.publisher", "book.edition", "book.language",
+	"book.page_count", "book.publication_date"}
+
+// Row is a parsed import row, Err is set when the row is malformed
+type Row struct {
+	Number int
+	Item   Item
+	Err    rest_errors.RestErr
+}
+
+// ImportReport tells the outcome of every imported row, Error is set when
+// the import stopped before the end of the input
+type ImportReport struct {
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Rows    []ImportRow         `json:"rows"`
+	Error   rest_errors.RestErr `json:"error,omitempty"`
+}
+
+type ImportRow struct {
+	Row   int                 `json:"row"`
+	Id    string              `json:"id,omitempty"`
+	Error rest_errors.RestErr `json:"error,omitempty"`
+}
+
+// RowReader reads import rows one by one, io.EOF is returned after the last row
+type RowReader interface {
+	Read() (*Row, error)
+}
+
+// RowWriter writes exported items
+type RowWriter interface {
+	Write(Item) error
+	Flush() error
+}
+
+func ValidateFormat(format string) rest_errors.RestErr {
+	if format != FormatNdjson && format != FormatCsv {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported format %s, use ndjson or csv", format))
+	}
+	return nil
+}
+
+func NewRowReader(format string, r io.Reader) (RowReader, rest_errors.RestErr) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	if format == FormatCsv {
+		return newCsvReader(r)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNdjsonLine)
+	return &ndjsonReader{scanner: scanner}, nil
+}
+
+func NewRowWriter(format string, w io.Writer) (RowWriter, rest_errors.RestErr) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	if format == FormatCsv {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvColumns); err != nil {
+			return nil, rest_errors.NewInternalServerError("csv header error", err)
+		}
+		return &csvWriter{writer: writer}, nil
+	}
+	return &ndjsonWriter{writer: bufio.NewWriter(w)}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Read() (*Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		row := Row{Number: r.line}
+		if err := json.Unmarshal([]byte(line), &row.Item); err != nil {
+			row.Err = rest_errors.NewBadRequestError(err.Error())
+		}
+		return &row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+	row     int
+}
+
+// newCsvReader reads the header, it may list any subset of the known columns
+func newCsvReader(r io.Reader) (RowReader, rest_errors.RestErr) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, rest_errors.NewBadRequestError(fmt.Sprintf("bad csv header : %s", err.Error()))
+	}
+	for idx, column := range header {
+		header[idx] = strings.TrimSpace(column)
+		if !isCsvColumn(header[idx]) {
+			return nil, rest_errors.NewBadRequestError(fmt.Sprintf("unsupported csv column %s", column))
+		}
+	}
+	return &csvReader{reader: reader, columns: header}, nil
+}
+
+func isCsvColumn(column string) bool {
+	for _, c := range csvColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *csvReader) Read() (*Row, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.row++
+	row := Row{Number: r.row}
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); !ok {
+			return nil, err
+		}
+		row.Err = rest_errors.NewBadRequestError(err.Error())
+		return &row, nil
+	}
+	for idx, value := range record {
+		if err := setCsvValue(&row.Item, r.columns[idx], strings.TrimSpace(value)); err != nil {
+			row.Err = rest_errors.NewBadRequestError(fmt.Sprintf("bad %s value %q", r.columns[idx], value))
+			break
+		}
+	}
+	return &row, nil
+}
+
+func setCsvValue(it *Item, column string, value string) error {
+	var err error
+	switch column {
+	case "id":
+		it.Id = value
+	case "seller":
+		if value != "" {
+			it.Seller, err = strconv.ParseInt(value, 10, 64)
+		}
+	case "title":
+		it.Title = value
+	case "description.plain_text":
+		it.Description.PlainText = value
+	case "description.html":
+		it.Description.Html = value
+	case "pictures":
+		for idx, url := range strings.Fields(value) {
+			it.Pictures = append(it.Pictures, Picture{Id: int64(idx + 1), Url: url})
+		}
+	case "video":
+		it.Video = value
+	case "price.amount":
+		if value != "" {
+			it.Price.Amount, err = strconv.ParseInt(value, 10, 64)
+		}
+	case "price.currency":
+		it.Price.Currency = value
+	case "available_quantity":
+		if value != "" {
+			it.AvailableQuantity, err = strconv.Atoi(value)
+		}
+	case "sold_quantity":
+		if value != "" {
+			it.SoldQuantity, err = strconv.Atoi(value)
+		}
+	case "status":
+		it.Status = value
+	case "book.isbn":
+		it.Book.Isbn = value
+	case "book.authors":
+		if value != "" {
+			it.Book.Authors = strings.Split(value, ";")
+		}
+	case "book.publisher":
+		it.Book.Publisher = value
+	case "book.edition":
+		it.Book.Edition = value
+	case "book.language":
+		it.Book.Language = value
+	case "book.page_count":
+		if value != "" {
+			it.Book.PageCount, err = strconv.Atoi(value)
+		}
+	case "book.publication_date":
+		it.Book.PublicationDate = value
+	}
+	return err
+}
+
+type ndjsonWriter struct {
+	writer *bufio.Writer
+}
+
+func (w *ndjsonWriter) Write(it Item) error {
+	bytes, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	if _, err := w.writer.Write(bytes); err != nil {
+		return err
+	}
+	return w.writer.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(it Item) error {
+	urls := make([]string, len(it.Pictures))
+	for idx, picture := range it.Pictures {
+		urls[idx] = picture.Url
+	}
+	return w.writer.Write([]string{
+		it.Id,
+		strconv.FormatInt(it.Seller, 10),
+		it.Title,
+		it.Description.PlainText,
+		it.Description.Html,
+		strings.Join(urls, " "),
+		it.Video,
+		strconv.FormatInt(it.Price.Amount, 10),
+		it.Price.Currency,
+		strconv.Itoa(it.AvailableQuantity),
+		strconv.Itoa(it.SoldQuantity),
+		it.Status,
+		it.Book.Isbn,
+		strings.Join(it.Book.Authors, ";"),
+		it.Book.Publisher,
+		it.Book.Edition,
+		it.Book.Language,
+		strconv.Itoa(it.Book.PageCount),
+		it.Book.PublicationDate,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
@@ -0,0 +1,106 @@
+package items
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, reader RowReader) []Row {
+	rows := make([]Row, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		assert.Nil(t, err)
+		rows = append(rows, *row)
+	}
+}
+
+func TestNdjsonReader(t *testing.T) {
+	input := `{"title": "a", "price": {"amount": 1000, "currency": "EUR"}}
+
+not json
+{"title": "b", "available_quantity": 2}
+`
+	reader, err := NewRowReader(FormatNdjson, strings.NewReader(input))
+	assert.Nil(t, err)
+
+	rows := readAll(t, reader)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, 1, rows[0].Number)
+	assert.Equal(t, money.New(1000, "EUR"), rows[0].Item.Price)
+	assert.Equal(t, 3, rows[1].Number)
+	assert.Equal(t, http.StatusBadRequest, rows[1].Err.Status())
+	assert.Equal(t, 4, rows[2].Number)
+	assert.Equal(t, 2, rows[2].Item.AvailableQuantity)
+}
+
+func TestCsvReader(t *testing.T) {
+	input := `title, price.amount, price.currency, available_quantity, pictures
+Go in action, 1050, usd, 3, http://a http://b
+bad price, 10.5, USD, 1,
+too, many, columns, here, extra, more
+`
+	reader, err := NewRowReader(FormatCsv, strings.NewReader(input))
+	assert.Nil(t, err)
+
+	rows := readAll(t, reader)
+	assert.Len(t, rows, 3)
+	assert.Nil(t, rows[0].Err)
+	assert.Equal(t, "Go in action", rows[0].Item.Title)
+	assert.Equal(t, money.New(1050, "usd"), rows[0].Item.Price)
+	assert.Equal(t, []Picture{{Id: 1, Url: "http://a"}, {Id: 2, Url: "http://b"}}, rows[0].Item.Pictures)
+	assert.NotNil(t, rows[1].Err)
+	assert.NotNil(t, rows[2].Err)
+}
+
+func TestCsvReaderUnknownColumn(t *testing.T) {
+	_, err := NewRowReader(FormatCsv, strings.NewReader("title,color\n"))
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := NewRowReader("xml", strings.NewReader(""))
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+	_, err = NewRowWriter("xml", &bytes.Buffer{})
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+}
+
+func TestCsvRoundTrip(t *testing.T) {
+	item := Item{Id: "1", Seller: 7, Title: "Go, \"quoted\"", Description: Description{PlainText: "text"},
+		Pictures: []Picture{{Id: 1, Url: "http://a"}}, Price: money.New(999, "USD"), AvailableQuantity: 2, SoldQuantity: 1,
+		Status: StatusActive, Book: Book{Isbn: "9780306406157", Authors: []string{"A. Author", "B. Author"},
+			Publisher: "Pub", Language: "en", PageCount: 300, PublicationDate: "2020-01-31"}}
+
+	var buf bytes.Buffer
+	writer, err := NewRowWriter(FormatCsv, &buf)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Write(item))
+	assert.Nil(t, writer.Flush())
+
+	reader, err := NewRowReader(FormatCsv, &buf)
+	assert.Nil(t, err)
+	rows := readAll(t, reader)
+	assert.Len(t, rows, 1)
+	assert.Nil(t, rows[0].Err)
+	assert.Equal(t, item, rows[0].Item)
+}
+
+func TestNdjsonWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := NewRowWriter(FormatNdjson, &buf)
+	writer.Write(Item{Id: "1"})
+	writer.Write(Item{Id: "2"})
+	assert.Nil(t, writer.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"id":"2"`)
+}
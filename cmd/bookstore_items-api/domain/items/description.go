@@ -0,0 +1,165 @@
+package items
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Html descriptions are rendered by the storefront as is, so only the elements and
+// attributes below are kept. Elements out of the list are unwrapped keeping their text,
+// the dropped ones lose their content too. Links get rel="nofollow noopener noreferrer".
+var (
+	allowedElements = map[atom.Atom]bool{
+		atom.P: true, atom.Br: true, atom.Hr: true, atom.Div: true, atom.Span: true,
+		atom.B: true, atom.Strong: true, atom.I: true, atom.Em: true, atom.U: true, atom.S: true,
+		atom.Sub: true, atom.Sup: true, atom.Small: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+		atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+		atom.Blockquote: true, atom.Pre: true, atom.Code: true,
+		atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+		atom.A: true, atom.Img: true,
+	}
+
+	allowedAttributes = map[atom.Atom]map[string]bool{
+		atom.A:   {"href": true, "title": true},
+		atom.Img: {"src": true, "alt": true, "title": true, "width": true, "height": true},
+		atom.Th:  {"colspan": true, "rowspan": true},
+		atom.Td:  {"colspan": true, "rowspan": true},
+	}
+
+	// attributes holding urls, with the accepted schemes
+	urlAttributes = map[string]map[string]bool{
+		"href": {"http": true, "https": true, "mailto": true},
+		"src":  {"http": true, "https": true},
+	}
+
+	droppedElements = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true, atom.Embed: true,
+		atom.Noscript: true, atom.Template: true, atom.Textarea: true, atom.Select: true,
+		atom.Svg: true, atom.Math: true, atom.Head: true, atom.Title: true,
+	}
+
+	// elements separating words of the plain text
+	blockElements = map[atom.Atom]bool{
+		atom.P: true, atom.Br: true, atom.Hr: true, atom.Div: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+		atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+		atom.Blockquote: true, atom.Pre: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+	}
+)
+
+// Normalize sanitizes the html, a missing plain text is derived from the html
+func (d *Description) Normalize() rest_errors.RestErr {
+	d.PlainText = strings.TrimSpace(d.PlainText)
+	if strings.TrimSpace(d.Html) == "" {
+		d.Html = ""
+		return nil
+	}
+
+	sanitized, text, err := sanitizeHtml(d.Html)
+	if err != nil {
+		return rest_errors.NewBadRequestError("invalid description html")
+	}
+	d.Html = sanitized
+	if d.PlainText == "" {
+		d.PlainText = text
+	}
+	return nil
+}
+
+// sanitizeHtml returns the html reduced to the allowed elements and its text
+func sanitizeHtml(s string) (string, string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return "", "", err
+	}
+
+	var out, text strings.Builder
+	for _, n := range nodes {
+		sanitizeNode(n, &out, &text)
+	}
+	return strings.TrimSpace(out.String()), strings.Join(strings.Fields(text.String()), " "), nil
+}
+
+func sanitizeNode(n *html.Node, out, text *strings.Builder) {
+	switch n.Type {
+	case html.TextNode:
+		out.WriteString(html.EscapeString(n.Data))
+		text.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		// comments and doctypes
+		return
+	}
+
+	if droppedElements[n.DataAtom] {
+		return
+	}
+	allowed := allowedElements[n.DataAtom]
+	if blockElements[n.DataAtom] {
+		text.WriteString(" ")
+	}
+
+	if allowed {
+		out.WriteString("<" + n.Data)
+		for _, attr := range sanitizeAttributes(n) {
+			fmt.Fprintf(out, ` %s="%s"`, attr.Key, html.EscapeString(attr.Val))
+		}
+		out.WriteString(">")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(c, out, text)
+	}
+	if allowed && !isVoidElement(n.DataAtom) {
+		out.WriteString("</" + n.Data + ">")
+	}
+
+	if blockElements[n.DataAtom] {
+		text.WriteString(" ")
+	}
+}
+
+func sanitizeAttributes(n *html.Node) []html.Attribute {
+	attrs := make([]html.Attribute, 0, len(n.Attr))
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" || !allowedAttributes[n.DataAtom][attr.Key] {
+			continue
+		}
+		if schemes, ok := urlAttributes[attr.Key]; ok {
+			value, ok := safeUrl(attr.Val, schemes)
+			if !ok {
+				continue
+			}
+			attr.Val = value
+		}
+		attrs = append(attrs, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+	if n.DataAtom == atom.A {
+		attrs = append(attrs, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+	}
+	return attrs
+}
+
+// safeUrl accepts relative urls and absolute ones with an allowed scheme
+func safeUrl(value string, schemes map[string]bool) (string, bool) {
+	value = strings.TrimSpace(value)
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" && !schemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isVoidElement(a atom.Atom) bool {
+	return a == atom.Br || a == atom.Hr || a == atom.Img
+}
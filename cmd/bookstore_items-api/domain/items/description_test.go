@@ -0,0 +1,47 @@
+package items
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescriptionNormalizeSanitizes(t *testing.T) {
+	cases := []struct {
+		html     string
+		expected string
+	}{
+		{`<p>A <b>classic</b></p>`, `<p>A <b>classic</b></p>`},
+		{`<p onclick="alert(1)" style="color:red">x</p>`, `<p>x</p>`},
+		{`<script>alert(1)</script><p>x</p>`, `<p>x</p>`},
+		{`<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{`<img src="javascript:alert(1)" alt="cover">`, `<img alt="cover">`},
+		{`<a href=" JavaScript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" target="_blank">x</a>`,
+			`<a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer">x</a>`},
+		{`<iframe src="https://evil"></iframe><svg><script>alert(1)</script></svg>`, ``},
+		{`<form><input value="x">text</form>`, `text`},
+		{`<!-- comment --><p>&lt;script&gt;</p>`, `<p>&lt;script&gt;</p>`},
+		{`<b>unclosed <i>tags`, `<b>unclosed <i>tags</i></b>`},
+		{`<style>p {}</style>`, ``},
+	}
+	for _, c := range cases {
+		d := Description{Html: c.html, PlainText: "text"}
+		assert.Nil(t, d.Normalize(), c.html)
+		assert.Equal(t, c.expected, d.Html, c.html)
+	}
+}
+
+func TestDescriptionNormalizePlainText(t *testing.T) {
+	d := Description{Html: `<h2>The&nbsp;Hobbit</h2><p>by <b>Tolkien</b><br>first edition</p><ul><li>hardcover</li><li>signed</li></ul><script>x</script>`}
+	assert.Nil(t, d.Normalize())
+	assert.Equal(t, "The Hobbit by Tolkien first edition hardcover signed", d.PlainText)
+
+	d = Description{Html: `<p>html</p>`, PlainText: "  given  "}
+	assert.Nil(t, d.Normalize())
+	assert.Equal(t, "given", d.PlainText)
+
+	d = Description{Html: "  ", PlainText: "text"}
+	assert.Nil(t, d.Normalize())
+	assert.Equal(t, Description{PlainText: "text"}, d)
+}
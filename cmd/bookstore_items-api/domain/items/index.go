@@ -0,0 +1,335 @@
+package items
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/olivere/elastic"
+)
+
+// Items are stored in versioned indices items_v1, items_v2 ... and always
+// accessed through the items alias. A mapping change is rolled out by the
+// reindex command : it builds the next version and swaps the alias.
+
+const (
+	indexPrefix       = itemName + "_v"
+	firstIndexVersion = 1
+
+	// mappingVersion is kept in the mapping _meta and grows with every mapping change,
+	// indices without it were created before money prices
+	mappingVersion = 3
+)
+
+const itemsMapping = `{
+	"settings": {
+		"number_of_shards": 1,
+		"number_of_replicas": 1
+	},
+	"mappings": {
+		"_doc": {
+			"_meta": {"mapping_version": 3},
+			"dynamic": "strict",
+			"properties": {
+				"id": {"type": "keyword"},
+				"seller": {"type": "long"},
+				"title": {
+					"type": "text",
+					"fields": {
+						"keyword": {"type": "keyword", "ignore_above": 256},
+						"suggest": {
+							"type": "completion",
+							"contexts": [{"name": "status", "type": "category", "path": "status"}]
+						}
+					}
+				},
+				"description": {
+					"properties": {
+						"plain_text": {"type": "text"},
+						"html": {"type": "text", "index": false}
+					}
+				},
+				"pictures": {
+					"properties": {
+						"id": {"type": "long"},
+						"url": {"type": "keyword", "index": false},
+						"thumbnail_url": {"type": "keyword", "index": false}
+					}
+				},
+				"video": {"type": "keyword", "index": false},
+				"price": {
+					"properties": {
+						"amount": {"type": "long"},
+						"currency": {"type": "keyword"}
+					}
+				},
+				"available_quantity": {"type": "integer"},
+				"sold_quantity": {"type": "integer"},
+				"status": {"type": "keyword"},
+				"book": {
+					"properties": {
+						"isbn": {"type": "keyword"},
+						"authors": {
+							"type": "text",
+							"fields": {
+								"keyword": {"type": "keyword", "ignore_above": 256},
+								"suggest": {
+									"type": "completion",
+									"contexts": [{"name": "status", "type": "category", "path": "status"}]
+								}
+							}
+						},
+						"publisher": {
+							"type": "text",
+							"fields": {"keyword": {"type": "keyword", "ignore_above": 256}}
+						},
+						"edition": {"type": "keyword"},
+						"language": {"type": "keyword"},
+						"page_count": {"type": "integer"},
+						"publication_date": {"type": "date", "format": "yyyy-MM-dd"}
+					}
+				},
+				"revision": {"type": "long"},
+				"pending_events": {
+					"properties": {
+						"id": {"type": "keyword"},
+						"type": {"type": "keyword"},
+						"item_id": {"type": "keyword"},
+						"seller": {"type": "long"},
+						"version": {"type": "long"},
+						"occurred_at": {"type": "date"},
+						"item": {"type": "object", "enabled": false}
+					}
+				}
+			}
+		}
+	}
+}`
+
+// migrateScript upgrades documents written by older versions while they are reindexed :
+// a float price becomes money in minor units of the default currency,
+// the id field, the search sort tiebreaker, gets the document id,
+// the revision starts from the number of writes of the document
+var migrateScript = elastic.NewScript(`
+	if (ctx._source.id == null || ctx._source.id == '') {
+		ctx._source.id = ctx._id;
+	}
+	if (ctx._source.revision == null) {
+		ctx._source.revision = ctx._version;
+	}
+	if (ctx._source.price instanceof Number) {
+		ctx._source.price = ['amount': Math.round(ctx._source.price * params.scale), 'currency': params.currency];
+	}`).
+	Lang("painless").
+	Params(map[string]interface{}{
+		"currency": money.DefaultCurrency,
+		"scale":    money.Scale(money.DefaultCurrency),
+	})
+
+func IndexName(version int) string {
+	return indexPrefix + strconv.Itoa(version)
+}
+
+// EnsureIndex creates the first index version and the items alias on a fresh cluster.
+// An existing alias is left as is, it fails when the alias points to an older mapping :
+// the strict mapping would reject item writes until the reindex command upgrades it
+func EnsureIndex() error {
+	indices, err := es.Client.AliasIndices(itemName)
+	if err != nil {
+		return err
+	}
+	if len(indices) > 0 {
+		return checkMappingVersion(indices)
+	}
+
+	legacy, err := es.Client.IndexExists(itemName)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return errors.New("items is a plain index, run the reindex command to migrate it")
+	}
+
+	index := IndexName(firstIndexVersion)
+	exists, err := es.Client.IndexExists(index)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := es.Client.CreateIndex(index, itemsMapping); err != nil {
+			return err
+		}
+	}
+	return es.Client.UpdateAliases(elastic.NewAliasAddAction(itemName).Index(index))
+}
+
+// Reindex copies items into the next index version created with the current mapping, migrating
+// documents of older versions on the way, and moves the alias in a single atomic action so readers never see a missing index.
+// The first copy runs while items stay writable. Writes to the old indices are blocked for the final pass :
+// it copies the items changed since, removes the items deleted since and swaps the alias, item writes fail meanwhile.
+// The old indices stay read only. A legacy plain items index is migrated as well, it is dropped by the alias swap.
+func Reindex() (string, error) {
+	current, err := es.Client.AliasIndices(itemName)
+	if err != nil {
+		return "", err
+	}
+
+	legacy := false
+	if len(current) == 0 {
+		if legacy, err = es.Client.IndexExists(itemName); err != nil {
+			return "", err
+		}
+		if !legacy {
+			return "", errors.New("no items index to reindex")
+		}
+		current = []string{itemName}
+	}
+
+	next := IndexName(nextIndexVersion(current))
+	if err := es.Client.CreateIndex(next, itemsMapping); err != nil {
+		return "", err
+	}
+	if err := copyItems(current, next); err != nil {
+		return "", err
+	}
+
+	if err := es.Client.BlockWrites(current, true); err != nil {
+		return "", err
+	}
+	if err := finalPass(current, next, legacy); err != nil {
+		if unblockErr := es.Client.BlockWrites(current, false); unblockErr != nil {
+			return "", fmt.Errorf("%w, the writes to %v stay blocked : %v", err, current, unblockErr)
+		}
+		return "", err
+	}
+	return next, nil
+}
+
+// finalPass runs with the writes blocked, the old indices don't change anymore
+func finalPass(current []string, next string, legacy bool) error {
+	if err := copyItems(current, next); err != nil {
+		return err
+	}
+	if err := removeDeleted(current, next); err != nil {
+		return err
+	}
+
+	actions := make([]elastic.AliasAction, 0, len(current)+1)
+	if legacy {
+		actions = append(actions, elastic.NewAliasRemoveIndexAction(itemName))
+	} else {
+		for _, index := range current {
+			actions = append(actions, elastic.NewAliasRemoveAction(itemName).Index(index))
+		}
+	}
+	actions = append(actions, elastic.NewAliasAddAction(itemName).Index(next))
+	return es.Client.UpdateAliases(actions...)
+}
+
+// copyItems copies the items keeping their versions, a repeated copy only writes the items changed since
+func copyItems(from []string, to string) error {
+	for _, index := range from {
+		if _, err := es.Client.Reindex(index, to, migrateScript); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDeleted deletes the copied items which are gone from the old indices
+func removeDeleted(from []string, to string) error {
+	kept := make(map[string]bool)
+	for _, index := range from {
+		if err := scrollIds(index, func(ids []string) error {
+			for _, id := range ids {
+				kept[id] = true
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return scrollIds(to, func(ids []string) error {
+		requests := make([]elastic.BulkableRequest, 0)
+		for _, id := range ids {
+			if !kept[id] {
+				requests = append(requests, elastic.NewBulkDeleteRequest().Index(to).Type(typeItem).Id(id))
+			}
+		}
+		if len(requests) == 0 {
+			return nil
+		}
+		result, err := es.Client.Bulk(requests...)
+		if err != nil {
+			return err
+		}
+		if failed := result.Failed(); len(failed) > 0 {
+			return fmt.Errorf("remove deleted item %s : %s", failed[0].Id, failed[0].Error.Reason)
+		}
+		return nil
+	})
+}
+
+// scrollIds passes the document ids of the index page by page
+func scrollIds(index string, consume func([]string) error) error {
+	source := elastic.NewSearchSource().
+		FetchSource(false).
+		Size(exportBatchSize).
+		Sort("_doc", true)
+
+	scrollId := ""
+	defer func() {
+		if scrollId != "" {
+			es.Client.ClearScroll(scrollId)
+		}
+	}()
+
+	for {
+		result, err := es.Client.Scroll(index, source, scrollId)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		scrollId = result.ScrollId
+
+		ids := make([]string, len(result.Hits.Hits))
+		for idx, hit := range result.Hits.Hits {
+			ids[idx] = hit.Id
+		}
+		if err := consume(ids); err != nil {
+			return err
+		}
+	}
+}
+
+func checkMappingVersion(indices []string) error {
+	for _, index := range indices {
+		meta, err := es.Client.MappingMeta(index, typeItem)
+		if err != nil {
+			return err
+		}
+		version, _ := meta["mapping_version"].(float64)
+		if int(version) < mappingVersion {
+			return fmt.Errorf("index %s has items mapping version %d, version %d is required : run the reindex command",
+				index, int(version), mappingVersion)
+		}
+	}
+	return nil
+}
+
+func nextIndexVersion(indices []string) int {
+	version := firstIndexVersion - 1
+	for _, index := range indices {
+		if v, err := strconv.Atoi(strings.TrimPrefix(index, indexPrefix)); err == nil && v > version {
+			version = v
+		}
+	}
+	return version + 1
+}
@@ -0,0 +1,134 @@
+package items
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func mappedFields(prefix string, properties map[string]interface{}, fields queries.FieldSet) {
+	for name, def := range properties {
+		fields[prefix+name] = true
+		if nested, ok := def.(map[string]interface{})["properties"]; ok {
+			mappedFields(prefix+name+".", nested.(map[string]interface{}), fields)
+		}
+	}
+}
+
+// the mapping is strict, every item field must be mapped
+func TestItemsMappingCoversItem(t *testing.T) {
+	var mapping struct {
+		Mappings map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"mappings"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(itemsMapping), &mapping))
+
+	fields := make(queries.FieldSet)
+	mappedFields("", mapping.Mappings[typeItem].Properties, fields)
+	// the outbox of the item is stored in its document
+	for field := range fields {
+		if field == "revision" || strings.HasPrefix(field, "pending_events") {
+			delete(fields, field)
+		}
+	}
+
+	assert.Equal(t, queries.NewFieldSet(Item{}), fields)
+}
+
+func TestQueryFieldsAreIndexed(t *testing.T) {
+	for _, f := range []string{"title", "description.plain_text", "pictures.id", "price.amount", "price.currency",
+		"available_quantity", "status", "book.isbn", "book.authors", "book.publication_date"} {
+		assert.True(t, QueryFields.Contains(f), f)
+	}
+	for _, f := range []string{"description", "description.html", "pictures", "pictures.url", "pictures.thumbnail_url",
+		"video", "price", "book", "revision", "pending_events", "pending_events.type"} {
+		assert.False(t, QueryFields.Contains(f), f)
+	}
+}
+
+func TestItemsMappingVersion(t *testing.T) {
+	var mapping struct {
+		Mappings map[string]struct {
+			Meta map[string]interface{} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(itemsMapping), &mapping))
+	assert.EqualValues(t, mappingVersion, mapping.Mappings[typeItem].Meta["mapping_version"])
+}
+
+func TestEnsureIndexOutdatedMapping(t *testing.T) {
+	meta := ``
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/_alias/items":
+			w.Write([]byte(`{"items_v1": {"aliases": {"items": {}}}}`))
+		case "/items_v1/_mapping/_doc":
+			w.Write([]byte(`{"items_v1": {"mappings": {"_doc": {` + meta + `"properties": {}}}}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	err := EnsureIndex()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "run the reindex command")
+
+	meta = `"_meta": {"mapping_version": 3}, `
+	assert.Nil(t, EnsureIndex())
+}
+
+// items deleted from the old index during the copy are removed from the new one
+func TestRemoveDeleted(t *testing.T) {
+	hits := map[string]string{"items_v1": `"1", "3"`, "items_v2": `"1", "2", "3"`}
+	var deleted []string
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			index := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_search")
+			ids := strings.Split(hits[index], ", ")
+			docs := make([]string, len(ids))
+			for idx, id := range ids {
+				docs[idx] = `{"_index": "` + index + `", "_id": ` + id + `}`
+			}
+			w.Write([]byte(`{"_scroll_id": "` + index + `", "hits": {"hits": [` + strings.Join(docs, ", ") + `]}}`))
+		case r.URL.Path == "/_search/scroll" && r.Method == http.MethodPost:
+			w.Write([]byte(`{"_scroll_id": "done", "hits": {"hits": []}}`))
+		case strings.HasPrefix(r.URL.Path, "/_search/scroll"):
+			w.Write([]byte(`{"succeeded": true}`))
+		case r.URL.Path == "/_bulk":
+			lines := bufio.NewScanner(r.Body)
+			for lines.Scan() {
+				var action map[string]struct {
+					Index string `json:"_index"`
+					Id    string `json:"_id"`
+				}
+				json.Unmarshal(lines.Bytes(), &action)
+				assert.Equal(t, "items_v2", action["delete"].Index)
+				deleted = append(deleted, action["delete"].Id)
+			}
+			w.Write([]byte(`{"items": [{"delete": {"_index": "items_v2", "_id": "2", "status": 200}}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	assert.Nil(t, removeDeleted([]string{"items_v1"}, "items_v2"))
+	assert.Equal(t, []string{"2"}, deleted)
+}
+
+func TestNextIndexVersion(t *testing.T) {
+	assert.Equal(t, 1, nextIndexVersion([]string{itemName}))
+	assert.Equal(t, 2, nextIndexVersion([]string{IndexName(1)}))
+	assert.Equal(t, 4, nextIndexVersion([]string{IndexName(3), IndexName(2)}))
+	assert.Equal(t, "items_v2", IndexName(2))
+}
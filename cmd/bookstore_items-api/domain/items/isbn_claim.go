@@ -0,0 +1,66 @@
+package items
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// A seller lists a book once. The listing item claims the seller isbn with a document
+// whose id is derived from both, created only if absent, so concurrent listings
+// of the same book can not both succeed.
+
+const (
+	claimsName = "item_isbns"
+
+	// claims younger than the grace period may belong to items being written
+	claimGracePeriod = time.Minute
+)
+
+const claimsMapping = `{
+	"mappings": {
+		"_doc": {
+			"dynamic": "strict",
+			"properties": {
+				"item_id": {"type": "keyword"},
+				"date_created": {"type": "date"}
+			}
+		}
+	}
+}`
+
+type IsbnClaim struct {
+	ItemId      string    `json:"item_id"`
+	DateCreated time.Time `json:"date_created"`
+
+	// stored revision, not a part of the document
+	Version *es.DocVersion `json:"-"`
+}
+
+func claimId(seller int64, isbn string) string {
+	return fmt.Sprintf("%d-%s", seller, isbn)
+}
+
+// isStale tells whether the claim outlived its item : the item is deleted, archived
+// or does not list the isbn of the seller anymore
+func (c IsbnClaim) isStale(owner *Item, seller int64, isbn string, now time.Time) bool {
+	if now.Sub(c.DateCreated) < claimGracePeriod {
+		return false
+	}
+	return owner == nil || owner.Status == StatusArchived || owner.Seller != seller || owner.Book.Isbn != isbn
+}
+
+func errDuplicateIsbn(isbn string) rest_errors.RestErr {
+	return rest_errors.NewConflictError(fmt.Sprintf("isbn %s is already listed by the seller", isbn))
+}
+
+// EnsureClaimsIndex creates the isbn claims index on a fresh cluster
+func EnsureClaimsIndex() error {
+	exists, err := es.Client.IndexExists(claimsName)
+	if err != nil || exists {
+		return err
+	}
+	return es.Client.CreateIndex(claimsName, claimsMapping)
+}
@@ -1,5 +1,18 @@
 package items
 
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+)
+
+// fields clients are allowed to filter items on, the ones the mapping indexes
+var QueryFields = queryFields()
+
 type Item struct {
 	Id                string      `json:"id"`
 	Seller            int64       `json:"seller"`
@@ -7,10 +20,38 @@ type Item struct {
 	Description       Description `json:"description"`
 	Pictures          []Picture   `json:"pictures"`
 	Video             string      `json:"video"`
-	Price             float32     `json:"price"`
+	Price             money.Money `json:"price"`
 	AvailableQuantity int         `json:"available_quantity"`
 	SoldQuantity      int         `json:"sold_quantity"`
 	Status            string      `json:"status"`
+	Book              Book        `json:"book"`
+
+	// stored revision, not a part of the document
+	Version *es.DocVersion `json:"-"`
+	// Sent holds the json fields of a partial update request, a zero value sent is a change too
+	Sent map[string]bool `json:"-"`
+	// Revision counts the changes of the item, the events of the changes not published yet
+	// are PendingEvents. Both are stored in the item document but are not a part of the item
+	Revision      int64             `json:"-"`
+	PendingEvents []json.RawMessage `json:"-"`
+}
+
+// itemDoc is the stored item. The events of a change are written with the item
+// in the same document write, they are not lost when the service stops after it
+type itemDoc struct {
+	Item
+	Revision      int64             `json:"revision"`
+	PendingEvents []json.RawMessage `json:"pending_events,omitempty"`
+}
+
+func docOf(it Item) itemDoc {
+	return itemDoc{Item: it, Revision: it.Revision, PendingEvents: it.PendingEvents}
+}
+
+func (d itemDoc) item() Item {
+	it := d.Item
+	it.Revision, it.PendingEvents = d.Revision, d.PendingEvents
+	return it
 }
 
 type Description struct {
@@ -19,6 +60,49 @@ type Description struct {
 }
 
 type Picture struct {
-	Id  int64  `json:"id"`
-	Url string `json:"url"`
+	Id           int64  `json:"id"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url,omitempty"`
+}
+
+type SearchResult struct {
+	Total        int64                  `json:"total"`
+	Page         int                    `json:"page"`
+	Size         int                    `json:"size"`
+	Items        []Item                 `json:"items"`
+	SearchAfter  []interface{}          `json:"search_after,omitempty"`
+	Aggregations map[string]Aggregation `json:"aggregations,omitempty"`
+	// item id -> field -> fragments with the matched words, for free text searches
+	Highlights map[string]map[string][]string `json:"highlights,omitempty"`
+}
+
+type Aggregation struct {
+	Type    string   `json:"type"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket key is a term for terms aggregations, the range key or the histogram interval start.
+// From/To are set for range buckets only
+type Bucket struct {
+	Key      interface{} `json:"key"`
+	From     *float64    `json:"from,omitempty"`
+	To       *float64    `json:"to,omitempty"`
+	DocCount int64       `json:"doc_count"`
+}
+
+func queryFields() queries.FieldSet {
+	fields, err := queries.NewMappedFieldSet(itemsMapping, typeItem, "revision", "pending_events")
+	if err != nil {
+		panic(err)
+	}
+	return fields
+}
+
+// NewItemId returns a random url safe id of the length of the elasticsearch generated ones
+func NewItemId() (string, error) {
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
@@ -1,72 +1,514 @@
 package items
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
 
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/olivere/elastic"
 )
 
 const (
 	itemName = "items"
 	typeItem = "_doc"
+
+	// items read per scroll page on export
+	exportBatchSize = 500
 )
 
 type ItemsPersistInterface interface {
 	Save(it *Item) rest_errors.RestErr
+	SaveAll([]Item) []rest_errors.RestErr
 	Get(Item) (*Item, rest_errors.RestErr)
-	Search(q queries.EsQuery) ([]Item, rest_errors.RestErr)
+	Search(q queries.EsQuery) (*SearchResult, rest_errors.RestErr)
+	Export(int64, func([]Item) rest_errors.RestErr) rest_errors.RestErr
+	Suggest(string, int) (*SuggestResult, rest_errors.RestErr)
+	Related(Item, int) ([]Item, rest_errors.RestErr)
+	// Update and Delete are conditional on it.Version when set,
+	// a stale version fails with rest_errors.NewPreconditionFailedError
+	Update(it *Item) rest_errors.RestErr
+	Delete(it *Item) rest_errors.RestErr
+	// ClaimIsbn reserves the book isbn of it.Seller for it.Id, the claim of another
+	// live item of the seller fails with rest_errors.NewConflictError
+	ClaimIsbn(it Item) rest_errors.RestErr
+	// ReleaseIsbn removes the claim of the item on the isbn, claims of other items are kept
+	ReleaseIsbn(it Item, isbn string) rest_errors.RestErr
+	// WithPendingEvents returns items having events not published yet
+	WithPendingEvents(int) ([]Item, rest_errors.RestErr)
 }
 type persist struct {
+	suggestTimeout time.Duration
 }
 
-func NewItemPersister() ItemsPersistInterface {
-	return new(persist)
+// NewItemPersister stores items in elasticsearch, suggestTimeout is the latency budget of suggestions
+func NewItemPersister(suggestTimeout time.Duration) ItemsPersistInterface {
+	return &persist{suggestTimeout: suggestTimeout}
 }
 
+// Save creates the item under a new id unless it.Id is preset, the id is kept in the source
+// as well so that searches can sort on it
 func (p *persist) Save(it *Item) rest_errors.RestErr {
-	result, err := es.Client.Index(itemName, typeItem, it)
+	preset := it.Id != ""
+	if !preset {
+		id, err := NewItemId()
+		if err != nil {
+			return rest_errors.NewInternalServerError("save item error", err)
+		}
+		it.Id = id
+	}
+	result, err := es.Client.Create(itemName, typeItem, it.Id, docOf(*it))
 	if err != nil {
-		rest_errors.NewInternalServerError("save item error", err)
+		if !preset {
+			it.Id = ""
+		}
+		if elastic.IsConflict(err) {
+			return rest_errors.NewConflictError("item already exists")
+		}
+		return rest_errors.NewInternalServerError("save item error", err)
 	}
-	it.Id = result.Id
+	it.Version = &es.DocVersion{SeqNo: result.SeqNo, PrimaryTerm: result.PrimaryTerm}
 	return nil
 }
 
+// SaveAll indexes the items with one bulk request. Saved items get their ids unless preset,
+// the result holds an error for every item which could not be saved
+func (p *persist) SaveAll(its []Item) []rest_errors.RestErr {
+	errs := make([]rest_errors.RestErr, len(its))
+	if len(its) == 0 {
+		return errs
+	}
+
+	// items which got their ids here, the ids are cleared again on failure
+	generated := make([]bool, len(its))
+	requests := make([]elastic.BulkableRequest, len(its))
+	for idx := range its {
+		if its[idx].Id == "" {
+			id, err := NewItemId()
+			if err != nil {
+				return failAll(its, generated, errs, err)
+			}
+			its[idx].Id, generated[idx] = id, true
+		}
+		requests[idx] = elastic.NewBulkIndexRequest().Index(itemName).Type(typeItem).
+			Id(its[idx].Id).OpType("create").Doc(docOf(its[idx]))
+	}
+
+	result, err := es.Client.Bulk(requests...)
+	if err != nil {
+		return failAll(its, generated, errs, err)
+	}
+
+	// response items follow the order of requests
+	for idx, item := range result.Items {
+		created := item["create"]
+		if created == nil || created.Error != nil {
+			reason := "no bulk response"
+			if created != nil {
+				reason = created.Error.Reason
+			}
+			if generated[idx] {
+				its[idx].Id = ""
+			}
+			errs[idx] = rest_errors.NewInternalServerError("save item error", errors.New(reason))
+			continue
+		}
+		its[idx].Version = &es.DocVersion{SeqNo: created.SeqNo, PrimaryTerm: created.PrimaryTerm}
+	}
+	return errs
+}
+
+func failAll(its []Item, generated []bool, errs []rest_errors.RestErr, err error) []rest_errors.RestErr {
+	for idx := range errs {
+		if generated[idx] {
+			its[idx].Id = ""
+		}
+		errs[idx] = rest_errors.NewInternalServerError("save items error", err)
+	}
+	return errs
+}
+
 func (p *persist) Get(it Item) (*Item, rest_errors.RestErr) {
 	result, err := es.Client.Get(itemName, typeItem, it.Id)
 	if err != nil {
-		return nil, rest_errors.NewInternalServerError("save item error", err)
+		if elastic.IsNotFound(err) {
+			return nil, rest_errors.NewNotFoundError("item not found")
+		}
+		return nil, rest_errors.NewInternalServerError("get item error", err)
+	}
+
+	bytes, err := result.Source.MarshalJSON()
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
+	}
+	var doc itemDoc
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
 	}
 
+	it = doc.item()
 	it.Id = result.Id
+	it.Version = es.VersionOf(result)
 	return &it, nil
 }
 
-func (p *persist) Search(q queries.EsQuery) ([]Item, rest_errors.RestErr) {
-	result, err := es.Client.Search(itemName, q.Build())
+// tooManyBuckets returns the name of the first histogram when elasticsearch rejected the search
+// for exceeding search.max_buckets, empty otherwise
+func tooManyBuckets(err error, aggs []queries.Aggregation) string {
+	e, ok := err.(*elastic.Error)
+	if !ok || e.Details == nil {
+		return ""
+	}
+	causes := append([]*elastic.ErrorDetails{e.Details}, e.Details.RootCause...)
+	for _, cause := range causes {
+		if cause.Type != "too_many_buckets_exception" {
+			continue
+		}
+		for _, agg := range aggs {
+			if agg.Type == queries.AggHistogram {
+				return agg.Name
+			}
+		}
+	}
+	return ""
+}
+
+// badRequest returns the reason of an elasticsearch 400, a query it refused
+func badRequest(err error) string {
+	e, ok := err.(*elastic.Error)
+	if !ok || e.Status != http.StatusBadRequest {
+		return ""
+	}
+	if e.Details == nil {
+		return http.StatusText(http.StatusBadRequest)
+	}
+	// the top reason of a search is "all shards failed", the root cause tells what is wrong
+	for _, cause := range e.Details.RootCause {
+		if cause.Reason != "" {
+			return cause.Reason
+		}
+	}
+	return e.Details.Reason
+}
+
+func (p *persist) Search(q queries.EsQuery) (*SearchResult, rest_errors.RestErr) {
+	result, err := es.Client.Search(itemName, q.Source())
+	if err != nil {
+		if name := tooManyBuckets(err, q.Aggregations); name != "" {
+			return nil, queries.TooManyBuckets(name)
+		}
+		if reason := badRequest(err); reason != "" {
+			return nil, rest_errors.NewBadRequestError(fmt.Sprintf("bad search : %s", reason))
+		}
+		return nil, rest_errors.NewInternalServerError("search items error", errors.New("ELK error"))
+	}
+
+	items, err := itemsOf(result.Hits.Hits)
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse search response error", errors.New("ELK error"))
+	}
+
+	searchResult := SearchResult{
+		Total: result.TotalHits(),
+		Page:  q.PageNumber(),
+		Size:  q.PageSize(),
+		Items: items,
+	}
+	if len(result.Hits.Hits) == q.PageSize() {
+		searchResult.SearchAfter = result.Hits.Hits[len(result.Hits.Hits)-1].Sort
+	}
+	if len(q.Aggregations) > 0 {
+		searchResult.Aggregations = parseAggregations(q.Aggregations, result.Aggregations)
+	}
+	for _, hit := range result.Hits.Hits {
+		if len(hit.Highlight) == 0 {
+			continue
+		}
+		if searchResult.Highlights == nil {
+			searchResult.Highlights = make(map[string]map[string][]string)
+		}
+		searchResult.Highlights[hit.Id] = hit.Highlight
+	}
+	return &searchResult, nil
+}
+
+// Related returns up to size active items of other sellers similar to the item by title and description
+func (p *persist) Related(it Item, size int) ([]Item, rest_errors.RestErr) {
+	result, err := es.Client.Search(itemName, relatedSource(it, size))
 	if err != nil {
-		return nil, rest_errors.NewInternalServerError("save item error", errors.New("ELK error"))
+		return nil, rest_errors.NewInternalServerError("related items error", err)
 	}
 
-	items := make([]Item, result.TotalHits())
+	items, err := itemsOf(result.Hits.Hits)
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse search response error", err)
+	}
+	return items, nil
+}
+
+func itemsOf(hits []*elastic.SearchHit) ([]Item, error) {
+	items := make([]Item, len(hits))
+	for idx, hit := range hits {
+		bytes, _ := hit.Source.MarshalJSON()
+		if err := json.Unmarshal(bytes, &items[idx]); err != nil {
+			return nil, err
+		}
+		items[idx].Id = hit.Id
+	}
+	return items, nil
+}
+
+// WithPendingEvents searches the items having events, with their versions so that
+// the events are cleared only if no change has been made meanwhile
+func (p *persist) WithPendingEvents(limit int) ([]Item, rest_errors.RestErr) {
+	source := elastic.NewSearchSource().
+		Query(elastic.NewExistsQuery("pending_events.id")).
+		Size(limit).
+		SeqNoAndPrimaryTerm(true)
+
+	result, err := es.Client.Search(itemName, source)
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("search items error", err)
+	}
+
+	ls := make([]Item, len(result.Hits.Hits))
 	for idx, hit := range result.Hits.Hits {
+		var doc itemDoc
 		bytes, _ := hit.Source.MarshalJSON()
+		if err := json.Unmarshal(bytes, &doc); err != nil {
+			return nil, rest_errors.NewInternalServerError("elk parse search response error", err)
+		}
+		ls[idx] = doc.item()
+		ls[idx].Id = hit.Id
+		ls[idx].Version = es.HitVersion(hit)
+	}
+	return ls, nil
+}
+
+// Export scrolls over all items of the seller and passes them to consume page by page
+func (p *persist) Export(seller int64, consume func([]Item) rest_errors.RestErr) rest_errors.RestErr {
+	source := elastic.NewSearchSource().
+		Query(elastic.NewBoolQuery().Filter(elastic.NewTermQuery("seller", seller))).
+		Size(exportBatchSize).
+		Sort("_doc", true)
 
-		var item Item
-		if err = json.Unmarshal(bytes, &item); err != nil {
-			return nil, rest_errors.NewInternalServerError("elk parse search response error", errors.New("ELK error"))
+	scrollId := ""
+	defer func() {
+		if scrollId != "" {
+			es.Client.ClearScroll(scrollId)
 		}
+	}()
 
-		item.Id = hit.Id
-		items[idx] = item
+	for {
+		result, err := es.Client.Scroll(itemName, source, scrollId)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return rest_errors.NewInternalServerError("export items error", err)
+		}
+		scrollId = result.ScrollId
+
+		items, err := itemsOf(result.Hits.Hits)
+		if err != nil {
+			return rest_errors.NewInternalServerError("elk parse scroll response error", err)
+		}
+		if err := consume(items); err != nil {
+			return err
+		}
 	}
+}
 
-	if len(items) == 0 {
-		return nil, rest_errors.NewNotFoundError("not found")
+// Suggest completes the prefix with titles and authors of active items.
+// Suggestions are optional for the client, no suggestions are returned when the latency budget is over
+func (p *persist) Suggest(prefix string, size int) (*SuggestResult, rest_errors.RestErr) {
+	suggesters := make([]elastic.Suggester, len(suggestFields))
+	for idx, f := range suggestFields {
+		suggesters[idx] = elastic.NewCompletionSuggester(f.Field).
+			Field(f.Path).
+			Prefix(prefix).
+			Size(size).
+			SkipDuplicates(true).
+			ContextQuery(elastic.NewSuggesterCategoryQuery(statusContext, StatusActive))
 	}
 
-	return items, nil
+	result := SuggestResult{Prefix: prefix, Suggestions: make([]Suggestion, 0)}
+	suggest, err := es.Client.Suggest(itemName, p.suggestTimeout, suggesters...)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Info("suggest timed out")
+			return &result, nil
+		}
+		return nil, rest_errors.NewInternalServerError("suggest items error", err)
+	}
+
+	lists := make([][]Suggestion, len(suggestFields))
+	for idx, f := range suggestFields {
+		for _, entry := range suggest[f.Field] {
+			for _, option := range entry.Options {
+				lists[idx] = append(lists[idx], Suggestion{
+					Text:  option.Text,
+					Id:    option.Id,
+					Field: f.Field,
+					Score: option.ScoreUnderscore,
+				})
+			}
+		}
+	}
+	result.Suggestions = topSuggestions(size, lists...)
+	return &result, nil
+}
+
+func parseAggregations(requested []queries.Aggregation, aggs elastic.Aggregations) map[string]Aggregation {
+	result := make(map[string]Aggregation, len(requested))
+	for _, rq := range requested {
+		agg := Aggregation{Type: rq.Type, Buckets: make([]Bucket, 0)}
+		switch rq.Type {
+		case queries.AggTerms:
+			if items, found := aggs.Terms(rq.Name); found {
+				for _, b := range items.Buckets {
+					agg.Buckets = append(agg.Buckets, Bucket{Key: b.Key, DocCount: b.DocCount})
+				}
+			}
+		case queries.AggRange:
+			if items, found := aggs.Range(rq.Name); found {
+				for _, b := range items.Buckets {
+					agg.Buckets = append(agg.Buckets, Bucket{Key: b.Key, From: b.From, To: b.To, DocCount: b.DocCount})
+				}
+			}
+		case queries.AggHistogram:
+			if items, found := aggs.Histogram(rq.Name); found {
+				for _, b := range items.Buckets {
+					agg.Buckets = append(agg.Buckets, Bucket{Key: b.Key, DocCount: b.DocCount})
+				}
+			}
+		}
+		result[rq.Name] = agg
+	}
+	return result
+}
+
+// Update writes the item, when it.Version is set the write fails with precondition failed
+// if the stored item has been changed since it was read
+func (p *persist) Update(it *Item) rest_errors.RestErr {
+	result, err := es.Client.Update(itemName, typeItem, it.Id, docOf(*it), it.Version)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return rest_errors.NewNotFoundError("item not found")
+		}
+		if elastic.IsConflict(err) {
+			return rest_errors.NewPreconditionFailedError("item has been modified concurrently")
+		}
+		return rest_errors.NewInternalServerError("update item error", err)
+	}
+	it.Version = &es.DocVersion{SeqNo: result.SeqNo, PrimaryTerm: result.PrimaryTerm}
+	return nil
+}
+
+// Delete removes the item, it.Version is set to the revision of the delete
+func (p *persist) Delete(it *Item) rest_errors.RestErr {
+	result, err := es.Client.Delete(itemName, typeItem, it.Id, it.Version)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return rest_errors.NewNotFoundError("item not found")
+		}
+		if elastic.IsConflict(err) {
+			return rest_errors.NewPreconditionFailedError("item has been modified concurrently")
+		}
+		return rest_errors.NewInternalServerError("delete item error", err)
+	}
+	it.Version = &es.DocVersion{SeqNo: result.SeqNo, PrimaryTerm: result.PrimaryTerm}
+	return nil
+}
+
+// ClaimIsbn creates the claim document of the seller isbn. An existing claim is taken over
+// only when it is stale, with a versioned write so that one of concurrent claimers wins
+func (p *persist) ClaimIsbn(it Item) rest_errors.RestErr {
+	id := claimId(it.Seller, it.Book.Isbn)
+	claim := IsbnClaim{ItemId: it.Id, DateCreated: time.Now().UTC()}
+	_, err := es.Client.Create(claimsName, typeItem, id, claim)
+	if err == nil {
+		return nil
+	}
+	if !elastic.IsConflict(err) {
+		return rest_errors.NewInternalServerError("claim isbn error", err)
+	}
+
+	current, restErr := p.getClaim(id)
+	if restErr != nil {
+		if restErr.Status() == http.StatusNotFound {
+			// released meanwhile
+			return errDuplicateIsbn(it.Book.Isbn)
+		}
+		return restErr
+	}
+	if current.ItemId != it.Id {
+		owner, restErr := p.Get(Item{Id: current.ItemId})
+		if restErr != nil && restErr.Status() != http.StatusNotFound {
+			return restErr
+		}
+		if !current.isStale(owner, it.Seller, it.Book.Isbn, claim.DateCreated) {
+			return errDuplicateIsbn(it.Book.Isbn)
+		}
+	}
+
+	// the own claim is renewed, a concurrent take over fails on its version
+	if _, err := es.Client.Update(claimsName, typeItem, id, claim, current.Version); err != nil {
+		if elastic.IsConflict(err) {
+			return errDuplicateIsbn(it.Book.Isbn)
+		}
+		return rest_errors.NewInternalServerError("claim isbn error", err)
+	}
+	return nil
+}
+
+// ReleaseIsbn deletes the claim if the item holds it
+func (p *persist) ReleaseIsbn(it Item, isbn string) rest_errors.RestErr {
+	id := claimId(it.Seller, isbn)
+	current, err := p.getClaim(id)
+	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	if current.ItemId != it.Id {
+		return nil
+	}
+	if _, err := es.Client.Delete(claimsName, typeItem, id, current.Version); err != nil {
+		// taken over or released meanwhile
+		if elastic.IsConflict(err) || elastic.IsNotFound(err) {
+			return nil
+		}
+		return rest_errors.NewInternalServerError("release isbn error", err)
+	}
+	return nil
+}
+
+func (p *persist) getClaim(id string) (*IsbnClaim, rest_errors.RestErr) {
+	result, err := es.Client.Get(claimsName, typeItem, id)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, rest_errors.NewNotFoundError("isbn claim not found")
+		}
+		return nil, rest_errors.NewInternalServerError("get isbn claim error", err)
+	}
+
+	var claim IsbnClaim
+	bytes, err := result.Source.MarshalJSON()
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
+	}
+	if err := json.Unmarshal(bytes, &claim); err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
+	}
+	claim.Version = es.VersionOf(result)
+	return &claim, nil
 }
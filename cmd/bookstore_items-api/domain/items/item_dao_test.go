@@ -0,0 +1,174 @@
+package items
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAggregations(t *testing.T) {
+	response := `{
+		"by_status": {"buckets": [{"key": "active", "doc_count": 3}, {"key": "paused", "doc_count": 1}]},
+		"by_price": {"buckets": [{"key": "cheap", "to": 10, "doc_count": 2}, {"key": "expensive", "from": 10, "doc_count": 2}]},
+		"price_histogram": {"buckets": [{"key": 0, "doc_count": 1}, {"key": 5, "doc_count": 3}]}
+	}`
+	var aggs elastic.Aggregations
+	assert.Nil(t, json.Unmarshal([]byte(response), &aggs))
+
+	result := parseAggregations([]queries.Aggregation{
+		{Name: "by_status", Type: queries.AggTerms},
+		{Name: "by_price", Type: queries.AggRange},
+		{Name: "price_histogram", Type: queries.AggHistogram},
+		{Name: "missing", Type: queries.AggTerms},
+	}, aggs)
+
+	assert.Equal(t, 4, len(result))
+
+	assert.Equal(t, "active", result["by_status"].Buckets[0].Key)
+	assert.EqualValues(t, 3, result["by_status"].Buckets[0].DocCount)
+
+	assert.Equal(t, "cheap", result["by_price"].Buckets[0].Key)
+	assert.Nil(t, result["by_price"].Buckets[0].From)
+	assert.EqualValues(t, 10, *result["by_price"].Buckets[0].To)
+
+	assert.EqualValues(t, 5, result["price_histogram"].Buckets[1].Key)
+	assert.EqualValues(t, 3, result["price_histogram"].Buckets[1].DocCount)
+
+	assert.Equal(t, 0, len(result["missing"].Buckets))
+}
+
+func TestRelatedSource(t *testing.T) {
+	source, err := relatedSource(Item{Id: "1", Seller: 7}, 5).Source()
+	assert.Nil(t, err)
+
+	bytes, _ := json.Marshal(source)
+	assert.JSONEq(t, `{
+		"query": {"bool": {
+			"must": {"more_like_this": {
+				"fields": ["title", "description.plain_text"],
+				"like": [{"_index": "items", "_type": "_doc", "_id": "1"}],
+				"min_term_freq": 1,
+				"min_doc_freq": 1
+			}},
+			"filter": {"term": {"status": "active"}},
+			"must_not": {"term": {"seller": 7}}
+		}},
+		"size": 5
+	}`, string(bytes))
+}
+
+// esServer points the elasticsearch client to a test server answering with the handler
+func esServer(t *testing.T, handler http.HandlerFunc) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := elastic.NewClient(elastic.SetURL(srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Client.SetClient(c)
+}
+
+func TestSaveCreatesWithId(t *testing.T) {
+	var path, opType string
+	var doc map[string]interface{}
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		path, opType = r.URL.Path, r.URL.Query().Get("op_type")
+		json.NewDecoder(r.Body).Decode(&doc)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"_index": "items_v1", "_type": "_doc", "_id": "x", "_seq_no": 4, "_primary_term": 1, "result": "created"}`))
+	})
+
+	it := Item{Title: "The Hobbit"}
+	assert.Nil(t, NewItemPersister(time.Second).Save(&it))
+
+	assert.Equal(t, 20, len(it.Id))
+	assert.Equal(t, "/items/_doc/"+it.Id, path)
+	assert.Equal(t, "create", opType)
+	assert.Equal(t, it.Id, doc["id"], "the sort tiebreaker")
+	assert.EqualValues(t, 4, it.Version.SeqNo)
+}
+
+func TestSaveFailedKeepsNoId(t *testing.T) {
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	it := Item{Title: "The Hobbit"}
+	assert.NotNil(t, NewItemPersister(time.Second).Save(&it))
+	assert.Empty(t, it.Id)
+}
+
+func TestSearchTooManyBuckets(t *testing.T) {
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed",
+			"root_cause": [{"type": "too_many_buckets_exception", "reason": "Trying to create too many buckets"}]}, "status": 503}`))
+	})
+
+	_, err := NewItemPersister(time.Second).Search(queries.EsQuery{Aggregations: []queries.Aggregation{
+		{Name: "histogram", Type: queries.AggHistogram, Field: "price", Interval: 1},
+	}})
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+}
+
+func TestSearchBadRequest(t *testing.T) {
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed",
+			"root_cause": [{"type": "illegal_argument_exception", "reason": "Text fields are not optimised"}]}, "status": 400}`))
+	})
+
+	_, err := NewItemPersister(time.Second).Search(queries.EsQuery{})
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	assert.Contains(t, err.Message(), "Text fields are not optimised")
+}
+
+func TestClaimIsbnTakesOverStaleClaim(t *testing.T) {
+	owner := `{"_index": "items_v1", "_type": "_doc", "_id": "old", "found": false}`
+	var takeover string
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/item_isbns/_doc/1-9780306406157" && r.URL.Query().Get("op_type") == "create":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": {"type": "version_conflict_engine_exception", "reason": "exists"}, "status": 409}`))
+		case r.URL.Path == "/item_isbns/_doc/1-9780306406157" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"_index": "item_isbns", "_type": "_doc", "_id": "1-9780306406157", "_seq_no": 3, "_primary_term": 1,
+				"found": true, "_source": {"item_id": "old", "date_created": "2020-01-01T00:00:00Z"}}`))
+		case r.URL.Path == "/items/_doc/old":
+			if owner == "" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			w.Write([]byte(owner))
+		case r.URL.Path == "/item_isbns/_doc/1-9780306406157":
+			takeover = r.URL.Query().Get("if_seq_no")
+			w.Write([]byte(`{"_index": "item_isbns", "_type": "_doc", "_id": "1-9780306406157", "_seq_no": 4, "_primary_term": 1, "result": "updated"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	it := Item{Id: "new", Seller: 1, Book: Book{Isbn: "9780306406157"}}
+
+	// the claimed item still lists the book
+	owner = `{"_index": "items_v1", "_type": "_doc", "_id": "old", "_seq_no": 1, "_primary_term": 1, "found": true,
+		"_source": {"seller": 1, "status": "active", "book": {"isbn": "9780306406157"}}}`
+	err := NewItemPersister(time.Second).ClaimIsbn(it)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.Status())
+	assert.Equal(t, "", takeover)
+
+	// the claimed item is gone
+	owner = ""
+	assert.Nil(t, NewItemPersister(time.Second).ClaimIsbn(it))
+	assert.Equal(t, "3", takeover)
+}
@@ -0,0 +1,782 @@
+package items
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// memoryPersist keeps items in the process memory, no elasticsearch is required.
+// Queries are evaluated close to elasticsearch over the dynamic mapping :
+// match compares lower cased words, range compares numbers or strings,
+// sorting always ends with the id tiebreaker as the elastic backend does.
+// Writes are versioned with a sequence number like a single shard index.
+type memoryPersist struct {
+	mu     sync.RWMutex
+	items  map[string]Item
+	claims map[string]IsbnClaim
+	seqNo  int64
+}
+
+// sort value of the relevance score, the default sort as in elasticsearch
+const scoreField = "_score"
+
+// public sort and aggregation field -> source path, fields not listed are source paths
+var memorySourceFields = map[string]string{
+	"price": "price.amount",
+}
+
+func sourcePath(field string) string {
+	if path, ok := memorySourceFields[field]; ok {
+		return path
+	}
+	return field
+}
+
+// memoryDoc is an item with its json source used to resolve field paths
+type memoryDoc struct {
+	item      Item
+	source    map[string]interface{}
+	sort      []interface{}
+	score     float64
+	highlight map[string][]string
+}
+
+func NewMemoryPersister() ItemsPersistInterface {
+	return &memoryPersist{
+		items:  make(map[string]Item),
+		claims: make(map[string]IsbnClaim),
+	}
+}
+
+func (p *memoryPersist) Save(it *Item) rest_errors.RestErr {
+	id := it.Id
+	if id == "" {
+		var err error
+		if id, err = NewItemId(); err != nil {
+			return rest_errors.NewInternalServerError("save item error", err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.items[id]; ok {
+		return rest_errors.NewConflictError("item already exists")
+	}
+	it.Id = id
+	it.Version = p.nextVersion()
+	p.items[id] = *it
+	return nil
+}
+
+func (p *memoryPersist) SaveAll(its []Item) []rest_errors.RestErr {
+	errs := make([]rest_errors.RestErr, len(its))
+	for idx := range its {
+		errs[idx] = p.Save(&its[idx])
+	}
+	return errs
+}
+
+// Export passes the items of the seller ordered by id, a snapshot taken before the first page
+func (p *memoryPersist) Export(seller int64, consume func([]Item) rest_errors.RestErr) rest_errors.RestErr {
+	p.mu.RLock()
+	ls := make([]Item, 0)
+	for _, it := range p.items {
+		if it.Seller == seller {
+			ls = append(ls, it)
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(ls, func(i, j int) bool {
+		return ls[i].Id < ls[j].Id
+	})
+	for start := 0; start < len(ls); start += exportBatchSize {
+		end := start + exportBatchSize
+		if end > len(ls) {
+			end = len(ls)
+		}
+		if err := consume(ls[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Suggest completes the prefix with titles and authors of active items starting with it,
+// words are compared lower cased as by the simple analyzer of completion fields
+func (p *memoryPersist) Suggest(prefix string, size int) (*SuggestResult, rest_errors.RestErr) {
+	input := completionInput(prefix)
+
+	p.mu.RLock()
+	lists := make([][]Suggestion, len(suggestFields))
+	for idx, f := range suggestFields {
+		seen := make(map[string]bool)
+		for _, it := range p.items {
+			if it.Status != StatusActive {
+				continue
+			}
+			for _, value := range f.values(it) {
+				completion := completionInput(value)
+				if completion == "" || seen[completion] || !strings.HasPrefix(completion, input) {
+					continue
+				}
+				seen[completion] = true
+				lists[idx] = append(lists[idx], Suggestion{Text: value, Id: it.Id, Field: f.Field, Score: 1})
+			}
+		}
+		sort.Slice(lists[idx], func(i, j int) bool {
+			return lists[idx][i].Text < lists[idx][j].Text
+		})
+	}
+	p.mu.RUnlock()
+
+	return &SuggestResult{Prefix: prefix, Suggestions: topSuggestions(size, lists...)}, nil
+}
+
+// Related scores active items of other sellers by the words of the title and description
+// shared with the item, the best scores go first
+func (p *memoryPersist) Related(it Item, size int) ([]Item, rest_errors.RestErr) {
+	words := relatedWords(it)
+
+	type scored struct {
+		item  Item
+		score int
+	}
+	p.mu.RLock()
+	found := make([]scored, 0)
+	for _, other := range p.items {
+		if other.Id == it.Id || other.Seller == it.Seller || other.Status != StatusActive {
+			continue
+		}
+		score := 0
+		for w := range relatedWords(other) {
+			if words[w] {
+				score++
+			}
+		}
+		if score > 0 {
+			found = append(found, scored{item: other, score: score})
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score > found[j].score
+		}
+		return found[i].item.Id < found[j].item.Id
+	})
+	if len(found) > size {
+		found = found[:size]
+	}
+
+	result := make([]Item, len(found))
+	for idx, f := range found {
+		result[idx] = f.item
+	}
+	return result, nil
+}
+
+func relatedWords(it Item) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range tokenize(it.Title + " " + it.Description.PlainText) {
+		words[w] = true
+	}
+	return words
+}
+
+// completionInput is the text as a completion field matches it
+func completionInput(text string) string {
+	return strings.Join(tokenize(text), " ")
+}
+
+func (p *memoryPersist) nextVersion() *es.DocVersion {
+	version := &es.DocVersion{SeqNo: p.seqNo, PrimaryTerm: 1}
+	p.seqNo++
+	return version
+}
+
+// checkVersion fails when the caller read a revision older than the stored one
+func (p *memoryPersist) checkVersion(it Item) rest_errors.RestErr {
+	stored, ok := p.items[it.Id]
+	if !ok {
+		return rest_errors.NewNotFoundError("item not found")
+	}
+	if it.Version != nil && *it.Version != *stored.Version {
+		return rest_errors.NewPreconditionFailedError("item has been modified concurrently")
+	}
+	return nil
+}
+
+func (p *memoryPersist) Get(it Item) (*Item, rest_errors.RestErr) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stored, ok := p.items[it.Id]
+	if !ok {
+		return nil, rest_errors.NewNotFoundError("item not found")
+	}
+	version := *stored.Version
+	stored.Version = &version
+	// appended to by the next change
+	stored.PendingEvents = append([]json.RawMessage(nil), stored.PendingEvents...)
+	return &stored, nil
+}
+
+func (p *memoryPersist) Update(it *Item) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkVersion(*it); err != nil {
+		return err
+	}
+	it.Version = p.nextVersion()
+	p.items[it.Id] = *it
+	return nil
+}
+
+func (p *memoryPersist) Delete(it *Item) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkVersion(*it); err != nil {
+		return err
+	}
+	it.Version = p.nextVersion()
+	delete(p.items, it.Id)
+	return nil
+}
+
+func (p *memoryPersist) ClaimIsbn(it Item) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := claimId(it.Seller, it.Book.Isbn)
+	now := time.Now().UTC()
+	if current, ok := p.claims[id]; ok && current.ItemId != it.Id {
+		var owner *Item
+		if stored, ok := p.items[current.ItemId]; ok {
+			owner = &stored
+		}
+		if !current.isStale(owner, it.Seller, it.Book.Isbn, now) {
+			return errDuplicateIsbn(it.Book.Isbn)
+		}
+	}
+	p.claims[id] = IsbnClaim{ItemId: it.Id, DateCreated: now}
+	return nil
+}
+
+func (p *memoryPersist) ReleaseIsbn(it Item, isbn string) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := claimId(it.Seller, isbn)
+	if current, ok := p.claims[id]; ok && current.ItemId == it.Id {
+		delete(p.claims, id)
+	}
+	return nil
+}
+
+func (p *memoryPersist) WithPendingEvents(limit int) ([]Item, rest_errors.RestErr) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ls := make([]Item, 0)
+	for _, it := range p.items {
+		if len(it.PendingEvents) > 0 && len(ls) < limit {
+			version := *it.Version
+			it.Version = &version
+			ls = append(ls, it)
+		}
+	}
+	return ls, nil
+}
+
+func (p *memoryPersist) Search(q queries.EsQuery) (*SearchResult, rest_errors.RestErr) {
+	p.mu.RLock()
+	docs := make([]memoryDoc, 0, len(p.items))
+	for _, it := range p.items {
+		doc, err := newMemoryDoc(it, q)
+		if err != nil {
+			p.mu.RUnlock()
+			return nil, rest_errors.NewInternalServerError("search items error", err)
+		}
+		if doc.matches(q) {
+			docs = append(docs, doc)
+		}
+	}
+	p.mu.RUnlock()
+
+	fields := sortFields(q)
+	sort.Slice(docs, func(i, j int) bool {
+		return compareSort(fields, docs[i].sort, docs[j].sort) < 0
+	})
+
+	start := q.From()
+	if len(q.SearchAfter) > 0 {
+		start = sort.Search(len(docs), func(i int) bool {
+			return compareSort(fields, docs[i].sort, q.SearchAfter) > 0
+		})
+	}
+	if start > len(docs) {
+		start = len(docs)
+	}
+	end := start + q.PageSize()
+	if end > len(docs) {
+		end = len(docs)
+	}
+
+	page := docs[start:end]
+	items := make([]Item, len(page))
+	highlights := make(map[string]map[string][]string)
+	for idx, doc := range page {
+		items[idx] = doc.item
+		if len(doc.highlight) > 0 {
+			highlights[doc.item.Id] = doc.highlight
+		}
+	}
+
+	result := SearchResult{
+		Total: int64(len(docs)),
+		Page:  q.PageNumber(),
+		Size:  q.PageSize(),
+		Items: items,
+	}
+	if len(page) == q.PageSize() {
+		result.SearchAfter = page[len(page)-1].sort
+	}
+	if len(highlights) > 0 {
+		result.Highlights = highlights
+	}
+	if len(q.Aggregations) > 0 {
+		result.Aggregations = make(map[string]Aggregation, len(q.Aggregations))
+		for _, agg := range q.Aggregations {
+			aggregation, err := aggregate(agg, docs)
+			if err != nil {
+				return nil, err
+			}
+			result.Aggregations[agg.Name] = aggregation
+		}
+	}
+	return &result, nil
+}
+
+func newMemoryDoc(it Item, q queries.EsQuery) (memoryDoc, error) {
+	doc := memoryDoc{item: it}
+
+	bytes, err := json.Marshal(it)
+	if err != nil {
+		return doc, err
+	}
+	if err := json.Unmarshal(bytes, &doc.source); err != nil {
+		return doc, err
+	}
+
+	if q.Text != "" {
+		doc.matchText(q.Text, q.TextBoosts())
+	}
+
+	fields := sortFields(q)
+	doc.sort = make([]interface{}, 0, len(fields)+1)
+	for _, s := range fields {
+		var value interface{}
+		if s.Field == scoreField {
+			value = doc.score
+		} else if values := doc.values(sourcePath(s.Field)); len(values) > 0 {
+			value = values[0]
+		}
+		doc.sort = append(doc.sort, value)
+	}
+	doc.sort = append(doc.sort, it.Id)
+	return doc, nil
+}
+
+// values resolves a dotted field path, arrays are flattened
+func (d memoryDoc) values(field string) []interface{} {
+	values := []interface{}{d.source}
+	for _, name := range strings.Split(field, ".") {
+		next := make([]interface{}, 0)
+		for _, v := range values {
+			if m, ok := v.(map[string]interface{}); ok {
+				next = appendValue(next, m[name])
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+func appendValue(values []interface{}, v interface{}) []interface{} {
+	switch t := v.(type) {
+	case nil:
+		return values
+	case []interface{}:
+		for _, elem := range t {
+			values = appendValue(values, elem)
+		}
+		return values
+	default:
+		return append(values, v)
+	}
+}
+
+// sortFields are the requested sort fields, the relevance score by default
+func sortFields(q queries.EsQuery) []queries.SortField {
+	if len(q.Sort) == 0 {
+		return []queries.SortField{{Field: scoreField, Order: "desc"}}
+	}
+	return q.Sort
+}
+
+// matchText scores the words of the text found in the boosted fields
+// and highlights them in the field values
+func (d *memoryDoc) matchText(text string, boosts queries.TextBoosts) {
+	words := make(map[string]bool)
+	for _, w := range tokenize(text) {
+		words[w] = true
+	}
+
+	for _, f := range boosts.Fields() {
+		for _, v := range d.values(f.Field) {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			fragment, found := highlightWords(s, words)
+			if found == 0 {
+				continue
+			}
+			d.score += f.Boost * float64(found)
+			if d.highlight == nil {
+				d.highlight = make(map[string][]string)
+			}
+			d.highlight[f.Field] = append(d.highlight[f.Field], fragment)
+		}
+	}
+}
+
+// highlightWords wraps the words of the text found in words with the highlight tags,
+// the text is html escaped as elasticsearch does with the html encoder
+func highlightWords(text string, words map[string]bool) (string, int) {
+	var sb strings.Builder
+	found, start := 0, -1
+	runes := []rune(text)
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := string(runes[start:end])
+		if words[strings.ToLower(word)] {
+			found++
+			word = queries.HighlightPreTag + html.EscapeString(word) + queries.HighlightPostTag
+		} else {
+			word = html.EscapeString(word)
+		}
+		sb.WriteString(word)
+		start = -1
+	}
+	for idx, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = idx
+			}
+			continue
+		}
+		flush(idx)
+		sb.WriteString(html.EscapeString(string(r)))
+	}
+	flush(len(runes))
+	return sb.String(), found
+}
+
+func (d memoryDoc) matches(q queries.EsQuery) bool {
+	if q.Text != "" && d.score == 0 {
+		return false
+	}
+	for _, fv := range q.Equals {
+		if !matchAny(d.values(fv.Field), fv.Value) {
+			return false
+		}
+	}
+	for _, fv := range q.NotEquals {
+		if matchAny(d.values(fv.Field), fv.Value) {
+			return false
+		}
+	}
+	if len(q.AnyOf) > 0 {
+		found := false
+		for _, fv := range q.AnyOf {
+			if matchAny(d.values(fv.Field), fv.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, r := range q.Range {
+		if !inRange(d.values(r.Field), r) {
+			return false
+		}
+	}
+	for _, fv := range q.Prefix {
+		if !hasPrefix(d.values(fv.Field), fmt.Sprint(fv.Value)) {
+			return false
+		}
+	}
+	for _, field := range q.Exists {
+		if len(d.values(field)) == 0 {
+			return false
+		}
+	}
+	if v := q.Visibility; v != nil {
+		if d.item.Status != v.Status && (v.Owner == 0 || d.item.Seller != v.Owner) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny is a match query : any word of the value is found in any field value
+func matchAny(values []interface{}, value interface{}) bool {
+	if s, ok := value.(string); ok {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			value = f
+		}
+	}
+	words := tokenize(value)
+	for _, v := range values {
+		for _, fieldWord := range tokenize(v) {
+			for _, w := range words {
+				if fieldWord == w {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func tokenize(v interface{}) []string {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return strings.FieldsFunc(strings.ToLower(t), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	default:
+		if f, ok := toFloat(t); ok {
+			return []string{strconv.FormatFloat(f, 'f', -1, 64)}
+		}
+		return []string{strings.ToLower(fmt.Sprint(t))}
+	}
+}
+
+func inRange(values []interface{}, r queries.RangeValue) bool {
+	for _, v := range values {
+		if (r.Gt == nil || compareValues(v, r.Gt) > 0) &&
+			(r.Gte == nil || compareValues(v, r.Gte) >= 0) &&
+			(r.Lt == nil || compareValues(v, r.Lt) < 0) &&
+			(r.Lte == nil || compareValues(v, r.Lte) <= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(values []interface{}, prefix string) bool {
+	prefix = strings.ToLower(prefix)
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(s), prefix) {
+			return true
+		}
+		for _, word := range tokenize(s) {
+			if strings.HasPrefix(word, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compareValues compares numbers when both sides are numeric, strings otherwise
+func compareValues(a, b interface{}) int {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// compareSort compares sort values, missing values go last as in elasticsearch
+func compareSort(fields []queries.SortField, a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == nil || b[i] == nil {
+			if a[i] == nil && b[i] == nil {
+				continue
+			}
+			if a[i] == nil {
+				return 1
+			}
+			return -1
+		}
+		cmp := compareValues(a[i], b[i])
+		if cmp == 0 {
+			continue
+		}
+		if i < len(fields) && fields[i].Order == "desc" {
+			return -cmp
+		}
+		return cmp
+	}
+	return 0
+}
+
+func aggregate(agg queries.Aggregation, docs []memoryDoc) (Aggregation, rest_errors.RestErr) {
+	result := Aggregation{Type: agg.Type, Buckets: make([]Bucket, 0)}
+	switch agg.Type {
+	case queries.AggTerms:
+		result.Buckets = termsBuckets(agg, docs)
+	case queries.AggRange:
+		for _, r := range agg.Ranges {
+			bucket := Bucket{Key: r.Key, From: r.From, To: r.To}
+			for _, doc := range docs {
+				for _, v := range doc.values(sourcePath(agg.Field)) {
+					f, ok := toFloat(v)
+					if ok && (r.From == nil || f >= *r.From) && (r.To == nil || f < *r.To) {
+						bucket.DocCount++
+						break
+					}
+				}
+			}
+			result.Buckets = append(result.Buckets, bucket)
+		}
+	case queries.AggHistogram:
+		buckets, err := histogramBuckets(agg, docs)
+		if err != nil {
+			return result, err
+		}
+		result.Buckets = buckets
+	}
+	return result, nil
+}
+
+func termsBuckets(agg queries.Aggregation, docs []memoryDoc) []Bucket {
+	counts := make(map[string]*Bucket)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, v := range doc.values(sourcePath(agg.Field)) {
+			key := fmt.Sprint(v)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := counts[key]; !ok {
+				counts[key] = &Bucket{Key: v}
+			}
+			counts[key].DocCount++
+		}
+	}
+
+	buckets := make([]Bucket, 0, len(counts))
+	for _, b := range counts {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].DocCount != buckets[j].DocCount {
+			return buckets[i].DocCount > buckets[j].DocCount
+		}
+		return compareValues(buckets[i].Key, buckets[j].Key) < 0
+	})
+	if len(buckets) > agg.TermsSize() {
+		buckets = buckets[:agg.TermsSize()]
+	}
+	return buckets
+}
+
+func histogramBuckets(agg queries.Aggregation, docs []memoryDoc) ([]Bucket, rest_errors.RestErr) {
+	counts := make(map[int64]int64)
+	min, max := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, doc := range docs {
+		seen := make(map[int64]bool)
+		for _, v := range doc.values(sourcePath(agg.Field)) {
+			f, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			n := int64(math.Floor(f / agg.Interval))
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			counts[n]++
+			if n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+		}
+	}
+
+	buckets := make([]Bucket, 0)
+	if len(counts) == 0 {
+		return buckets, nil
+	}
+	// the empty buckets between min and max count as well
+	if float64(max)-float64(min) >= queries.MaxBuckets {
+		return nil, queries.TooManyBuckets(agg.Name)
+	}
+	for n := min; n <= max; n++ {
+		buckets = append(buckets, Bucket{Key: float64(n) * agg.Interval, DocCount: counts[n]})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,344 @@
+package items
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func memoryWithItems(t *testing.T, its ...Item) ItemsPersistInterface {
+	persist := NewMemoryPersister()
+	for i := range its {
+		assert.Nil(t, persist.Save(&its[i]))
+	}
+	return persist
+}
+
+func usd(amount int64) money.Money {
+	return money.New(amount, "USD")
+}
+
+func titles(result *SearchResult) []string {
+	ls := make([]string, len(result.Items))
+	for i, it := range result.Items {
+		ls[i] = it.Title
+	}
+	return ls
+}
+
+func TestMemorySaveGetUpdateDelete(t *testing.T) {
+	persist := NewMemoryPersister()
+
+	it := Item{Title: "Go in action", Seller: 1}
+	assert.Nil(t, persist.Save(&it))
+	assert.NotEmpty(t, it.Id)
+
+	stored, err := persist.Get(Item{Id: it.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, "Go in action", stored.Title)
+
+	stored.Title = "Go in action, 2nd edition"
+	assert.Nil(t, persist.Update(stored))
+
+	stored, _ = persist.Get(Item{Id: it.Id})
+	assert.Equal(t, "Go in action, 2nd edition", stored.Title)
+
+	assert.Nil(t, persist.Delete(&Item{Id: it.Id}))
+
+	_, err = persist.Get(Item{Id: it.Id})
+	assert.Equal(t, http.StatusNotFound, err.Status())
+	assert.Equal(t, http.StatusNotFound, persist.Update(&Item{Id: it.Id}).Status())
+	assert.Equal(t, http.StatusNotFound, persist.Delete(&Item{Id: it.Id}).Status())
+}
+
+func TestMemoryStaleVersion(t *testing.T) {
+	persist := NewMemoryPersister()
+	it := Item{Title: "Go in action", Seller: 1}
+	assert.Nil(t, persist.Save(&it))
+
+	first, _ := persist.Get(Item{Id: it.Id})
+	second, _ := persist.Get(Item{Id: it.Id})
+	assert.Nil(t, persist.Update(first))
+	assert.NotEqual(t, *second.Version, *first.Version)
+
+	assert.Equal(t, http.StatusPreconditionFailed, persist.Update(second).Status())
+	assert.Equal(t, http.StatusPreconditionFailed, persist.Delete(second).Status())
+	assert.Nil(t, persist.Delete(first))
+}
+
+func TestMemorySearchClauses(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "The Hobbit", Status: "active", Price: usd(1000), AvailableQuantity: 1,
+			Description: Description{PlainText: "Tolkien classic"}},
+		Item{Title: "The Lord of the Rings", Status: "active", Price: usd(3000), AvailableQuantity: 0},
+		Item{Title: "Harry Potter", Status: "paused", Price: usd(1500), AvailableQuantity: 5},
+	)
+
+	cases := []struct {
+		q        queries.EsQuery
+		expected []string
+	}{
+		{queries.EsQuery{Equals: []queries.FieldValue{{Field: "title", Value: "the"}}},
+			[]string{"The Hobbit", "The Lord of the Rings"}},
+		{queries.EsQuery{Equals: []queries.FieldValue{{Field: "price.amount", Value: 1500}}},
+			[]string{"Harry Potter"}},
+		{queries.EsQuery{NotEquals: []queries.FieldValue{{Field: "status", Value: "active"}}},
+			[]string{"Harry Potter"}},
+		{queries.EsQuery{AnyOf: []queries.FieldValue{{Field: "title", Value: "hobbit"}, {Field: "title", Value: "harry"}}},
+			[]string{"Harry Potter", "The Hobbit"}},
+		{queries.EsQuery{Range: []queries.RangeValue{{Field: "price.amount", Gt: 1000, Lte: 3000}}},
+			[]string{"Harry Potter", "The Lord of the Rings"}},
+		{queries.EsQuery{Range: []queries.RangeValue{{Field: "available_quantity", Gt: 0}}},
+			[]string{"Harry Potter", "The Hobbit"}},
+		{queries.EsQuery{Prefix: []queries.FieldValue{{Field: "title", Value: "lor"}}},
+			[]string{"The Lord of the Rings"}},
+		{queries.EsQuery{Equals: []queries.FieldValue{{Field: "description.plain_text", Value: "tolkien"}}},
+			[]string{"The Hobbit"}},
+	}
+	for _, c := range cases {
+		c.q.Sort = []queries.SortField{{Field: "title"}}
+		result, err := persist.Search(c.q)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, titles(result))
+		assert.EqualValues(t, len(c.expected), result.Total)
+	}
+}
+
+func TestMemorySearchVisibility(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "a", Seller: 1, Status: StatusActive},
+		Item{Title: "b", Seller: 1, Status: StatusDraft},
+		Item{Title: "c", Seller: 2, Status: StatusPaused},
+	)
+	sortByTitle := []queries.SortField{{Field: "title"}}
+
+	result, _ := persist.Search(queries.EsQuery{Sort: sortByTitle,
+		Visibility: &queries.Visibility{Status: StatusActive}})
+	assert.Equal(t, []string{"a"}, titles(result))
+
+	result, _ = persist.Search(queries.EsQuery{Sort: sortByTitle,
+		Visibility: &queries.Visibility{Status: StatusActive, Owner: 1}})
+	assert.Equal(t, []string{"a", "b"}, titles(result))
+}
+
+func TestMemorySearchBook(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "a", Book: Book{Isbn: "9780306406157", Authors: []string{"Rob Pike"}}},
+		Item{Title: "b", Book: Book{Isbn: "9780804429573", Authors: []string{"Alan Donovan", "Brian Kernighan"}}},
+	)
+
+	result, _ := persist.Search(queries.EsQuery{Equals: []queries.FieldValue{{Field: "book.authors", Value: "kernighan"}}})
+	assert.Equal(t, []string{"b"}, titles(result))
+
+	result, _ = persist.Search(queries.EsQuery{Equals: []queries.FieldValue{{Field: "book.isbn", Value: "9780306406157"}}})
+	assert.Equal(t, []string{"a"}, titles(result))
+}
+
+func TestMemorySearchText(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "The Hobbit", Book: Book{Authors: []string{"J.R.R. Tolkien"}}},
+		Item{Title: "The Silmarillion", Book: Book{Authors: []string{"J.R.R. Tolkien"}}},
+		Item{Title: "Harry Potter", Description: Description{PlainText: "not a hobbit"}},
+		Item{Title: "Dune"},
+	)
+
+	result, err := persist.Search(queries.EsQuery{Text: "tolkien hobbit hardcover"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"The Hobbit", "The Silmarillion", "Harry Potter"}, titles(result))
+
+	highlight := result.Highlights[result.Items[0].Id]
+	assert.Equal(t, []string{"The <em>Hobbit</em>"}, highlight["title"])
+	assert.Equal(t, []string{"J.R.R. <em>Tolkien</em>"}, highlight["book.authors"])
+}
+
+func TestMemorySearchTextEscapesHighlights(t *testing.T) {
+	persist := memoryWithItems(t, Item{Title: `Hobbit <img src=x onerror="alert(1)">`})
+
+	result, err := persist.Search(queries.EsQuery{Text: "hobbit img"})
+	assert.Nil(t, err)
+	highlight := result.Highlights[result.Items[0].Id]
+	assert.Equal(t, []string{`<em>Hobbit</em> &lt;<em>img</em> src=x onerror=&#34;alert(1)&#34;&gt;`}, highlight["title"])
+}
+
+func TestMemorySuggest(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "The Hobbit", Status: StatusActive, Book: Book{Authors: []string{"J.R.R. Tolkien"}}},
+		Item{Title: "The Hobbit", Status: StatusActive},
+		Item{Title: "The Two Towers", Status: StatusActive, Book: Book{Authors: []string{"Tolkien"}}},
+		Item{Title: "The House", Status: StatusDraft},
+		Item{Title: "Thud", Status: StatusActive, Book: Book{Authors: []string{"Terry Pratchett"}}},
+	)
+
+	result, err := persist.Suggest("the h", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, "the h", result.Prefix)
+	assert.Equal(t, 1, len(result.Suggestions))
+	assert.Equal(t, Suggestion{Text: "The Hobbit", Id: result.Suggestions[0].Id, Field: "title", Score: 1}, result.Suggestions[0])
+
+	result, _ = persist.Suggest("T", 3)
+	assert.Equal(t, []Suggestion{
+		{Text: "The Hobbit", Field: "title"}, {Text: "The Two Towers", Field: "title"}, {Text: "Thud", Field: "title"},
+	}, suggestionTexts(result))
+
+	result, _ = persist.Suggest("Terry P", 5)
+	assert.Equal(t, []Suggestion{{Text: "Terry Pratchett", Field: "book.authors"}}, suggestionTexts(result))
+}
+
+func TestMemoryRelated(t *testing.T) {
+	its := []Item{
+		{Title: "The Hobbit", Seller: 1, Status: StatusActive, Description: Description{PlainText: "tolkien first edition"}},
+		{Title: "The Hobbit", Seller: 1, Status: StatusActive},
+		{Title: "The Hobbit", Seller: 2, Status: StatusPaused},
+		{Title: "Hobbit illustrated", Seller: 2, Status: StatusActive, Description: Description{PlainText: "tolkien first edition"}},
+		{Title: "Harry Potter first edition", Seller: 3, Status: StatusActive},
+		{Title: "Dune", Seller: 3, Status: StatusActive},
+	}
+	persist := memoryWithItems(t, its...)
+
+	related, err := persist.Related(its[0], 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Hobbit illustrated", "Harry Potter first edition"}, titles(&SearchResult{Items: related}))
+
+	related, _ = persist.Related(its[0], 1)
+	assert.Equal(t, 1, len(related))
+}
+
+func suggestionTexts(result *SuggestResult) []Suggestion {
+	ls := make([]Suggestion, len(result.Suggestions))
+	for i, s := range result.Suggestions {
+		ls[i] = Suggestion{Text: s.Text, Field: s.Field}
+	}
+	return ls
+}
+
+func TestMemorySearchPaging(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Title: "a", Price: usd(500)}, Item{Title: "b", Price: usd(400)}, Item{Title: "c", Price: usd(300)},
+		Item{Title: "d", Price: usd(200)}, Item{Title: "e", Price: usd(100)},
+	)
+	sortByPrice := []queries.SortField{{Field: "price", Order: "desc"}}
+
+	result, err := persist.Search(queries.EsQuery{Page: 2, Size: 2, Sort: sortByPrice})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5, result.Total)
+	assert.Equal(t, []string{"c", "d"}, titles(result))
+	assert.NotNil(t, result.SearchAfter)
+
+	result, err = persist.Search(queries.EsQuery{Size: 2, Sort: sortByPrice, SearchAfter: result.SearchAfter})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"e"}, titles(result))
+	assert.Nil(t, result.SearchAfter)
+}
+
+func TestMemorySearchAggregations(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Status: "active", Price: usd(500), Seller: 1},
+		Item{Status: "active", Price: usd(1200), Seller: 1},
+		Item{Status: "paused", Price: usd(2700), Seller: 2},
+	)
+	ten := 1000.0
+	result, err := persist.Search(queries.EsQuery{Aggregations: []queries.Aggregation{
+		{Name: "status", Type: queries.AggTerms, Field: "status"},
+		{Name: "prices", Type: queries.AggRange, Field: "price", Ranges: []queries.AggregationRange{
+			{Key: "cheap", To: &ten}, {Key: "expensive", From: &ten},
+		}},
+		{Name: "histogram", Type: queries.AggHistogram, Field: "price", Interval: 1000},
+	}})
+	assert.Nil(t, err)
+
+	status := result.Aggregations["status"].Buckets
+	assert.Equal(t, 2, len(status))
+	assert.Equal(t, "active", status[0].Key)
+	assert.EqualValues(t, 2, status[0].DocCount)
+
+	prices := result.Aggregations["prices"].Buckets
+	assert.EqualValues(t, 1, prices[0].DocCount)
+	assert.EqualValues(t, 2, prices[1].DocCount)
+
+	histogram := result.Aggregations["histogram"].Buckets
+	assert.Equal(t, 3, len(histogram))
+	assert.EqualValues(t, 2000, histogram[2].Key)
+	assert.EqualValues(t, 1, histogram[2].DocCount)
+}
+
+func TestMemorySaveAllExport(t *testing.T) {
+	persist := NewMemoryPersister()
+	its := []Item{{Title: "a", Seller: 1}, {Title: "b", Seller: 2}, {Title: "c", Seller: 1}}
+	for _, err := range persist.SaveAll(its) {
+		assert.Nil(t, err)
+	}
+	assert.NotEmpty(t, its[2].Id)
+
+	exported := make([]Item, 0)
+	err := persist.Export(1, func(ls []Item) rest_errors.RestErr {
+		exported = append(exported, ls...)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, exported, 2)
+	for _, it := range exported {
+		assert.EqualValues(t, 1, it.Seller)
+	}
+}
+
+func TestMemorySearchTooManyBuckets(t *testing.T) {
+	persist := memoryWithItems(t,
+		Item{Status: "active", Price: usd(1)},
+		Item{Status: "active", Price: usd(50000)},
+	)
+
+	_, err := persist.Search(queries.EsQuery{Aggregations: []queries.Aggregation{
+		{Name: "histogram", Type: queries.AggHistogram, Field: "price", Interval: 1},
+	}})
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+
+	result, err := persist.Search(queries.EsQuery{Aggregations: []queries.Aggregation{
+		{Name: "histogram", Type: queries.AggHistogram, Field: "price", Interval: 10},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, 5001, len(result.Aggregations["histogram"].Buckets))
+}
+
+func TestMemoryClaimIsbn(t *testing.T) {
+	persist := NewMemoryPersister()
+	first := Item{Id: "1", Seller: 1, Status: StatusActive, Book: Book{Isbn: "9780306406157"}}
+	second := Item{Id: "2", Seller: 1, Status: StatusActive, Book: Book{Isbn: "9780306406157"}}
+
+	assert.Nil(t, persist.ClaimIsbn(first))
+	assert.Nil(t, persist.Save(&first))
+	err := persist.ClaimIsbn(second)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.Status())
+
+	// the claims of other items are kept
+	assert.Nil(t, persist.ReleaseIsbn(second, "9780306406157"))
+	assert.NotNil(t, persist.ClaimIsbn(second))
+
+	// another seller lists the book
+	assert.Nil(t, persist.ClaimIsbn(Item{Id: "3", Seller: 2, Book: Book{Isbn: "9780306406157"}}))
+
+	assert.Nil(t, persist.ReleaseIsbn(first, "9780306406157"))
+	assert.Nil(t, persist.ClaimIsbn(second))
+}
+
+func TestMemoryClaimIsbnStale(t *testing.T) {
+	persist := NewMemoryPersister()
+	first := Item{Id: "1", Seller: 1, Status: StatusArchived, Book: Book{Isbn: "9780306406157"}}
+	assert.Nil(t, persist.Save(&first))
+	assert.Nil(t, persist.ClaimIsbn(first))
+	second := Item{Id: "2", Seller: 1, Status: StatusActive, Book: Book{Isbn: "9780306406157"}}
+
+	// the claim may belong to an item being written
+	assert.NotNil(t, persist.ClaimIsbn(second))
+
+	claims := persist.(*memoryPersist).claims
+	claim := claims[claimId(1, "9780306406157")]
+	claim.DateCreated = claim.DateCreated.Add(-claimGracePeriod)
+	claims[claimId(1, "9780306406157")] = claim
+	assert.Nil(t, persist.ClaimIsbn(second))
+}
@@ -0,0 +1,23 @@
+package items
+
+import "github.com/olivere/elastic"
+
+// text fields compared to find related items
+var relatedFields = []string{"title", "description.plain_text"}
+
+// relatedSource finds active items of other sellers with terms of the item text,
+// the item itself is excluded by more_like_this
+func relatedSource(it Item, size int) *elastic.SearchSource {
+	like := elastic.NewMoreLikeThisQuery().
+		Field(relatedFields...).
+		LikeItems(elastic.NewMoreLikeThisQueryItem().Index(itemName).Type(typeItem).Id(it.Id)).
+		// titles are short, every term counts
+		MinTermFreq(1).
+		MinDocFreq(1)
+
+	query := elastic.NewBoolQuery().
+		Must(like).
+		Filter(elastic.NewTermQuery("status", StatusActive)).
+		MustNot(elastic.NewTermQuery("seller", it.Seller))
+	return elastic.NewSearchSource().Query(query).Size(size)
+}
@@ -0,0 +1,72 @@
+package items
+
+import (
+	"fmt"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const (
+	StatusDraft    = "draft"
+	StatusActive   = "active"
+	StatusPaused   = "paused"
+	StatusSoldOut  = "sold_out"
+	StatusArchived = "archived"
+)
+
+// status -> statuses it can move to, archived is final
+var transitions = map[string][]string{
+	StatusDraft:    {StatusActive, StatusArchived},
+	StatusActive:   {StatusPaused, StatusSoldOut, StatusArchived},
+	StatusPaused:   {StatusActive, StatusArchived},
+	StatusSoldOut:  {StatusActive, StatusArchived},
+	StatusArchived: {},
+}
+
+func IsValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+func CanTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition checks a status change requested by the seller.
+// sold_out is never requested, it follows the available quantity
+func (i Item) ValidateTransition(to string) rest_errors.RestErr {
+	if !IsValidStatus(to) {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("unknown status %s", to))
+	}
+	if to == StatusSoldOut {
+		return rest_errors.NewBadRequestError("sold_out is set when no items are available")
+	}
+	from := i.Status
+	if from == "" {
+		// items created before statuses were introduced
+		from = StatusDraft
+	}
+	if !CanTransition(from, to) {
+		return rest_errors.NewConflictError(fmt.Sprintf("item can not move from %s to %s", from, to))
+	}
+	if to == StatusActive && i.AvailableQuantity <= 0 {
+		return rest_errors.NewConflictError("no items available to activate")
+	}
+	return nil
+}
+
+// SyncStockStatus marks an active item sold out when its stock runs out
+// and puts it back on sale when the stock is replenished
+func (i *Item) SyncStockStatus() {
+	switch {
+	case i.Status == StatusActive && i.AvailableQuantity <= 0:
+		i.Status = StatusSoldOut
+	case i.Status == StatusSoldOut && i.AvailableQuantity > 0:
+		i.Status = StatusActive
+	}
+}
@@ -0,0 +1,35 @@
+package items
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, CanTransition(StatusDraft, StatusActive))
+	assert.True(t, CanTransition(StatusActive, StatusPaused))
+	assert.True(t, CanTransition(StatusPaused, StatusActive))
+	assert.True(t, CanTransition(StatusSoldOut, StatusArchived))
+	assert.False(t, CanTransition(StatusActive, StatusDraft))
+	assert.False(t, CanTransition(StatusArchived, StatusActive))
+	assert.False(t, CanTransition("unknown", StatusActive))
+}
+
+func TestSyncStockStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		quantity int
+		expected string
+	}{
+		{StatusActive, 0, StatusSoldOut},
+		{StatusActive, 1, StatusActive},
+		{StatusSoldOut, 1, StatusActive},
+		{StatusPaused, 0, StatusPaused},
+		{StatusDraft, 0, StatusDraft},
+	} {
+		item := Item{Status: tc.status, AvailableQuantity: tc.quantity}
+		item.SyncStockStatus()
+		assert.Equal(t, tc.expected, item.Status)
+	}
+}
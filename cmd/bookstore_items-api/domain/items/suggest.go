@@ -0,0 +1,64 @@
+package items
+
+import (
+	"sort"
+	"strings"
+)
+
+// name of the completion context holding the item status
+const statusContext = "status"
+
+// Suggestion completes a prefix with a title or an author, the id is an item holding the text
+type Suggestion struct {
+	Text  string  `json:"text"`
+	Id    string  `json:"id"`
+	Field string  `json:"field"`
+	Score float64 `json:"-"`
+}
+
+type SuggestResult struct {
+	Prefix      string       `json:"prefix"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// suggestField is an item field completed by a completion sub field of the mapping
+type suggestField struct {
+	Field  string
+	Path   string
+	values func(Item) []string
+}
+
+var suggestFields = []suggestField{
+	{Field: "title", Path: "title.suggest", values: func(it Item) []string {
+		return []string{it.Title}
+	}},
+	{Field: "book.authors", Path: "book.authors.suggest", values: func(it Item) []string {
+		return it.Book.Authors
+	}},
+}
+
+// topSuggestions merges the suggestions of every field by score, titles go first on a tie
+func topSuggestions(size int, lists ...[]Suggestion) []Suggestion {
+	all := make([]Suggestion, 0)
+	for _, ls := range lists {
+		all = append(all, ls...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Score > all[j].Score
+	})
+
+	result := make([]Suggestion, 0, size)
+	seen := make(map[string]bool)
+	for _, s := range all {
+		key := s.Field + "\x00" + strings.ToLower(s.Text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, s)
+		if len(result) == size {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package items
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const (
+	MaxPictures = 12
+
+	maxTitleLength     = 256
+	maxPlainTextLength = 50_000
+	maxHtmlLength      = 100_000
+)
+
+// rules reported in violations
+const (
+	RuleRequired  = "required"
+	RuleMaxLength = "max_length"
+	RuleMin       = "min"
+	RuleMaxItems  = "max_items"
+	RuleUnique    = "unique"
+	RuleUrl       = "url"
+	RuleCurrency  = "currency"
+	RuleIsbn      = "isbn"
+	RuleDate      = "date"
+	RuleOneOf     = "one_of"
+)
+
+// Violation is a rule broken by an item field, the field is its json path
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type violations []interface{}
+
+func (v *violations) add(field, rule, message string) {
+	*v = append(*v, Violation{Field: field, Rule: rule, Message: message})
+}
+
+func (v *violations) maxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, RuleMaxLength, fmt.Sprintf("%s is longer than %d characters", field, max))
+	}
+}
+
+func (v *violations) min(field string, value int64, min int64) {
+	if value < min {
+		v.add(field, RuleMin, fmt.Sprintf("%s must not be less than %d", field, min))
+	}
+}
+
+func (v *violations) url(field, value string) {
+	if value != "" && !isWebUrl(value) {
+		v.add(field, RuleUrl, fmt.Sprintf("%s must be an http or https url", field))
+	}
+}
+
+// Validate checks the item fields and reports all violations at once,
+// the causes of the returned error are the violations
+func (it Item) Validate() rest_errors.RestErr {
+	var v violations
+
+	if strings.TrimSpace(it.Title) == "" {
+		v.add("title", RuleRequired, "title is required")
+	}
+	v.maxLength("title", it.Title, maxTitleLength)
+	v.maxLength("description.plain_text", it.Description.PlainText, maxPlainTextLength)
+	if len(it.Description.Html) > maxHtmlLength {
+		v.add("description.html", RuleMaxLength, fmt.Sprintf("description.html is longer than %d bytes", maxHtmlLength))
+	}
+
+	if it.Status != "" && !IsValidStatus(it.Status) {
+		v.add("status", RuleOneOf, fmt.Sprintf("unknown status %s", it.Status))
+	}
+
+	if !it.Price.IsZero() {
+		v.min("price.amount", it.Price.Amount, 0)
+		price := it.Price
+		if err := price.Normalize(); err != nil {
+			v.add("price.currency", RuleCurrency, err.Error())
+		}
+	}
+	v.min("available_quantity", int64(it.AvailableQuantity), 0)
+	v.min("sold_quantity", int64(it.SoldQuantity), 0)
+
+	it.validatePictures(&v)
+	v.url("video", it.Video)
+	it.Book.validate(&v)
+
+	if len(v) > 0 {
+		return rest_errors.NewValidationError("invalid item", v)
+	}
+	return nil
+}
+
+func (it Item) validatePictures(v *violations) {
+	if len(it.Pictures) > MaxPictures {
+		v.add("pictures", RuleMaxItems, fmt.Sprintf("an item has at most %d pictures", MaxPictures))
+	}
+	ids := make(map[int64]bool, len(it.Pictures))
+	for idx, picture := range it.Pictures {
+		field := fmt.Sprintf("pictures[%d]", idx)
+		if ids[picture.Id] {
+			v.add(field+".id", RuleUnique, fmt.Sprintf("picture id %d is used twice", picture.Id))
+		}
+		ids[picture.Id] = true
+		if picture.Url == "" {
+			v.add(field+".url", RuleRequired, "picture url is required")
+		}
+		v.url(field+".url", picture.Url)
+		v.url(field+".thumbnail_url", picture.ThumbnailUrl)
+	}
+}
+
+func (b Book) validate(v *violations) {
+	if b.Isbn != "" {
+		if _, err := NormalizeIsbn(b.Isbn); err != nil {
+			v.add("book.isbn", RuleIsbn, err.Error())
+		}
+	}
+	v.min("book.page_count", int64(b.PageCount), 0)
+	if b.PublicationDate != "" {
+		if _, err := time.Parse(publicationDateLayout, b.PublicationDate); err != nil {
+			v.add("book.publication_date", RuleDate, "publication_date must be yyyy-mm-dd")
+		}
+	}
+}
+
+func isWebUrl(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
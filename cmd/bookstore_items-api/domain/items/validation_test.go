@@ -0,0 +1,88 @@
+package items
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOk(t *testing.T) {
+	it := Item{
+		Title:       "The Hobbit",
+		Description: Description{PlainText: "a classic", Html: "<p>a classic</p>"},
+		Status:      StatusActive,
+		Price:       money.New(1999, "EUR"),
+		Pictures: []Picture{
+			{Id: 1, Url: "https://cdn.example.com/1", ThumbnailUrl: "https://cdn.example.com/1/thumbnail"},
+			{Id: 2, Url: "http://cdn.example.com/2"},
+		},
+		Video:             "https://video.example.com/hobbit",
+		AvailableQuantity: 1,
+		Book:              Book{Isbn: "978-0-306-40615-7", PageCount: 310, PublicationDate: "1937-09-21"},
+	}
+	assert.Nil(t, it.Validate())
+}
+
+func TestValidateReportsAllViolations(t *testing.T) {
+	it := Item{
+		Title:        "  ",
+		Status:       "unknown",
+		Price:        money.New(-1, "XYZ"),
+		SoldQuantity: -1,
+		Pictures: []Picture{
+			{Id: 1, Url: "javascript:alert(1)"},
+			{Id: 1, ThumbnailUrl: "/relative"},
+		},
+		Book: Book{Isbn: "123", PageCount: -1, PublicationDate: "21.09.1937"},
+	}
+
+	err := it.Validate()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.Status())
+	var rules []string
+	for _, cause := range err.Causes() {
+		v := cause.(Violation)
+		assert.NotEmpty(t, v.Message)
+		rules = append(rules, v.Field+":"+v.Rule)
+	}
+	assert.Equal(t, []string{
+		"title:required",
+		"status:one_of",
+		"price.amount:min",
+		"price.currency:currency",
+		"sold_quantity:min",
+		"pictures[0].url:url",
+		"pictures[1].id:unique",
+		"pictures[1].url:required",
+		"pictures[1].thumbnail_url:url",
+		"book.isbn:isbn",
+		"book.page_count:min",
+		"book.publication_date:date",
+	}, rules)
+}
+
+func TestValidateLimits(t *testing.T) {
+	pictures := make([]Picture, MaxPictures+1)
+	for idx := range pictures {
+		pictures[idx] = Picture{Id: int64(idx), Url: fmt.Sprintf("https://cdn.example.com/%d", idx)}
+	}
+	it := Item{
+		Title:       strings.Repeat("t", maxTitleLength+1),
+		Description: Description{PlainText: strings.Repeat("p", maxPlainTextLength+1), Html: strings.Repeat("h", maxHtmlLength+1)},
+		Pictures:    pictures,
+	}
+
+	err := it.Validate()
+
+	assert.NotNil(t, err)
+	var fields []string
+	for _, cause := range err.Causes() {
+		fields = append(fields, cause.(Violation).Field)
+	}
+	assert.Equal(t, []string{"title", "description.plain_text", "description.html", "pictures"}, fields)
+}
@@ -0,0 +1,94 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is assumed for prices stored before currencies were introduced
+const DefaultCurrency = "USD"
+
+// digits of the minor unit of ISO 4217 currencies
+var currencies = map[string]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "INR": 2, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2,
+	"NOK": 2, "NZD": 2, "PLN": 2, "RUB": 2, "SEK": 2, "SGD": 2, "TRY": 2, "UAH": 2,
+	"USD": 2, "ZAR": 2,
+}
+
+// Money is an amount in minor units of the currency : 1999 USD is 19.99 dollars
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromMajor converts an amount in major units, 19.99 -> 1999, rounding to the minor unit
+func FromMajor(value float64, currency string) (Money, error) {
+	digits, ok := currencies[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency %s", currency)
+	}
+	return Money{Amount: int64(math.Round(value * math.Pow10(digits))), Currency: currency}, nil
+}
+
+// Scale is the number of minor units in a major unit of the currency
+func Scale(currency string) int64 {
+	return int64(math.Pow10(currencies[currency]))
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0 && m.Currency == ""
+}
+
+// Normalize upper cases the currency and checks it is a supported ISO 4217 code
+func (m *Money) Normalize() error {
+	m.Currency = strings.ToUpper(strings.TrimSpace(m.Currency))
+	if _, ok := currencies[m.Currency]; !ok {
+		return fmt.Errorf("unsupported currency %q", m.Currency)
+	}
+	return nil
+}
+
+func (m Money) String() string {
+	digits := currencies[m.Currency]
+	if digits == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	scale := Scale(m.Currency)
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/scale, digits, amount%scale, m.Currency)
+}
+
+// UnmarshalJSON also reads a bare number, the legacy price in major units of the default currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '{' {
+		value, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return fmt.Errorf("money must be an object or a number : %s", string(data))
+		}
+		legacy, err := FromMajor(value, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = legacy
+		return nil
+	}
+
+	type plain Money
+	return json.Unmarshal(data, (*plain)(m))
+}
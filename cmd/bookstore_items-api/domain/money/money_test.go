@@ -0,0 +1,59 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromMajor(t *testing.T) {
+	m, err := FromMajor(19.99, "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, New(1999, "USD"), m)
+
+	m, _ = FromMajor(0.285, "KWD")
+	assert.EqualValues(t, 285, m.Amount)
+
+	m, _ = FromMajor(1500, "JPY")
+	assert.EqualValues(t, 1500, m.Amount)
+
+	_, err = FromMajor(1, "XXX")
+	assert.NotNil(t, err)
+}
+
+func TestNormalize(t *testing.T) {
+	m := New(100, " eur ")
+	assert.Nil(t, m.Normalize())
+	assert.Equal(t, "EUR", m.Currency)
+
+	m = New(100, "")
+	assert.NotNil(t, m.Normalize())
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "19.99 USD", New(1999, "USD").String())
+	assert.Equal(t, "0.05 EUR", New(5, "EUR").String())
+	assert.Equal(t, "-1.50 GBP", New(-150, "GBP").String())
+	assert.Equal(t, "1500 JPY", New(1500, "JPY").String())
+	assert.Equal(t, "1.005 KWD", New(1005, "KWD").String())
+}
+
+func TestJson(t *testing.T) {
+	bytes, _ := json.Marshal(New(1999, "USD"))
+	assert.Equal(t, `{"amount":1999,"currency":"USD"}`, string(bytes))
+
+	var m Money
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": 250, "currency": "EUR"}`), &m))
+	assert.Equal(t, New(250, "EUR"), m)
+
+	// legacy float price
+	assert.Nil(t, json.Unmarshal([]byte(`10.1`), &m))
+	assert.Equal(t, New(1010, DefaultCurrency), m)
+
+	m = New(1, "USD")
+	assert.Nil(t, json.Unmarshal([]byte(`null`), &m))
+	assert.Equal(t, New(1, "USD"), m)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`"ten"`), &m))
+}
@@ -0,0 +1,161 @@
+package pictures
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	// decoders of the accepted formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// largest decoded picture, rejects decompression bombs
+	maxPixels = 40_000_000
+
+	thumbnailQuality = 85
+
+	// bits of a picture id, the integers a float64 holds exactly
+	maxIdBits = 53
+)
+
+// Upload is a picture sent by the seller of the item
+type Upload struct {
+	ItemId      string
+	Seller      int64
+	ContentType string
+	Data        []byte
+}
+
+// content types of the accepted pictures
+var ContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Check reads the picture header only : the declared content type must be accepted and match
+// the content, the dimensions must be accepted. It is cheap, run it before the full decode
+func Check(data []byte, contentType string) error {
+	if !ContentTypes[contentType] {
+		return fmt.Errorf("content type %q is not accepted, use jpeg, png or gif", contentType)
+	}
+	if detected := http.DetectContentType(data); detected != contentType {
+		return fmt.Errorf("content is %s, not %s", detected, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid picture : %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return errors.New("picture dimensions are not accepted")
+	}
+	return nil
+}
+
+// Decode checks the picture and decodes it
+func Decode(data []byte, contentType string) (image.Image, error) {
+	if err := Check(data, contentType); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture : %w", err)
+	}
+	return img, nil
+}
+
+// Thumbnail scales the picture down to fit a size x size square keeping the aspect ratio,
+// every thumbnail pixel averages the source pixels it covers.
+// Transparent pixels are put on a white background
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+		if tw < 1 {
+			tw = 1
+		}
+		if th < 1 {
+			th = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			dst.Set(x, y, average(src, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// average is the mean color of the source rectangle composed over white
+func average(src image.Image, x0, y0, x1, y1 int) color.Color {
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			pr, pg, pb, pa := src.At(x, y).RGBA()
+			r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.White
+	}
+	// colors are alpha premultiplied, white fills what the alpha leaves
+	white := 0xffff*n - a
+	return color.RGBA64{
+		R: uint16((r + white) / n),
+		G: uint16((g + white) / n),
+		B: uint16((b + white) / n),
+		A: 0xffff,
+	}
+}
+
+// EncodeThumbnail writes the thumbnail as jpeg
+func EncodeThumbnail(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: thumbnailQuality})
+}
+
+// NewId is a random positive picture id, concurrent uploads never share an id.
+// Ids are at most 2^53-1, javascript clients read them as exact numbers
+func NewId() (int64, error) {
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		if id := int64(binary.BigEndian.Uint64(buf[:]) >> (64 - maxIdBits)); id > 0 {
+			return id, nil
+		}
+	}
+}
+
+// OriginalKey is the blob key of the uploaded picture
+func OriginalKey(itemId string, pictureId int64) string {
+	return fmt.Sprintf("items/%s/%d", itemId, pictureId)
+}
+
+// ThumbnailKey is the blob key of the picture thumbnail
+func ThumbnailKey(itemId string, pictureId int64) string {
+	return fmt.Sprintf("items/%s/%d_thumbnail", itemId, pictureId)
+}
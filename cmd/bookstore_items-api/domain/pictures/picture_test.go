@@ -0,0 +1,95 @@
+package pictures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	data := pngBytes(t, 40, 20)
+
+	img, err := Decode(data, "image/png")
+	assert.Nil(t, err)
+	assert.Equal(t, 40, img.Bounds().Dx())
+
+	_, err = Decode(data, "image/jpeg")
+	assert.NotNil(t, err)
+
+	_, err = Decode(data, "image/webp")
+	assert.NotNil(t, err)
+
+	_, err = Decode([]byte("<html></html>"), "image/png")
+	assert.NotNil(t, err)
+
+	_, err = Decode(data[:60], "image/png")
+	assert.NotNil(t, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	img, _ := Decode(pngBytes(t, 400, 100), "image/png")
+
+	thumb := Thumbnail(img, 200)
+	assert.Equal(t, image.Rect(0, 0, 200, 50), thumb.Bounds())
+
+	// the top row is red and opaque, the rest transparent goes white
+	r, g, b, _ := thumb.At(0, 49).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b})
+	r, g, _, _ = thumb.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.True(t, g < 0xffff)
+
+	small := Thumbnail(img, 1000)
+	assert.Equal(t, image.Rect(0, 0, 400, 100), small.Bounds())
+
+	tiny := Thumbnail(img, 10)
+	assert.Equal(t, image.Rect(0, 0, 10, 2), tiny.Bounds())
+
+	var buf bytes.Buffer
+	assert.Nil(t, EncodeThumbnail(&buf, thumb))
+	_, err := Decode(buf.Bytes(), "image/jpeg")
+	assert.Nil(t, err)
+}
+
+// a header announcing 10000 x 10000 pixels is refused without decoding the pixels
+func TestCheckDimensions(t *testing.T) {
+	data := pngBytes(t, 10, 10)
+	// IHDR : length, type, width, height ... and the crc of type and data
+	binary.BigEndian.PutUint32(data[16:], 10000)
+	binary.BigEndian.PutUint32(data[20:], 10000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	err := Check(data, "image/png")
+	assert.EqualError(t, err, "picture dimensions are not accepted")
+	assert.Nil(t, Check(pngBytes(t, 10, 10), "image/png"))
+}
+
+func TestNewId(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id, err := NewId()
+		assert.Nil(t, err)
+		assert.True(t, id > 0)
+		assert.True(t, id <= 1<<53-1)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, "items/abc/3", OriginalKey("abc", 3))
+	assert.Equal(t, "items/abc/3_thumbnail", ThumbnailKey("abc", 3))
+}
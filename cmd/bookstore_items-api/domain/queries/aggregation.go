@@ -0,0 +1,125 @@
+package queries
+
+import (
+	"fmt"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/olivere/elastic"
+)
+
+const (
+	AggTerms     = "terms"
+	AggRange     = "range"
+	AggHistogram = "histogram"
+
+	defaultTermsSize = 10
+	maxTermsSize     = 100
+	maxAggregations  = 10
+
+	// MaxBuckets caps the buckets of a histogram like the elasticsearch search.max_buckets setting
+	MaxBuckets = 10000
+)
+
+// public aggregation field -> indexed field
+var aggregationFields = map[string]string{
+	"status":             "status",
+	"seller":             "seller",
+	"price":              "price.amount",
+	"available_quantity": "available_quantity",
+	"sold_quantity":      "sold_quantity",
+}
+
+type AggregationRange struct {
+	Key  string   `json:"key"`
+	From *float64 `json:"from"`
+	To   *float64 `json:"to"`
+}
+
+// Aggregation is a named facet computed over the items matched by the query
+type Aggregation struct {
+	Name     string             `json:"name"`
+	Type     string             `json:"type"`
+	Field    string             `json:"field"`
+	Size     int                `json:"size"`
+	Interval float64            `json:"interval"`
+	Ranges   []AggregationRange `json:"ranges"`
+}
+
+func (a Aggregation) Validate() rest_errors.RestErr {
+	if a.Name == "" {
+		return rest_errors.NewBadRequestError("aggregation name is required")
+	}
+	if _, ok := aggregationFields[a.Field]; !ok {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported aggregation field %s", a.Field))
+	}
+	switch a.Type {
+	case AggTerms:
+		if a.Size < 0 || a.Size > maxTermsSize {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("aggregation size must be between 1 and %d", maxTermsSize))
+		}
+	case AggRange:
+		if len(a.Ranges) == 0 {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("aggregation %s has no ranges", a.Name))
+		}
+	case AggHistogram:
+		// the aggregation fields hold integers, a smaller interval only adds empty buckets
+		if a.Interval < 1 {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("aggregation %s interval must be at least 1", a.Name))
+		}
+	default:
+		return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported aggregation type %s", a.Type))
+	}
+	return nil
+}
+
+func (a Aggregation) Build() elastic.Aggregation {
+	field := aggregationFields[a.Field]
+	switch a.Type {
+	case AggRange:
+		agg := elastic.NewRangeAggregation().Field(field)
+		for _, r := range a.Ranges {
+			var from, to interface{}
+			if r.From != nil {
+				from = *r.From
+			}
+			if r.To != nil {
+				to = *r.To
+			}
+			agg.AddRangeWithKey(r.Key, from, to)
+		}
+		return agg
+	case AggHistogram:
+		return elastic.NewHistogramAggregation().Field(field).Interval(a.Interval).MinDocCount(0)
+	default:
+		return elastic.NewTermsAggregation().Field(field).Size(a.TermsSize())
+	}
+}
+
+// TooManyBuckets is the error of a histogram exceeding MaxBuckets
+func TooManyBuckets(name string) rest_errors.RestErr {
+	return rest_errors.NewBadRequestError(fmt.Sprintf("aggregation %s has more than %d buckets, use a larger interval", name, MaxBuckets))
+}
+
+func (a Aggregation) TermsSize() int {
+	if a.Size == 0 {
+		return defaultTermsSize
+	}
+	return a.Size
+}
+
+func validateAggregations(aggs []Aggregation) rest_errors.RestErr {
+	if len(aggs) > maxAggregations {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("too many aggregations, max %d", maxAggregations))
+	}
+	names := make(map[string]bool, len(aggs))
+	for _, a := range aggs {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		if names[a.Name] {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("duplicate aggregation %s", a.Name))
+		}
+		names[a.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregationValidateFailed(t *testing.T) {
+	cases := [][]Aggregation{
+		{{Type: AggTerms, Field: "status"}},
+		{{Name: "a", Type: AggTerms, Field: "title"}},
+		{{Name: "a", Type: "avg", Field: "price"}},
+		{{Name: "a", Type: AggTerms, Field: "status", Size: maxTermsSize + 1}},
+		{{Name: "a", Type: AggRange, Field: "price"}},
+		{{Name: "a", Type: AggHistogram, Field: "price"}},
+		{{Name: "a", Type: AggHistogram, Field: "price", Interval: 0.001}},
+		{{Name: "a", Type: AggTerms, Field: "status"}, {Name: "a", Type: AggTerms, Field: "seller"}},
+	}
+	for _, aggs := range cases {
+		err := EsQuery{Aggregations: aggs}.Validate(testFields)
+		assert.NotNil(t, err)
+		assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	}
+}
+
+func TestSourceAggregations(t *testing.T) {
+	to := 10.0
+	from := 10.0
+	q := EsQuery{Aggregations: []Aggregation{
+		{Name: "by_status", Type: AggTerms, Field: "status"},
+		{Name: "by_price", Type: AggRange, Field: "price", Ranges: []AggregationRange{
+			{Key: "cheap", To: &to},
+			{Key: "expensive", From: &from},
+		}},
+		{Name: "price_histogram", Type: AggHistogram, Field: "price", Interval: 5},
+	}}
+	assert.Nil(t, q.Validate(testFields))
+
+	src := sourceJson(t, q)
+	bytes, _ := json.Marshal(src["aggregations"])
+	aggs := string(bytes)
+
+	assert.Contains(t, aggs, `"by_status":{"terms":{"field":"status","size":10}}`)
+	assert.Contains(t, aggs, `"ranges":[{"key":"cheap","to":10},{"from":10,"key":"expensive"}]`)
+	assert.Contains(t, aggs, `"price_histogram":{"histogram":{"field":"price.amount","interval":5,"min_doc_count":0}}`)
+}
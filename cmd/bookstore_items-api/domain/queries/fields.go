@@ -0,0 +1,80 @@
+package queries
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// FieldSet is a whitelist of the document fields a query may filter on
+type FieldSet map[string]bool
+
+// NewFieldSet collects json field paths of the document struct.
+// Nested structs and slices of structs are flattened : description.plain_text, pictures.url
+func NewFieldSet(doc interface{}) FieldSet {
+	fields := make(FieldSet)
+	collectFields(reflect.TypeOf(doc), "", fields)
+	return fields
+}
+
+// NewMappedFieldSet collects the field paths an index mapping of the document type indexes.
+// Objects and fields mapped with "index": false or "enabled": false are left out,
+// so are the excluded top level fields, stored in the document but not a part of it
+func NewMappedFieldSet(mapping string, docType string, excluded ...string) (FieldSet, error) {
+	var m struct {
+		Mappings map[string]struct {
+			Properties map[string]mappedField `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &m); err != nil {
+		return nil, err
+	}
+	properties := m.Mappings[docType].Properties
+	for _, name := range excluded {
+		delete(properties, name)
+	}
+	fields := make(FieldSet)
+	collectMapped(properties, "", fields)
+	return fields, nil
+}
+
+type mappedField struct {
+	Type       string                 `json:"type"`
+	Index      *bool                  `json:"index"`
+	Enabled    *bool                  `json:"enabled"`
+	Properties map[string]mappedField `json:"properties"`
+}
+
+func collectMapped(properties map[string]mappedField, prefix string, fields FieldSet) {
+	for name, f := range properties {
+		switch {
+		case f.Properties != nil:
+			collectMapped(f.Properties, prefix+name+".", fields)
+		case f.Type == "object" || f.Index != nil && !*f.Index || f.Enabled != nil && !*f.Enabled:
+		default:
+			fields[prefix+name] = true
+		}
+	}
+}
+
+func (f FieldSet) Contains(field string) bool {
+	return f[field]
+}
+
+func collectFields(t reflect.Type, prefix string, fields FieldSet) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[prefix+name] = true
+		collectFields(sf.Type, prefix+name+".", fields)
+	}
+}
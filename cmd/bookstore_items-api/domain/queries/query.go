@@ -1,21 +1,246 @@
 package queries
 
-import "github.com/olivere/elastic"
+import (
+	"fmt"
+
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/olivere/elastic"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+
+	// elasticsearch index.max_result_window default
+	maxResultWindow = 10000
+
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+// public sort field -> indexed field
+var sortFields = map[string]string{
+	"price":         "price.amount",
+	"sold_quantity": "sold_quantity",
+	"title":         "title.keyword",
+}
 
 type FieldValue struct {
 	Field string      `json:"field"`
 	Value interface{} `json:"value"`
 }
 
+type RangeValue struct {
+	Field string      `json:"field"`
+	Gt    interface{} `json:"gt"`
+	Gte   interface{} `json:"gte"`
+	Lt    interface{} `json:"lt"`
+	Lte   interface{} `json:"lte"`
+}
+
+type SortField struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
+}
+
+// Visibility limits results to documents in the public status,
+// the owner sees own documents in any status
+type Visibility struct {
+	Status string
+	Owner  int64
+}
+
 type EsQuery struct {
-	Equals []FieldValue `json:"equals"`
+	Text         string        `json:"q"`
+	Equals       []FieldValue  `json:"equals"`
+	NotEquals    []FieldValue  `json:"not_equals"`
+	AnyOf        []FieldValue  `json:"any_of"`
+	Range        []RangeValue  `json:"range"`
+	Prefix       []FieldValue  `json:"prefix"`
+	Exists       []string      `json:"exists"`
+	Page         int           `json:"page"`
+	Size         int           `json:"size"`
+	Sort         []SortField   `json:"sort"`
+	SearchAfter  []interface{} `json:"search_after"`
+	Aggregations []Aggregation `json:"aggregations"`
+
+	// set by the service for the caller, never by clients
+	Visibility *Visibility `json:"-"`
+	Boosts     *TextBoosts `json:"-"`
+}
+
+// Validate checks paging, sorting and rejects filters on fields out of the whitelist
+func (q EsQuery) Validate(fields FieldSet) rest_errors.RestErr {
+	for _, field := range q.filteredFields() {
+		if !fields.Contains(field) {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported field %s", field))
+		}
+	}
+	for _, r := range q.Range {
+		if r.Gt == nil && r.Gte == nil && r.Lt == nil && r.Lte == nil {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("empty range for field %s", r.Field))
+		}
+	}
+	if err := validateText(q.Text); err != nil {
+		return rest_errors.NewBadRequestError(err.Error())
+	}
+	if q.Page < 0 {
+		return rest_errors.NewBadRequestError("page must not be negative")
+	}
+	if q.Size < 0 || q.Size > MaxPageSize {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("size must be between 0 and %d, 0 is the default %d",
+			MaxPageSize, DefaultPageSize))
+	}
+	for _, s := range q.Sort {
+		if _, ok := sortFields[s.Field]; !ok {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported sort field %s", s.Field))
+		}
+		if s.Order != "" && s.Order != orderAsc && s.Order != orderDesc {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("unsupported sort order %s", s.Order))
+		}
+	}
+	if n := q.sortLength(); len(q.SearchAfter) > 0 && len(q.SearchAfter) != n {
+		return rest_errors.NewBadRequestError(fmt.Sprintf("search_after must have %d values, one per sort field", n))
+	}
+	if len(q.SearchAfter) == 0 && q.From()+q.PageSize() > maxResultWindow {
+		return rest_errors.NewBadRequestError("page is too deep, use search_after")
+	}
+	return validateAggregations(q.Aggregations)
+}
+
+func (q EsQuery) PageNumber() int {
+	if q.Page < 1 {
+		return 1
+	}
+	return q.Page
+}
+
+func (q EsQuery) PageSize() int {
+	if q.Size < 1 {
+		return DefaultPageSize
+	}
+	return q.Size
+}
+
+func (q EsQuery) From() int {
+	return (q.PageNumber() - 1) * q.PageSize()
+}
+
+func (q EsQuery) filteredFields() []string {
+	fields := make([]string, 0)
+	for _, ls := range [][]FieldValue{q.Equals, q.NotEquals, q.AnyOf, q.Prefix} {
+		for _, fv := range ls {
+			fields = append(fields, fv.Field)
+		}
+	}
+	for _, r := range q.Range {
+		fields = append(fields, r.Field)
+	}
+	return append(fields, q.Exists...)
+}
+
+// TextBoosts returns the boosts of the free text search
+func (q EsQuery) TextBoosts() TextBoosts {
+	if q.Boosts == nil {
+		return DefaultTextBoosts
+	}
+	return *q.Boosts
 }
 
 func (q EsQuery) Build() elastic.Query {
 	query := elastic.NewBoolQuery()
-	queries := make([]elastic.Query, 0)
-	for _, q := range q.Equals {
-		queries = append(queries, elastic.NewMatchQuery(q.Field, q.Value))
+	if q.Text != "" {
+		query.Must(q.TextBoosts().query(q.Text))
+	}
+	for _, fv := range q.Equals {
+		query.Must(elastic.NewMatchQuery(fv.Field, fv.Value))
+	}
+	for _, fv := range q.NotEquals {
+		query.MustNot(elastic.NewMatchQuery(fv.Field, fv.Value))
+	}
+	for _, fv := range q.AnyOf {
+		query.Should(elastic.NewMatchQuery(fv.Field, fv.Value))
+	}
+	if len(q.AnyOf) > 0 {
+		query.MinimumNumberShouldMatch(1)
+	}
+	for _, r := range q.Range {
+		query.Filter(r.build())
+	}
+	for _, fv := range q.Prefix {
+		query.Filter(elastic.NewPrefixQuery(fv.Field, fmt.Sprint(fv.Value)))
+	}
+	for _, field := range q.Exists {
+		query.Filter(elastic.NewExistsQuery(field))
+	}
+	if q.Visibility != nil {
+		query.Filter(q.Visibility.build())
+	}
+	return query
+}
+
+func (v Visibility) build() elastic.Query {
+	query := elastic.NewBoolQuery().Should(elastic.NewTermQuery("status", v.Status))
+	if v.Owner != 0 {
+		query.Should(elastic.NewTermQuery("seller", v.Owner))
+	}
+	return query.MinimumNumberShouldMatch(1)
+}
+
+func (r RangeValue) build() elastic.Query {
+	query := elastic.NewRangeQuery(r.Field)
+	if r.Gt != nil {
+		query.Gt(r.Gt)
+	}
+	if r.Gte != nil {
+		query.Gte(r.Gte)
+	}
+	if r.Lt != nil {
+		query.Lt(r.Lt)
+	}
+	if r.Lte != nil {
+		query.Lte(r.Lte)
+	}
+	return query
+}
+
+// Source builds the search request : query, paging, sorting and aggregations.
+// search_after pages are always fetched from the beginning of the window
+func (q EsQuery) Source() *elastic.SearchSource {
+	source := elastic.NewSearchSource().
+		Query(q.Build()).
+		Size(q.PageSize()).
+		SortBy(q.sorters()...)
+
+	for _, agg := range q.Aggregations {
+		source.Aggregation(agg.Name, agg.Build())
+	}
+	if q.Text != "" {
+		source.Highlight(q.TextBoosts().highlight())
+	}
+
+	if len(q.SearchAfter) > 0 {
+		return source.SearchAfter(q.SearchAfter...)
+	}
+	return source.From(q.From())
+}
+
+// sortLength is the number of sort values of a hit, the sort fields or the score and the tiebreaker
+func (q EsQuery) sortLength() int {
+	if len(q.Sort) == 0 {
+		return 2
+	}
+	return len(q.Sort) + 1
+}
+
+func (q EsQuery) sorters() []elastic.Sorter {
+	sorters := make([]elastic.Sorter, 0, len(q.Sort)+2)
+	for _, s := range q.Sort {
+		sorters = append(sorters, elastic.NewFieldSort(sortFields[s.Field]).Order(s.Order != orderDesc))
+	}
+	if len(sorters) == 0 {
+		sorters = append(sorters, elastic.NewScoreSort())
 	}
-	return query.Must(queries...)
+	// tiebreaker keeps search_after pages stable, the id keyword field has doc values unlike _id
+	return append(sorters, elastic.NewFieldSort("id").Asc())
 }
@@ -0,0 +1,182 @@
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDoc struct {
+	Title  string `json:"title"`
+	Price  int    `json:"price"`
+	Status string `json:"status"`
+	Nested struct {
+		Text string `json:"text"`
+	} `json:"nested"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Skipped string `json:"-"`
+}
+
+var testFields = NewFieldSet(testDoc{})
+
+func sourceJson(t *testing.T, q EsQuery) map[string]interface{} {
+	src, err := q.Source().Source()
+	assert.Nil(t, err)
+	bytes, _ := json.Marshal(src)
+
+	var result map[string]interface{}
+	json.Unmarshal(bytes, &result)
+	return result
+}
+
+func TestValidateOk(t *testing.T) {
+	q := EsQuery{Page: 2, Size: 20, Sort: []SortField{{Field: "price", Order: "desc"}}}
+	assert.Nil(t, q.Validate(testFields))
+}
+
+func TestValidateFailed(t *testing.T) {
+	cases := []EsQuery{
+		{Page: -1},
+		{Size: MaxPageSize + 1},
+		{Sort: []SortField{{Field: "seller"}}},
+		{Sort: []SortField{{Field: "price", Order: "up"}}},
+		{Page: 1001, Size: 10},
+		{Equals: []FieldValue{{Field: "unknown", Value: 1}}},
+		{NotEquals: []FieldValue{{Field: "Skipped", Value: 1}}},
+		{Exists: []string{"nested"}, Prefix: []FieldValue{{Field: "nested.unknown", Value: "a"}}},
+		{Range: []RangeValue{{Field: "price"}}},
+		{SearchAfter: []interface{}{10.5}},
+		{Sort: []SortField{{Field: "price"}, {Field: "title"}}, SearchAfter: []interface{}{10.5, "id"}},
+	}
+	for _, q := range cases {
+		err := q.Validate(testFields)
+		assert.NotNil(t, err)
+		assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	}
+}
+
+func TestValidateDeepPageWithSearchAfter(t *testing.T) {
+	q := EsQuery{Page: 1001, Size: 10, SearchAfter: []interface{}{10.5, "id"}}
+	assert.Nil(t, q.Validate(testFields))
+
+	q = EsQuery{Sort: []SortField{{Field: "price"}, {Field: "title"}}, SearchAfter: []interface{}{10.5, "a", "id"}}
+	assert.Nil(t, q.Validate(testFields))
+}
+
+func TestSourcePaging(t *testing.T) {
+	src := sourceJson(t, EsQuery{Page: 3, Size: 20})
+	assert.EqualValues(t, 40, src["from"])
+	assert.EqualValues(t, 20, src["size"])
+}
+
+func TestSourceDefaults(t *testing.T) {
+	src := sourceJson(t, EsQuery{})
+	assert.EqualValues(t, 0, src["from"])
+	assert.EqualValues(t, DefaultPageSize, src["size"])
+}
+
+func TestSourceSort(t *testing.T) {
+	src := sourceJson(t, EsQuery{Sort: []SortField{{Field: "title", Order: "desc"}}})
+	sort := src["sort"].([]interface{})
+	assert.Equal(t, 2, len(sort))
+	assert.Contains(t, sort[0], "title.keyword")
+	assert.Contains(t, sort[1], "id")
+	assert.NotContains(t, sort[1], "_id")
+}
+
+func TestSourceSearchAfter(t *testing.T) {
+	src := sourceJson(t, EsQuery{Page: 5, SearchAfter: []interface{}{10.5, "id"}})
+	_, hasFrom := src["from"]
+	assert.False(t, hasFrom)
+	assert.Equal(t, []interface{}{10.5, "id"}, src["search_after"])
+}
+
+func TestNewFieldSet(t *testing.T) {
+	for _, f := range []string{"title", "price", "status", "nested", "nested.text", "tags", "tags.name"} {
+		assert.True(t, testFields.Contains(f), f)
+	}
+	assert.False(t, testFields.Contains("Skipped"))
+	assert.False(t, testFields.Contains("-"))
+}
+
+func TestNewMappedFieldSet(t *testing.T) {
+	mapping := `{"mappings": {"_doc": {"properties": {
+		"title": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+		"video": {"type": "keyword", "index": false},
+		"price": {"properties": {"amount": {"type": "long"}}},
+		"raw": {"type": "object", "enabled": false},
+		"internal": {"type": "long"}
+	}}}}`
+	fields, err := NewMappedFieldSet(mapping, "_doc", "internal")
+	assert.Nil(t, err)
+	assert.Equal(t, FieldSet{"title": true, "price.amount": true}, fields)
+
+	_, err = NewMappedFieldSet("{", "_doc")
+	assert.NotNil(t, err)
+}
+
+func TestBuildClauses(t *testing.T) {
+	q := EsQuery{
+		Equals:    []FieldValue{{Field: "title", Value: "go"}},
+		NotEquals: []FieldValue{{Field: "status", Value: "archived"}},
+		AnyOf:     []FieldValue{{Field: "status", Value: "active"}, {Field: "status", Value: "paused"}},
+		Range:     []RangeValue{{Field: "price", Gte: 10, Lt: 20}},
+		Prefix:    []FieldValue{{Field: "title", Value: "har"}},
+		Exists:    []string{"nested.text"},
+	}
+	assert.Nil(t, q.Validate(testFields))
+
+	src, err := q.Build().Source()
+	assert.Nil(t, err)
+	bytes, _ := json.Marshal(src)
+
+	var result map[string]map[string]interface{}
+	json.Unmarshal(bytes, &result)
+	boolQuery := result["bool"]
+
+	assert.NotNil(t, boolQuery["must"])
+	assert.NotNil(t, boolQuery["must_not"])
+	assert.Equal(t, 2, len(boolQuery["should"].([]interface{})))
+	assert.EqualValues(t, "1", boolQuery["minimum_should_match"])
+	assert.Equal(t, 3, len(boolQuery["filter"].([]interface{})))
+	assert.Contains(t, string(bytes), `"range":{"price":{"from":10,"include_lower":true,"include_upper":false,"to":20}}`)
+}
+
+func TestBuildVisibility(t *testing.T) {
+	q := EsQuery{Visibility: &Visibility{Status: "active", Owner: 7}}
+
+	src, err := q.Build().Source()
+	assert.Nil(t, err)
+	bytes, _ := json.Marshal(src)
+
+	assert.Contains(t, string(bytes), `"filter":{"bool":{"minimum_should_match":"1","should":[{"term":{"status":"active"}},{"term":{"seller":7}}]}}`)
+
+	q.Visibility.Owner = 0
+	src, _ = q.Build().Source()
+	bytes, _ = json.Marshal(src)
+	assert.NotContains(t, string(bytes), "seller")
+}
+
+func TestSourceText(t *testing.T) {
+	q := EsQuery{Text: "tolkien hobbit", Boosts: &TextBoosts{Title: 5, Description: 1, Authors: 2}}
+	assert.Nil(t, q.Validate(testFields))
+
+	src := sourceJson(t, q)
+	bytes, _ := json.Marshal(src)
+
+	assert.Contains(t, string(bytes), `"multi_match":{"fields":["title^5.000000","description.plain_text^1.000000","book.authors^2.000000"],"operator":"or","query":"tolkien hobbit","tie_breaker":0.3,"type":"cross_fields"}`)
+	highlight := src["highlight"].(map[string]interface{})
+	assert.Equal(t, []interface{}{HighlightPreTag}, highlight["pre_tags"])
+	assert.Equal(t, "html", highlight["encoder"])
+	assert.Len(t, highlight["fields"], 3)
+}
+
+func TestValidateTextLength(t *testing.T) {
+	q := EsQuery{Text: strings.Repeat("a", maxTextLength+1)}
+	assert.Equal(t, http.StatusBadRequest, q.Validate(testFields).Status())
+}
@@ -0,0 +1,61 @@
+package queries
+
+import (
+	"fmt"
+
+	"github.com/olivere/elastic"
+)
+
+const (
+	maxTextLength = 256
+
+	HighlightPreTag  = "<em>"
+	HighlightPostTag = "</em>"
+)
+
+// TextBoosts weights the fields a free text search runs across
+type TextBoosts struct {
+	Title       float64
+	Description float64
+	Authors     float64
+}
+
+var DefaultTextBoosts = TextBoosts{Title: 3, Description: 1, Authors: 2}
+
+type BoostedField struct {
+	Field string
+	Boost float64
+}
+
+// Fields returns the searched fields with their boosts
+func (b TextBoosts) Fields() []BoostedField {
+	return []BoostedField{
+		{Field: "title", Boost: b.Title},
+		{Field: "description.plain_text", Boost: b.Description},
+		{Field: "book.authors", Boost: b.Authors},
+	}
+}
+
+func (b TextBoosts) query(text string) elastic.Query {
+	query := elastic.NewMultiMatchQuery(text).Type("cross_fields").Operator("or").TieBreaker(0.3)
+	for _, f := range b.Fields() {
+		query.FieldWithBoost(f.Field, f.Boost)
+	}
+	return query
+}
+
+func (b TextBoosts) highlight() *elastic.Highlight {
+	// titles and descriptions are stored as sent, the html encoder escapes them around the tags
+	highlight := elastic.NewHighlight().PreTags(HighlightPreTag).PostTags(HighlightPostTag).Encoder("html")
+	for _, f := range b.Fields() {
+		highlight.Fields(elastic.NewHighlighterField(f.Field).FragmentSize(150).NumOfFragments(3))
+	}
+	return highlight
+}
+
+func validateText(text string) error {
+	if len(text) > maxTextLength {
+		return fmt.Errorf("q must be at most %d characters", maxTextLength)
+	}
+	return nil
+}
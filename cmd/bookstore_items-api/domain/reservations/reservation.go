@@ -0,0 +1,51 @@
+package reservations
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusCommitted = "committed"
+	StatusReleased  = "released"
+)
+
+// Reservation holds item stock for a buyer until it is committed as sold,
+// released by the buyer or expired
+type Reservation struct {
+	Id          string    `json:"id"`
+	ItemId      string    `json:"item_id"`
+	Buyer       int64     `json:"buyer"`
+	Quantity    int       `json:"quantity"`
+	Status      string    `json:"status"`
+	DateCreated time.Time `json:"date_created"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// stored revision, not a part of the document
+	Version *es.DocVersion `json:"-"`
+}
+
+func (r Reservation) Validate() rest_errors.RestErr {
+	if r.Quantity <= 0 {
+		return rest_errors.NewBadRequestError("quantity must be positive")
+	}
+	return nil
+}
+
+func (r Reservation) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// newId returns a random url safe id of the length of the elasticsearch generated ones
+func newId() (string, error) {
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,139 @@
+package reservations
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/olivere/elastic"
+)
+
+const (
+	indexName       = "reservations"
+	typeReservation = "_doc"
+)
+
+const reservationsMapping = `{
+	"mappings": {
+		"_doc": {
+			"dynamic": "strict",
+			"properties": {
+				"id": {"type": "keyword"},
+				"item_id": {"type": "keyword"},
+				"buyer": {"type": "long"},
+				"quantity": {"type": "integer"},
+				"status": {"type": "keyword"},
+				"date_created": {"type": "date"},
+				"expires_at": {"type": "date"}
+			}
+		}
+	}
+}`
+
+type ReservationsPersistInterface interface {
+	Save(*Reservation) rest_errors.RestErr
+	Get(Reservation) (*Reservation, rest_errors.RestErr)
+	Update(*Reservation) rest_errors.RestErr
+	FindExpired(time.Time, int) ([]Reservation, rest_errors.RestErr)
+}
+
+type persist struct {
+}
+
+func NewReservationPersister() ReservationsPersistInterface {
+	return new(persist)
+}
+
+// EnsureIndex creates the reservations index on a fresh cluster
+func EnsureIndex() error {
+	exists, err := es.Client.IndexExists(indexName)
+	if err != nil || exists {
+		return err
+	}
+	return es.Client.CreateIndex(indexName, reservationsMapping)
+}
+
+// Save stores a new reservation, its id is generated here to be a part of the document too
+func (p *persist) Save(r *Reservation) rest_errors.RestErr {
+	id, err := newId()
+	if err != nil {
+		return rest_errors.NewInternalServerError("save reservation error", err)
+	}
+	r.Id = id
+	result, err := es.Client.Create(indexName, typeReservation, r.Id, r)
+	if err != nil {
+		r.Id = ""
+		return rest_errors.NewInternalServerError("save reservation error", err)
+	}
+	r.Version = &es.DocVersion{SeqNo: result.SeqNo, PrimaryTerm: result.PrimaryTerm}
+	return nil
+}
+
+func (p *persist) Get(r Reservation) (*Reservation, rest_errors.RestErr) {
+	result, err := es.Client.Get(indexName, typeReservation, r.Id)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, rest_errors.NewNotFoundError("reservation not found")
+		}
+		return nil, rest_errors.NewInternalServerError("get reservation error", err)
+	}
+
+	bytes, err := result.Source.MarshalJSON()
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
+	}
+	if err := json.Unmarshal(bytes, &r); err != nil {
+		return nil, rest_errors.NewInternalServerError("elk parse get response error", err)
+	}
+
+	r.Id = result.Id
+	r.Version = es.VersionOf(result)
+	return &r, nil
+}
+
+// Update writes the reservation if it has not been changed since it was read
+func (p *persist) Update(r *Reservation) rest_errors.RestErr {
+	result, err := es.Client.Update(indexName, typeReservation, r.Id, r, r.Version)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return rest_errors.NewNotFoundError("reservation not found")
+		}
+		if elastic.IsConflict(err) {
+			return rest_errors.NewConflictError("reservation has been modified concurrently")
+		}
+		return rest_errors.NewInternalServerError("update reservation error", err)
+	}
+	r.Version = &es.DocVersion{SeqNo: result.SeqNo, PrimaryTerm: result.PrimaryTerm}
+	return nil
+}
+
+// FindExpired returns pending reservations expired before the moment, with their versions
+// so that releasing them loses against a concurrent commit
+func (p *persist) FindExpired(before time.Time, limit int) ([]Reservation, rest_errors.RestErr) {
+	query := elastic.NewBoolQuery().Filter(
+		elastic.NewTermQuery("status", StatusPending),
+		elastic.NewRangeQuery("expires_at").Lte(before),
+	)
+	source := elastic.NewSearchSource().
+		Query(query).
+		Size(limit).
+		Sort("expires_at", true).
+		SeqNoAndPrimaryTerm(true)
+
+	result, err := es.Client.Search(indexName, source)
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("search reservations error", err)
+	}
+
+	ls := make([]Reservation, len(result.Hits.Hits))
+	for idx, hit := range result.Hits.Hits {
+		bytes, _ := hit.Source.MarshalJSON()
+		if err := json.Unmarshal(bytes, &ls[idx]); err != nil {
+			return nil, rest_errors.NewInternalServerError("elk parse search response error", err)
+		}
+		ls[idx].Id = hit.Id
+		ls[idx].Version = es.HitVersion(hit)
+	}
+	return ls, nil
+}
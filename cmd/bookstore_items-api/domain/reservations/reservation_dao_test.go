@@ -0,0 +1,77 @@
+package reservations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func esServer(t *testing.T, handler http.HandlerFunc) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := elastic.NewClient(elastic.SetURL(srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Client.SetClient(c)
+}
+
+// the expiry sweep updates with the versions found, a reservation committed meanwhile is not released
+func TestFindExpiredReturnsVersions(t *testing.T) {
+	var body map[string]interface{}
+	var seqNo, primaryTerm string
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/reservations/_search":
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"hits": {"hits": [{"_index": "reservations", "_id": "r1", "_seq_no": 7, "_primary_term": 2,
+				"_source": {"item_id": "1", "quantity": 2, "status": "pending"}}]}}`))
+		case "/reservations/_doc/r1":
+			seqNo, primaryTerm = r.URL.Query().Get("if_seq_no"), r.URL.Query().Get("if_primary_term")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": {"type": "version_conflict_engine_exception", "reason": "conflict"}, "status": 409}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	p := NewReservationPersister()
+	ls, err := p.FindExpired(time.Now(), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, true, body["seq_no_primary_term"])
+	assert.Equal(t, 1, len(ls))
+	assert.Equal(t, &es.DocVersion{SeqNo: 7, PrimaryTerm: 2}, ls[0].Version)
+
+	ls[0].Status = StatusReleased
+	err = p.Update(&ls[0])
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.Status())
+	assert.Equal(t, "7", seqNo)
+	assert.Equal(t, "2", primaryTerm)
+}
+
+func TestSaveWritesId(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	esServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"_index": "reservations", "_type": "_doc", "_id": "x", "_seq_no": 0, "_primary_term": 1, "result": "created"}`))
+	})
+
+	r := Reservation{ItemId: "1", Quantity: 2, Status: StatusPending}
+	assert.Nil(t, NewReservationPersister().Save(&r))
+	assert.NotEmpty(t, r.Id)
+	assert.Equal(t, "/reservations/_doc/"+r.Id, path)
+	assert.Equal(t, r.Id, body["id"])
+}
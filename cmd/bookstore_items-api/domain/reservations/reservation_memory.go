@@ -0,0 +1,92 @@
+package reservations
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// memoryPersist keeps reservations in the process memory with versioned writes
+type memoryPersist struct {
+	mu           sync.RWMutex
+	reservations map[string]Reservation
+	seqNo        int64
+}
+
+func NewMemoryPersister() ReservationsPersistInterface {
+	return &memoryPersist{
+		reservations: make(map[string]Reservation),
+	}
+}
+
+func (p *memoryPersist) nextVersion() *es.DocVersion {
+	version := &es.DocVersion{SeqNo: p.seqNo, PrimaryTerm: 1}
+	p.seqNo++
+	return version
+}
+
+func (p *memoryPersist) Save(r *Reservation) rest_errors.RestErr {
+	id, err := newId()
+	if err != nil {
+		return rest_errors.NewInternalServerError("save reservation error", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r.Id = id
+	r.Version = p.nextVersion()
+	p.reservations[r.Id] = *r
+	return nil
+}
+
+func (p *memoryPersist) Get(r Reservation) (*Reservation, rest_errors.RestErr) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stored, ok := p.reservations[r.Id]
+	if !ok {
+		return nil, rest_errors.NewNotFoundError("reservation not found")
+	}
+	version := *stored.Version
+	stored.Version = &version
+	return &stored, nil
+}
+
+func (p *memoryPersist) Update(r *Reservation) rest_errors.RestErr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, ok := p.reservations[r.Id]
+	if !ok {
+		return rest_errors.NewNotFoundError("reservation not found")
+	}
+	if r.Version != nil && *r.Version != *stored.Version {
+		return rest_errors.NewConflictError("reservation has been modified concurrently")
+	}
+	r.Version = p.nextVersion()
+	p.reservations[r.Id] = *r
+	return nil
+}
+
+func (p *memoryPersist) FindExpired(before time.Time, limit int) ([]Reservation, rest_errors.RestErr) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ls := make([]Reservation, 0)
+	for _, r := range p.reservations {
+		if r.Status == StatusPending && r.IsExpired(before) {
+			ls = append(ls, r)
+		}
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		return ls[i].ExpiresAt.Before(ls[j].ExpiresAt)
+	})
+	if len(ls) > limit {
+		ls = ls[:limit]
+	}
+	return ls, nil
+}
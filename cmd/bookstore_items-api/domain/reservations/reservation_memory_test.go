@@ -0,0 +1,52 @@
+package reservations
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUpdateRejectsStaleVersion(t *testing.T) {
+	p := NewMemoryPersister()
+	r := Reservation{ItemId: "1", Quantity: 1, Status: StatusPending}
+	assert.Nil(t, p.Save(&r))
+
+	first, _ := p.Get(Reservation{Id: r.Id})
+	second, _ := p.Get(Reservation{Id: r.Id})
+
+	first.Status = StatusCommitted
+	assert.Nil(t, p.Update(first))
+
+	second.Status = StatusReleased
+	err := p.Update(second)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.Status())
+
+	stored, _ := p.Get(Reservation{Id: r.Id})
+	assert.Equal(t, StatusCommitted, stored.Status)
+}
+
+func TestMemoryFindExpired(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := NewMemoryPersister()
+	for _, r := range []Reservation{
+		{ItemId: "late", Status: StatusPending, ExpiresAt: now.Add(-time.Minute)},
+		{ItemId: "early", Status: StatusPending, ExpiresAt: now.Add(-time.Hour)},
+		{ItemId: "live", Status: StatusPending, ExpiresAt: now.Add(time.Minute)},
+		{ItemId: "done", Status: StatusCommitted, ExpiresAt: now.Add(-time.Hour)},
+	} {
+		r := r
+		assert.Nil(t, p.Save(&r))
+	}
+
+	expired, err := p.FindExpired(now, 10)
+	assert.Nil(t, err)
+	assert.Len(t, expired, 2)
+	assert.Equal(t, "early", expired[0].ItemId)
+	assert.Equal(t, "late", expired[1].ItemId)
+
+	expired, _ = p.FindExpired(now, 1)
+	assert.Len(t, expired, 1)
+}
@@ -1,14 +1,37 @@
 module github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api
 
-go 1.16
+go 1.17
 
 require (
 	github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go v0.0.0-20210609214412-3524eff24a08
-	github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go v0.0.0-20210608201528-30f3944a9c2d
+	github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go v0.0.0-20210618165705-2eba8b769f1e
 	github.com/google/wire v0.5.0
 	github.com/gorilla/mux v1.8.0
 	github.com/ilyakaznacheev/cleanenv v1.2.5
-	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/olivere/elastic v6.2.35+incompatible
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/net v0.17.0
+)
+
+require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fortytw2/leaktest v1.3.0 // indirect
+	github.com/joho/godotenv v1.3.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	go.uber.org/zap v1.17.0 // indirect
+	gopkg.in/yaml.v2 v2.2.8 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+	olympos.io/encoding/edn v0.0.0-20200308123125-93e3b8dd0e24 // indirect
+)
+
+replace (
+	github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go => ../../pkg/bookstore-oauth-go
+	github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go => ../../pkg/bookstore_utils_go
 )
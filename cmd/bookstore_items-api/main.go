@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/config"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+const (
+	cmdReindex = "reindex"
+)
+
 type Args struct {
 	ConfigPath string
+	Command    string
 }
 
 func ProcessArgs(conf config.Config) Args {
@@ -26,11 +33,24 @@ func ProcessArgs(conf config.Config) Args {
 		help, _ := cleanenv.GetDescription(conf, nil)
 		fmt.Fprintln(flags.Output())
 		fmt.Fprintln(flags.Output(), help)
+		fmt.Fprintln(flags.Output(), "Commands:")
+		fmt.Fprintln(flags.Output(), "  reindex   copy items into a new index version and switch the items alias")
 	}
 	flags.Parse(os.Args[1:])
+	args.Command = flags.Arg(0)
 	return args
 }
 
+func reindex() {
+	es.Init()
+	index, err := items.Reindex()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("items alias points to ", index)
+}
+
 func main() {
 
 	args := ProcessArgs(config.Config{})
@@ -41,6 +61,11 @@ func main() {
 		os.Exit(2)
 	}
 
+	if args.Command == cmdReindex {
+		reindex()
+		return
+	}
+
 	fmt.Println("starting with config ", args.ConfigPath)
 
 	app := inject(conf)
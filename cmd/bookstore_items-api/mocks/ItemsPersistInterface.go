@@ -16,6 +16,54 @@ type ItemsPersistInterface struct {
 	mock.Mock
 }
 
+// ClaimIsbn provides a mock function with given fields: it
+func (_m *ItemsPersistInterface) ClaimIsbn(it items.Item) rest_errors.RestErr {
+	ret := _m.Called(it)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(items.Item) rest_errors.RestErr); ok {
+		r0 = rf(it)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: it
+func (_m *ItemsPersistInterface) Delete(it *items.Item) rest_errors.RestErr {
+	ret := _m.Called(it)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(*items.Item) rest_errors.RestErr); ok {
+		r0 = rf(it)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: _a0, _a1
+func (_m *ItemsPersistInterface) Export(_a0 int64, _a1 func([]items.Item) rest_errors.RestErr) rest_errors.RestErr {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(int64, func([]items.Item) rest_errors.RestErr) rest_errors.RestErr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: _a0
 func (_m *ItemsPersistInterface) Get(_a0 items.Item) (*items.Item, rest_errors.RestErr) {
 	ret := _m.Called(_a0)
@@ -41,6 +89,47 @@ func (_m *ItemsPersistInterface) Get(_a0 items.Item) (*items.Item, rest_errors.R
 	return r0, r1
 }
 
+// Related provides a mock function with given fields: _a0, _a1
+func (_m *ItemsPersistInterface) Related(_a0 items.Item, _a1 int) ([]items.Item, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []items.Item
+	if rf, ok := ret.Get(0).(func(items.Item, int) []items.Item); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]items.Item)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(items.Item, int) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// ReleaseIsbn provides a mock function with given fields: it, isbn
+func (_m *ItemsPersistInterface) ReleaseIsbn(it items.Item, isbn string) rest_errors.RestErr {
+	ret := _m.Called(it, isbn)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(items.Item, string) rest_errors.RestErr); ok {
+		r0 = rf(it, isbn)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: it
 func (_m *ItemsPersistInterface) Save(it *items.Item) rest_errors.RestErr {
 	ret := _m.Called(it)
@@ -57,16 +146,32 @@ func (_m *ItemsPersistInterface) Save(it *items.Item) rest_errors.RestErr {
 	return r0
 }
 
+// SaveAll provides a mock function with given fields: _a0
+func (_m *ItemsPersistInterface) SaveAll(_a0 []items.Item) []rest_errors.RestErr {
+	ret := _m.Called(_a0)
+
+	var r0 []rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func([]items.Item) []rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
 // Search provides a mock function with given fields: q
-func (_m *ItemsPersistInterface) Search(q queries.EsQuery) ([]items.Item, rest_errors.RestErr) {
+func (_m *ItemsPersistInterface) Search(q queries.EsQuery) (*items.SearchResult, rest_errors.RestErr) {
 	ret := _m.Called(q)
 
-	var r0 []items.Item
-	if rf, ok := ret.Get(0).(func(queries.EsQuery) []items.Item); ok {
+	var r0 *items.SearchResult
+	if rf, ok := ret.Get(0).(func(queries.EsQuery) *items.SearchResult); ok {
 		r0 = rf(q)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]items.Item)
+			r0 = ret.Get(0).(*items.SearchResult)
 		}
 	}
 
@@ -81,3 +186,69 @@ func (_m *ItemsPersistInterface) Search(q queries.EsQuery) ([]items.Item, rest_e
 
 	return r0, r1
 }
+
+// Suggest provides a mock function with given fields: _a0, _a1
+func (_m *ItemsPersistInterface) Suggest(_a0 string, _a1 int) (*items.SuggestResult, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *items.SuggestResult
+	if rf, ok := ret.Get(0).(func(string, int) *items.SuggestResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.SuggestResult)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(string, int) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: it
+func (_m *ItemsPersistInterface) Update(it *items.Item) rest_errors.RestErr {
+	ret := _m.Called(it)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(*items.Item) rest_errors.RestErr); ok {
+		r0 = rf(it)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// WithPendingEvents provides a mock function with given fields: _a0
+func (_m *ItemsPersistInterface) WithPendingEvents(_a0 int) ([]items.Item, rest_errors.RestErr) {
+	ret := _m.Called(_a0)
+
+	var r0 []items.Item
+	if rf, ok := ret.Get(0).(func(int) []items.Item); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]items.Item)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(int) rest_errors.RestErr); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
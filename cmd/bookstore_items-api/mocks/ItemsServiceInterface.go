@@ -41,6 +41,38 @@ func (_m *ItemsServiceInterface) Create(_a0 items.Item) (*items.Item, rest_error
 	return r0, r1
 }
 
+// Delete provides a mock function with given fields: _a0
+func (_m *ItemsServiceInterface) Delete(_a0 items.Item) rest_errors.RestErr {
+	ret := _m.Called(_a0)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(items.Item) rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: _a0, _a1
+func (_m *ItemsServiceInterface) Export(_a0 int64, _a1 items.RowWriter) rest_errors.RestErr {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(int64, items.RowWriter) rest_errors.RestErr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: _a0
 func (_m *ItemsServiceInterface) Get(_a0 string) (*items.Item, rest_errors.RestErr) {
 	ret := _m.Called(_a0)
@@ -66,12 +98,37 @@ func (_m *ItemsServiceInterface) Get(_a0 string) (*items.Item, rest_errors.RestE
 	return r0, r1
 }
 
-// Search provides a mock function with given fields: _a0
-func (_m *ItemsServiceInterface) Search(_a0 queries.EsQuery) ([]items.Item, rest_errors.RestErr) {
+// Import provides a mock function with given fields: _a0, _a1
+func (_m *ItemsServiceInterface) Import(_a0 int64, _a1 items.RowReader) (*items.ImportReport, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *items.ImportReport
+	if rf, ok := ret.Get(0).(func(int64, items.RowReader) *items.ImportReport); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.ImportReport)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(int64, items.RowReader) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// Related provides a mock function with given fields: _a0
+func (_m *ItemsServiceInterface) Related(_a0 string) ([]items.Item, rest_errors.RestErr) {
 	ret := _m.Called(_a0)
 
 	var r0 []items.Item
-	if rf, ok := ret.Get(0).(func(queries.EsQuery) []items.Item); ok {
+	if rf, ok := ret.Get(0).(func(string) []items.Item); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
@@ -80,7 +137,107 @@ func (_m *ItemsServiceInterface) Search(_a0 queries.EsQuery) ([]items.Item, rest
 	}
 
 	var r1 rest_errors.RestErr
-	if rf, ok := ret.Get(1).(func(queries.EsQuery) rest_errors.RestErr); ok {
+	if rf, ok := ret.Get(1).(func(string) rest_errors.RestErr); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: _a0, _a1
+func (_m *ItemsServiceInterface) Search(_a0 queries.EsQuery, _a1 int64) (*items.SearchResult, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *items.SearchResult
+	if rf, ok := ret.Get(0).(func(queries.EsQuery, int64) *items.SearchResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.SearchResult)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(queries.EsQuery, int64) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// SellerItems provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *ItemsServiceInterface) SellerItems(_a0 int64, _a1 string, _a2 queries.EsQuery, _a3 int64) (*items.SearchResult, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *items.SearchResult
+	if rf, ok := ret.Get(0).(func(int64, string, queries.EsQuery, int64) *items.SearchResult); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.SearchResult)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(int64, string, queries.EsQuery, int64) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// Suggest provides a mock function with given fields: _a0, _a1
+func (_m *ItemsServiceInterface) Suggest(_a0 string, _a1 int) (*items.SuggestResult, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *items.SuggestResult
+	if rf, ok := ret.Get(0).(func(string, int) *items.SuggestResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.SuggestResult)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(string, int) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
+
+// Transition provides a mock function with given fields: _a0
+func (_m *ItemsServiceInterface) Transition(_a0 items.Item) (*items.Item, rest_errors.RestErr) {
+	ret := _m.Called(_a0)
+
+	var r0 *items.Item
+	if rf, ok := ret.Get(0).(func(items.Item) *items.Item); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.Item)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(items.Item) rest_errors.RestErr); ok {
 		r1 = rf(_a0)
 	} else {
 		if ret.Get(1) != nil {
@@ -90,3 +247,28 @@ func (_m *ItemsServiceInterface) Search(_a0 queries.EsQuery) ([]items.Item, rest
 
 	return r0, r1
 }
+
+// Update provides a mock function with given fields: _a0, _a1
+func (_m *ItemsServiceInterface) Update(_a0 bool, _a1 items.Item) (*items.Item, rest_errors.RestErr) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *items.Item
+	if rf, ok := ret.Get(0).(func(bool, items.Item) *items.Item); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*items.Item)
+		}
+	}
+
+	var r1 rest_errors.RestErr
+	if rf, ok := ret.Get(1).(func(bool, items.Item) rest_errors.RestErr); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(rest_errors.RestErr)
+		}
+	}
+
+	return r0, r1
+}
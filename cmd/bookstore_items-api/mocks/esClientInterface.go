@@ -3,9 +3,11 @@
 package mocks
 
 import (
+	es "github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
 	elastic "github.com/olivere/elastic"
-
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // esClientInterface is an autogenerated mock type for the esClientInterface type
@@ -13,6 +15,146 @@ type esClientInterface struct {
 	mock.Mock
 }
 
+// AliasIndices provides a mock function with given fields: _a0
+func (_m *esClientInterface) AliasIndices(_a0 string) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BlockWrites provides a mock function with given fields: _a0, _a1
+func (_m *esClientInterface) BlockWrites(_a0 []string, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Bulk provides a mock function with given fields: _a0
+func (_m *esClientInterface) Bulk(_a0 ...elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *elastic.BulkResponse
+	if rf, ok := ret.Get(0).(func(...elastic.BulkableRequest) *elastic.BulkResponse); ok {
+		r0 = rf(_a0...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.BulkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(...elastic.BulkableRequest) error); ok {
+		r1 = rf(_a0...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClearScroll provides a mock function with given fields: _a0
+func (_m *esClientInterface) ClearScroll(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *esClientInterface) Create(_a0 string, _a1 string, _a2 string, _a3 interface{}) (*elastic.IndexResponse, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *elastic.IndexResponse
+	if rf, ok := ret.Get(0).(func(string, string, string, interface{}) *elastic.IndexResponse); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.IndexResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateIndex provides a mock function with given fields: _a0, _a1
+func (_m *esClientInterface) CreateIndex(_a0 string, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *esClientInterface) Delete(_a0 string, _a1 string, _a2 string, _a3 *es.DocVersion) (*elastic.DeleteResponse, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *elastic.DeleteResponse
+	if rf, ok := ret.Get(0).(func(string, string, string, *es.DocVersion) *elastic.DeleteResponse); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.DeleteResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, *es.DocVersion) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: _a0, _a1, _a2
 func (_m *esClientInterface) Get(_a0 string, _a1 string, _a2 string) (*elastic.GetResult, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -59,12 +201,102 @@ func (_m *esClientInterface) Index(_a0 string, _a1 string, _a2 interface{}) (*el
 	return r0, r1
 }
 
+// IndexExists provides a mock function with given fields: _a0
+func (_m *esClientInterface) IndexExists(_a0 string) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MappingMeta provides a mock function with given fields: _a0, _a1
+func (_m *esClientInterface) MappingMeta(_a0 string, _a1 string) (map[string]interface{}, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 map[string]interface{}
+	if rf, ok := ret.Get(0).(func(string, string) map[string]interface{}); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]interface{})
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reindex provides a mock function with given fields: _a0, _a1, _a2
+func (_m *esClientInterface) Reindex(_a0 string, _a1 string, _a2 *elastic.Script) (*elastic.BulkIndexByScrollResponse, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *elastic.BulkIndexByScrollResponse
+	if rf, ok := ret.Get(0).(func(string, string, *elastic.Script) *elastic.BulkIndexByScrollResponse); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.BulkIndexByScrollResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, *elastic.Script) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Scroll provides a mock function with given fields: _a0, _a1, _a2
+func (_m *esClientInterface) Scroll(_a0 string, _a1 *elastic.SearchSource, _a2 string) (*elastic.SearchResult, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *elastic.SearchResult
+	if rf, ok := ret.Get(0).(func(string, *elastic.SearchSource, string) *elastic.SearchResult); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.SearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *elastic.SearchSource, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Search provides a mock function with given fields: _a0, _a1
-func (_m *esClientInterface) Search(_a0 string, _a1 elastic.Query) (*elastic.SearchResult, error) {
+func (_m *esClientInterface) Search(_a0 string, _a1 *elastic.SearchSource) (*elastic.SearchResult, error) {
 	ret := _m.Called(_a0, _a1)
 
 	var r0 *elastic.SearchResult
-	if rf, ok := ret.Get(0).(func(string, elastic.Query) *elastic.SearchResult); ok {
+	if rf, ok := ret.Get(0).(func(string, *elastic.SearchSource) *elastic.SearchResult); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
@@ -73,7 +305,7 @@ func (_m *esClientInterface) Search(_a0 string, _a1 elastic.Query) (*elastic.Sea
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, elastic.Query) error); ok {
+	if rf, ok := ret.Get(1).(func(string, *elastic.SearchSource) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -86,3 +318,76 @@ func (_m *esClientInterface) Search(_a0 string, _a1 elastic.Query) (*elastic.Sea
 func (_m *esClientInterface) SetClient(_a0 *elastic.Client) {
 	_m.Called(_a0)
 }
+
+// Suggest provides a mock function with given fields: _a0, _a1, _a2
+func (_m *esClientInterface) Suggest(_a0 string, _a1 time.Duration, _a2 ...elastic.Suggester) (elastic.SearchSuggest, error) {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 elastic.SearchSuggest
+	if rf, ok := ret.Get(0).(func(string, time.Duration, ...elastic.Suggester) elastic.SearchSuggest); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(elastic.SearchSuggest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration, ...elastic.Suggester) error); ok {
+		r1 = rf(_a0, _a1, _a2...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *esClientInterface) Update(_a0 string, _a1 string, _a2 string, _a3 interface{}, _a4 *es.DocVersion) (*elastic.IndexResponse, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 *elastic.IndexResponse
+	if rf, ok := ret.Get(0).(func(string, string, string, interface{}, *es.DocVersion) *elastic.IndexResponse); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.IndexResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, interface{}, *es.DocVersion) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateAliases provides a mock function with given fields: _a0
+func (_m *esClientInterface) UpdateAliases(_a0 ...elastic.AliasAction) error {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...elastic.AliasAction) error); ok {
+		r0 = rf(_a0...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/publisher"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+type EventsServiceInterface interface {
+	Dispatch() (int, rest_errors.RestErr)
+}
+
+type eventsService struct {
+	outbox    events.OutboxPersistInterface
+	items     items.ItemsPersistInterface
+	publisher publisher.PublisherInterface
+	batchSize int
+	now       func() time.Time
+}
+
+// deleteGracePeriod is the time an item delete may take. The deleted event is appended
+// before the item is deleted, an item found past it has not been deleted
+const deleteGracePeriod = time.Minute
+
+// NewEventsService publishes the item events, batchSize items and events are read per dispatch
+func NewEventsService(outbox events.OutboxPersistInterface, itemsPersist items.ItemsPersistInterface,
+	publisher publisher.PublisherInterface, batchSize int) EventsServiceInterface {
+	return &eventsService{
+		outbox:    outbox,
+		items:     itemsPersist,
+		publisher: publisher,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// Dispatch moves the pending events of the items to the outbox, then publishes the outbox events
+// in order and removes the published ones. A failed delivery ends the dispatch, the event is published
+// again by the next one. An event published but not removed is published again as well, delivery is at-least-once
+func (s *eventsService) Dispatch() (int, rest_errors.RestErr) {
+	if err := s.collect(); err != nil {
+		return 0, err
+	}
+	pending, err := s.outbox.Pending(s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range pending {
+		if event.Type == events.TypeItemDeleted {
+			deleted, err := s.isDeleted(event)
+			if err != nil {
+				return published, err
+			}
+			if !deleted {
+				continue
+			}
+		}
+		payload, marshalErr := json.Marshal(event)
+		if marshalErr != nil {
+			return published, rest_errors.NewInternalServerError("publish event error", marshalErr)
+		}
+		if publishErr := s.publisher.Publish(event.Id, payload); publishErr != nil {
+			return published, rest_errors.NewInternalServerError("publish event error", publishErr)
+		}
+		if err := s.outbox.Remove(event); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// collect moves the events written with the items to the outbox. The events are cleared
+// by a versioned item write, an item changed meanwhile keeps them until the next dispatch
+// and appending them again overwrites them
+func (s *eventsService) collect() rest_errors.RestErr {
+	ls, err := s.items.WithPendingEvents(s.batchSize)
+	if err != nil {
+		return err
+	}
+	for idx := range ls {
+		it := &ls[idx]
+		pending, parseErr := events.PendingOf(*it)
+		if parseErr != nil {
+			return rest_errors.NewInternalServerError("read item events error", parseErr)
+		}
+		if err := appendEvents(s.outbox, pending...); err != nil {
+			return err
+		}
+		it.PendingEvents = nil
+		if err := s.items.Update(it); err != nil && !isStaleWrite(err) && err.Status() != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDeleted tells whether the deleted event can be published. The event of an item still found
+// is kept while the delete may be running and dropped after
+func (s *eventsService) isDeleted(event events.Event) (bool, rest_errors.RestErr) {
+	_, err := s.items.Get(items.Item{Id: event.ItemId})
+	if err == nil {
+		if s.now().Sub(event.OccurredAt) < deleteGracePeriod {
+			return false, nil
+		}
+		return false, s.outbox.Remove(event)
+	}
+	if err.Status() == http.StatusNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+// stageItemChange counts the change of the item and adds its events to the item pending events,
+// before is nil for new items. The events are stored by the item write, nothing is lost
+// when the write fails or the service stops after it
+func stageItemChange(now time.Time, before *items.Item, after *items.Item) rest_errors.RestErr {
+	after.Revision = 1
+	if before != nil {
+		after.Revision = before.Revision + 1
+	}
+	ls, err := events.ItemChanged(before, *after, now)
+	if err == nil {
+		err = events.Stage(after, ls...)
+	}
+	if err != nil {
+		return rest_errors.NewInternalServerError("record item event error", err)
+	}
+	return nil
+}
+
+func appendEvents(outbox events.OutboxPersistInterface, ls ...events.Event) rest_errors.RestErr {
+	if err := outbox.Append(ls...); err != nil {
+		logger.Error("item events are not recorded", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/publisher"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// failingPublisher accepts the first messages only
+type failingPublisher struct {
+	accepted int
+	ids      []string
+}
+
+func (p *failingPublisher) Publish(id string, payload []byte) error {
+	if len(p.ids) == p.accepted {
+		return errors.New("subscriber is down")
+	}
+	p.ids = append(p.ids, id)
+	return nil
+}
+
+type EventServiceSuite struct {
+	suite.Suite
+	outbox events.OutboxPersistInterface
+}
+
+func TestEventServiceSuite(t *testing.T) {
+	suite.Run(t, new(EventServiceSuite))
+}
+
+func (s *EventServiceSuite) SetupTest() {
+	s.outbox = events.NewMemoryOutbox()
+	s.outbox.Append(
+		events.Event{Id: "1", Type: events.TypeItemCreated, ItemId: "a", Version: 1, Item: &items.Item{Id: "a"}},
+		events.Event{Id: "2", Type: events.TypeItemUpdated, ItemId: "a", Version: 2, Item: &items.Item{Id: "a"}},
+		events.Event{Id: "3", Type: events.TypeItemDeleted, ItemId: "a", Version: 3},
+	)
+}
+
+func (s *EventServiceSuite) TestDispatch() {
+	memory := publisher.NewMemoryPublisher()
+	service := NewEventsService(s.outbox, items.NewMemoryPersister(), memory, 2)
+
+	published, err := service.Dispatch()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, published)
+
+	published, err = service.Dispatch()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 1, published)
+
+	var ids []string
+	for _, message := range memory.Messages() {
+		var e events.Event
+		assert.Nil(s.T(), json.Unmarshal(message, &e))
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(s.T(), []string{"1", "2", "3"}, ids)
+	pending, _ := s.outbox.Pending(10)
+	assert.Empty(s.T(), pending)
+}
+
+func (s *EventServiceSuite) TestDispatchStopsOnFailure() {
+	failing := &failingPublisher{accepted: 1}
+	service := NewEventsService(s.outbox, items.NewMemoryPersister(), failing, 10)
+
+	published, err := service.Dispatch()
+	assert.Equal(s.T(), 1, published)
+	assert.Equal(s.T(), http.StatusInternalServerError, err.Status())
+
+	// the failed event and the events after it stay in order
+	pending, _ := s.outbox.Pending(10)
+	assert.Len(s.T(), pending, 2)
+	assert.Equal(s.T(), "2", pending[0].Id)
+
+	failing.accepted = 3
+	published, err = service.Dispatch()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, published)
+	assert.Equal(s.T(), []string{"1", "2", "3"}, failing.ids)
+}
+
+// the events written with an item are moved to the outbox and cleared from the item
+func (s *EventServiceSuite) TestDispatchCollectsItemEvents() {
+	persist := items.NewMemoryPersister()
+	it := items.Item{Title: "The Hobbit"}
+	assert.Nil(s.T(), stageItemChange(time.Now(), nil, &it))
+	assert.Nil(s.T(), persist.Save(&it))
+	memory := publisher.NewMemoryPublisher()
+	service := NewEventsService(events.NewMemoryOutbox(), persist, memory, 10)
+
+	published, err := service.Dispatch()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 1, published)
+
+	stored, _ := persist.Get(items.Item{Id: it.Id})
+	assert.Empty(s.T(), stored.PendingEvents)
+	assert.EqualValues(s.T(), 1, stored.Revision)
+	withEvents, _ := persist.WithPendingEvents(10)
+	assert.Empty(s.T(), withEvents)
+}
+
+// the deleted event of an item still stored waits for the delete, it is dropped after
+func (s *EventServiceSuite) TestDispatchHoldsDeletedEventOfStoredItem() {
+	persist := items.NewMemoryPersister()
+	it := items.Item{Title: "The Hobbit"}
+	assert.Nil(s.T(), persist.Save(&it))
+	deleted, _ := events.ItemDeleted(it, time.Now())
+	outbox := events.NewMemoryOutbox()
+	outbox.Append(deleted)
+	memory := publisher.NewMemoryPublisher()
+	service := NewEventsService(outbox, persist, memory, 10).(*eventsService)
+
+	published, err := service.Dispatch()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 0, published)
+	pending, _ := outbox.Pending(10)
+	assert.Len(s.T(), pending, 1)
+
+	service.now = func() time.Time { return time.Now().Add(deleteGracePeriod) }
+	published, _ = service.Dispatch()
+	assert.Equal(s.T(), 0, published)
+	pending, _ = outbox.Pending(10)
+	assert.Empty(s.T(), pending)
+	assert.Empty(s.T(), memory.Messages())
+}
@@ -1,32 +1,174 @@
 package services
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/blob"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
 )
 
 type ItemsServiceInterface interface {
 	Create(items.Item) (*items.Item, rest_errors.RestErr)
 	Get(string) (*items.Item, rest_errors.RestErr)
-	Search(queries.EsQuery) ([]items.Item, rest_errors.RestErr)
+	Search(queries.EsQuery, int64) (*items.SearchResult, rest_errors.RestErr)
+	SellerItems(int64, string, queries.EsQuery, int64) (*items.SearchResult, rest_errors.RestErr)
+	Update(bool, items.Item) (*items.Item, rest_errors.RestErr)
+	Transition(items.Item) (*items.Item, rest_errors.RestErr)
+	Import(int64, items.RowReader) (*items.ImportReport, rest_errors.RestErr)
+	Export(int64, items.RowWriter) rest_errors.RestErr
+	Suggest(string, int) (*items.SuggestResult, rest_errors.RestErr)
+	Related(string) ([]items.Item, rest_errors.RestErr)
+	Delete(items.Item) rest_errors.RestErr
 }
 
+const (
+	// items saved with one bulk request on import
+	importBatchSize = 500
+
+	defaultSuggestSize = 5
+	maxSuggestSize     = 20
+	maxSuggestPrefix   = 50
+)
+
 type itemsService struct {
-	persist items.ItemsPersistInterface
+	persist     items.ItemsPersistInterface
+	outbox      events.OutboxPersistInterface
+	store       blob.StoreInterface
+	boosts      queries.TextBoosts
+	relatedSize int
+	now         func() time.Time
 }
 
-func NewItemsService(persist items.ItemsPersistInterface) ItemsServiceInterface {
+// NewItemsService creates the service, item changes are recorded in the item pending events,
+// deletes in the outbox. The pictures of deleted items are removed from the store.
+// relatedSize is the number of related items returned
+func NewItemsService(persist items.ItemsPersistInterface, outbox events.OutboxPersistInterface,
+	store blob.StoreInterface, boosts queries.TextBoosts, relatedSize int) ItemsServiceInterface {
 	return &itemsService{
-		persist: persist,
+		persist:     persist,
+		outbox:      outbox,
+		store:       store,
+		boosts:      boosts,
+		relatedSize: relatedSize,
+		now:         time.Now,
 	}
 }
 
-func (s *itemsService) Create(items items.Item) (*items.Item, rest_errors.RestErr) {
-	if err := s.persist.Save(&items); err != nil {
+func (s *itemsService) Create(it items.Item) (*items.Item, rest_errors.RestErr) {
+	if err := prepareNew(&it); err != nil {
+		return nil, err
+	}
+	id, err := items.NewItemId()
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("save item error", err)
+	}
+	it.Id = id
+	if err := stageItemChange(s.now(), nil, &it); err != nil {
+		return nil, err
+	}
+	if err := s.claimIsbn(it); err != nil {
 		return nil, err
 	}
-	return &items, nil
+	if err := s.persist.Save(&it); err != nil {
+		s.releaseIsbn(it, it.Book.Isbn)
+		return nil, err
+	}
+	return &it, nil
+}
+
+// prepareNew checks a new item and sets its initial status.
+// Pictures are added by uploads only, the ones sent with the item are dropped
+func prepareNew(it *items.Item) rest_errors.RestErr {
+	it.Pictures = nil
+	if it.Status == "" {
+		it.Status = items.StatusDraft
+	}
+	if err := it.Validate(); err != nil {
+		return err
+	}
+	if it.Status != items.StatusDraft && it.Status != items.StatusActive {
+		return rest_errors.NewBadRequestError("new item must be draft or active")
+	}
+	if err := normalizePrice(&it.Price); err != nil {
+		return err
+	}
+	if err := it.Description.Normalize(); err != nil {
+		return err
+	}
+	if err := it.Book.Normalize(); err != nil {
+		return err
+	}
+	it.SyncStockStatus()
+	return nil
+}
+
+// normalizePrice checks the currency of a price, an item may have no price yet
+func normalizePrice(price *money.Money) rest_errors.RestErr {
+	if price.IsZero() {
+		return nil
+	}
+	if price.Amount < 0 {
+		return rest_errors.NewBadRequestError("price must not be negative")
+	}
+	if err := price.Normalize(); err != nil {
+		return rest_errors.NewBadRequestError(err.Error())
+	}
+	return nil
+}
+
+// claimIsbn rejects a second listing of the same book by the seller before the item is written,
+// archived listings do not count. The claim is atomic, the search finds listings older than the claims
+func (s *itemsService) claimIsbn(it items.Item) rest_errors.RestErr {
+	if it.Book.Isbn == "" || it.Status == items.StatusArchived {
+		return nil
+	}
+	if err := s.checkDuplicateIsbn(it); err != nil {
+		return err
+	}
+	return s.persist.ClaimIsbn(it)
+}
+
+// releaseIsbn frees the isbn of the item after a write, a claim left behind goes stale
+func (s *itemsService) releaseIsbn(it items.Item, isbn string) {
+	if isbn == "" {
+		return
+	}
+	if err := s.persist.ReleaseIsbn(it, isbn); err != nil {
+		logger.Error(fmt.Sprintf("isbn %s claim of item %s not released", isbn, it.Id), err)
+	}
+}
+
+func (s *itemsService) checkDuplicateIsbn(it items.Item) rest_errors.RestErr {
+
+	q := queries.EsQuery{
+		Equals: []queries.FieldValue{
+			{Field: "seller", Value: it.Seller},
+			{Field: "book.isbn", Value: it.Book.Isbn},
+		},
+		NotEquals: []queries.FieldValue{{Field: "status", Value: items.StatusArchived}},
+		Size:      2,
+	}
+	result, err := s.persist.Search(q)
+	if err != nil {
+		return err
+	}
+	for _, found := range result.Items {
+		if found.Id != it.Id {
+			return rest_errors.NewConflictError(fmt.Sprintf("isbn %s is already listed by the seller", it.Book.Isbn))
+		}
+	}
+	return nil
 }
 
 func (s *itemsService) Get(Id string) (*items.Item, rest_errors.RestErr) {
@@ -38,6 +180,392 @@ func (s *itemsService) Get(Id string) (*items.Item, rest_errors.RestErr) {
 	return res, nil
 }
 
-func (s *itemsService) Search(q queries.EsQuery) ([]items.Item, rest_errors.RestErr) {
+// Search returns active items only, a seller also finds own items in any status
+func (s *itemsService) Search(q queries.EsQuery, callerId int64) (*items.SearchResult, rest_errors.RestErr) {
+	if err := q.Validate(items.QueryFields); err != nil {
+		return nil, err
+	}
+	q.Visibility = &queries.Visibility{Status: items.StatusActive, Owner: callerId}
+	q.Boosts = &s.boosts
+	for _, ls := range [][]queries.FieldValue{q.Equals, q.NotEquals, q.AnyOf} {
+		normalizeIsbnValues(ls)
+	}
 	return s.persist.Search(q)
 }
+
+// SellerItems lists the seller items in the status when set, q holds paging and sorting only.
+// Other callers see active items, the seller sees own items in any status
+func (s *itemsService) SellerItems(seller int64, status string, q queries.EsQuery, callerId int64) (*items.SearchResult, rest_errors.RestErr) {
+	if seller <= 0 {
+		return nil, rest_errors.NewBadRequestError("invalid seller id")
+	}
+	if status != "" && !items.IsValidStatus(status) {
+		return nil, rest_errors.NewBadRequestError(fmt.Sprintf("unknown status %s", status))
+	}
+
+	listing := queries.EsQuery{
+		Equals: []queries.FieldValue{{Field: "seller", Value: seller}},
+		Page:   q.Page,
+		Size:   q.Size,
+		Sort:   q.Sort,
+	}
+	if status != "" {
+		listing.Equals = append(listing.Equals, queries.FieldValue{Field: "status", Value: status})
+	}
+	return s.Search(listing, callerId)
+}
+
+// Suggest completes the prefix with titles and authors of active items, size 0 is the default size
+func (s *itemsService) Suggest(prefix string, size int) (*items.SuggestResult, rest_errors.RestErr) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, rest_errors.NewBadRequestError("prefix is required")
+	}
+	if utf8.RuneCountInString(prefix) > maxSuggestPrefix {
+		return nil, rest_errors.NewBadRequestError(fmt.Sprintf("prefix is longer than %d characters", maxSuggestPrefix))
+	}
+	if size == 0 {
+		size = defaultSuggestSize
+	}
+	if size < 0 || size > maxSuggestSize {
+		return nil, rest_errors.NewBadRequestError(fmt.Sprintf("size must be between 1 and %d", maxSuggestSize))
+	}
+	return s.persist.Suggest(prefix, size)
+}
+
+// Related returns active items of other sellers similar to the item
+func (s *itemsService) Related(id string) ([]items.Item, rest_errors.RestErr) {
+	it, err := s.persist.Get(items.Item{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return s.persist.Related(*it, s.relatedSize)
+}
+
+// normalizeIsbnValues lets clients search by ISBN-10 or a hyphenated isbn
+func normalizeIsbnValues(ls []queries.FieldValue) {
+	for idx, fv := range ls {
+		if value, ok := fv.Value.(string); ok && fv.Field == "book.isbn" {
+			if isbn, err := items.NormalizeIsbn(value); err == nil {
+				ls[idx].Value = isbn
+			}
+		}
+	}
+}
+
+// Update changes the item fields, pictures are changed through the pictures service and the sold
+// quantity by the reservations. A partial update sets the available quantity when it.Sent has it.
+// When it.Version is set the update applies to that revision only
+func (s *itemsService) Update(isPartial bool, it items.Item) (*items.Item, rest_errors.RestErr) {
+	current, err := s.persist.Get(items.Item{Id: it.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Seller != it.Seller {
+		return nil, rest_errors.NewForbiddenError("item belongs to another seller")
+	}
+	expected := it.Version
+	if err := checkIfMatch(expected, current.Version); err != nil {
+		return nil, err
+	}
+	before := *current
+
+	status, isbn := it.Status, current.Book.Isbn
+	if isPartial {
+		if it.Title != "" {
+			current.Title = it.Title
+		}
+		if it.Description.PlainText != "" {
+			current.Description.PlainText = it.Description.PlainText
+		}
+		if it.Description.Html != "" {
+			current.Description.Html = it.Description.Html
+			if it.Description.PlainText == "" {
+				// derived again from the new html
+				current.Description.PlainText = ""
+			}
+		}
+		if it.Video != "" {
+			current.Video = it.Video
+		}
+		if !it.Price.IsZero() {
+			current.Price = it.Price
+		}
+		if it.Sent["available_quantity"] {
+			current.AvailableQuantity = it.AvailableQuantity
+		}
+		mergeBook(&current.Book, it.Book)
+	} else {
+		it.Id = current.Id
+		it.Seller = current.Seller
+		it.Version = current.Version
+		it.Status = current.Status
+		it.Pictures = current.Pictures
+		// sales are counted by the reservations, not set by the seller
+		it.SoldQuantity = current.SoldQuantity
+		it.Revision, it.PendingEvents = current.Revision, current.PendingEvents
+		*current = it
+	}
+
+	if err := current.Validate(); err != nil {
+		return nil, err
+	}
+	if status != "" && status != current.Status {
+		if err := current.ValidateTransition(status); err != nil {
+			return nil, err
+		}
+		current.Status = status
+	}
+	current.SyncStockStatus()
+
+	if err := normalizePrice(&current.Price); err != nil {
+		return nil, err
+	}
+	if err := current.Description.Normalize(); err != nil {
+		return nil, err
+	}
+	if err := current.Book.Normalize(); err != nil {
+		return nil, err
+	}
+	if err := stageItemChange(s.now(), &before, current); err != nil {
+		return nil, err
+	}
+	claimed := current.Book.Isbn != isbn
+	if claimed {
+		if err := s.claimIsbn(*current); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.persist.Update(current); err != nil {
+		if claimed {
+			s.releaseIsbn(*current, current.Book.Isbn)
+		}
+		return nil, writeError(err, expected)
+	}
+	if claimed || current.Status == items.StatusArchived {
+		s.releaseIsbn(before, isbn)
+	}
+	return current, nil
+}
+
+// mergeBook applies the non zero metadata of a partial update
+func mergeBook(current *items.Book, update items.Book) {
+	if update.Isbn != "" {
+		current.Isbn = update.Isbn
+	}
+	if update.Authors != nil {
+		current.Authors = update.Authors
+	}
+	if update.Publisher != "" {
+		current.Publisher = update.Publisher
+	}
+	if update.Edition != "" {
+		current.Edition = update.Edition
+	}
+	if update.Language != "" {
+		current.Language = update.Language
+	}
+	if update.PageCount != 0 {
+		current.PageCount = update.PageCount
+	}
+	if update.PublicationDate != "" {
+		current.PublicationDate = update.PublicationDate
+	}
+}
+
+// Transition moves the item into the requested status, conditional on it.Version when set
+func (s *itemsService) Transition(it items.Item) (*items.Item, rest_errors.RestErr) {
+	current, err := s.persist.Get(items.Item{Id: it.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Seller != it.Seller {
+		return nil, rest_errors.NewForbiddenError("item belongs to another seller")
+	}
+	if err := checkIfMatch(it.Version, current.Version); err != nil {
+		return nil, err
+	}
+	if err := current.ValidateTransition(it.Status); err != nil {
+		return nil, err
+	}
+
+	before := *current
+	current.Status = it.Status
+	if err := stageItemChange(s.now(), &before, current); err != nil {
+		return nil, err
+	}
+	if err := s.persist.Update(current); err != nil {
+		return nil, writeError(err, it.Version)
+	}
+	if current.Status == items.StatusArchived {
+		s.releaseIsbn(before, before.Book.Isbn)
+	}
+	return current, nil
+}
+
+func (s *itemsService) Delete(it items.Item) rest_errors.RestErr {
+	current, err := s.persist.Get(items.Item{Id: it.Id})
+	if err != nil {
+		return err
+	}
+
+	if current.Seller != it.Seller {
+		return rest_errors.NewForbiddenError("item belongs to another seller")
+	}
+	if err := checkIfMatch(it.Version, current.Version); err != nil {
+		return err
+	}
+	// the events of the item go to the outbox before the item is gone,
+	// the deleted event is published once the item is not found
+	ls, parseErr := events.PendingOf(*current)
+	if parseErr != nil {
+		return rest_errors.NewInternalServerError("read item events error", parseErr)
+	}
+	deleted, eventErr := events.ItemDeleted(*current, s.now())
+	if eventErr != nil {
+		return rest_errors.NewInternalServerError("record item event error", eventErr)
+	}
+	if err := appendEvents(s.outbox, append(ls, deleted)...); err != nil {
+		return err
+	}
+
+	if err := s.persist.Delete(current); err != nil {
+		if removeErr := s.outbox.Remove(deleted); removeErr != nil {
+			logger.Error(fmt.Sprintf("deleted event of item %s not removed", current.Id), removeErr)
+		}
+		return writeError(err, it.Version)
+	}
+	s.releaseIsbn(*current, current.Book.Isbn)
+	for _, p := range current.Pictures {
+		deleteBlobs(s.store, current.Id, p.Id)
+	}
+	return nil
+}
+
+// checkIfMatch fails when the caller expects a revision of the item other than the current one
+func checkIfMatch(expected, current *es.DocVersion) rest_errors.RestErr {
+	if expected != nil && (current == nil || *expected != *current) {
+		return rest_errors.NewPreconditionFailedError("item has been modified since it was read")
+	}
+	return nil
+}
+
+// writeError reports a write lost against a concurrent one as a conflict
+// to callers which did not ask for a revision
+func writeError(err rest_errors.RestErr, expected *es.DocVersion) rest_errors.RestErr {
+	if expected == nil && isStaleWrite(err) {
+		return errConcurrentItemWrites()
+	}
+	return err
+}
+
+// isStaleWrite tells whether a versioned item write failed on a revision changed meanwhile
+func isStaleWrite(err rest_errors.RestErr) bool {
+	return err.Status() == http.StatusPreconditionFailed
+}
+
+func errConcurrentItemWrites() rest_errors.RestErr {
+	return rest_errors.NewConflictError("item has been modified concurrently")
+}
+
+// Import creates the seller items read from the rows, valid rows are saved in batches
+func (s *itemsService) Import(seller int64, reader items.RowReader) (*items.ImportReport, rest_errors.RestErr) {
+	report := items.ImportReport{Rows: make([]items.ImportRow, 0)}
+
+	batch := make([]items.Item, 0, importBatchSize)
+	// report row of every batch item
+	rows := make([]int, 0, importBatchSize)
+	// isbns of the import rows, they are not searchable before the batch is saved
+	imported := make(map[string]bool)
+
+	flush := func() {
+		for idx, err := range s.persist.SaveAll(batch) {
+			row := &report.Rows[rows[idx]]
+			if err != nil {
+				s.releaseIsbn(batch[idx], batch[idx].Book.Isbn)
+				row.Error = err
+				report.Failed++
+				continue
+			}
+			row.Id = batch[idx].Id
+			report.Created++
+		}
+		batch, rows = batch[:0], rows[:0]
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.Error = rest_errors.NewBadRequestError(fmt.Sprintf("import stopped after %d rows : %s", report.Total, err.Error()))
+			break
+		}
+
+		report.Total++
+		report.Rows = append(report.Rows, items.ImportRow{Row: row.Number})
+
+		it := row.Item
+		if row.Err == nil {
+			it.Seller, it.SoldQuantity, it.Version = seller, 0, nil
+			row.Err = prepareNew(&it)
+		}
+		if row.Err == nil {
+			var err error
+			if it.Id, err = items.NewItemId(); err != nil {
+				row.Err = rest_errors.NewInternalServerError("save item error", err)
+			}
+		}
+		if row.Err == nil {
+			row.Err = stageItemChange(s.now(), nil, &it)
+		}
+		if row.Err == nil && it.Book.Isbn != "" {
+			if imported[it.Book.Isbn] {
+				row.Err = rest_errors.NewConflictError(fmt.Sprintf("isbn %s is listed twice in the import", it.Book.Isbn))
+			} else if row.Err = s.claimIsbn(it); row.Err == nil {
+				imported[it.Book.Isbn] = true
+			}
+		}
+		if row.Err != nil {
+			report.Rows[len(report.Rows)-1].Error = row.Err
+			report.Failed++
+			continue
+		}
+
+		batch = append(batch, it)
+		rows = append(rows, len(report.Rows)-1)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+	return &report, nil
+}
+
+// Export writes all items of the seller, the output is flushed page by page
+func (s *itemsService) Export(seller int64, writer items.RowWriter) rest_errors.RestErr {
+	flush := func() rest_errors.RestErr {
+		if err := writer.Flush(); err != nil {
+			return rest_errors.NewInternalServerError("export write error", err)
+		}
+		return nil
+	}
+
+	err := s.persist.Export(seller, func(ls []items.Item) rest_errors.RestErr {
+		for _, it := range ls {
+			if err := writer.Write(it); err != nil {
+				return rest_errors.NewInternalServerError("export write error", err)
+			}
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	// the csv header of an empty export
+	return flush()
+}
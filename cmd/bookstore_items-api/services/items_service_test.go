@@ -1,10 +1,19 @@
 package services
 
 import (
+	"bytes"
 	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/blob"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/es"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/money"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/pictures"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/queries"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
@@ -16,11 +25,14 @@ import (
 //go:generate mockery --name=ItemsServiceInterface --output ../mocks
 //go:generate mockery  --name=ItemsPersistInterface --dir=../domain/items  --output ../mocks
 //go:generate mockery  --name=esClientInterface --dir=../client/es  --output ../mocks
+//go:generate mockery  --name=OutboxPersistInterface --dir=../domain/events  --output ../mocks
 
 type ItemServiceSuite struct {
 	suite.Suite
 	itemsService ItemsServiceInterface
 	daoItemsMock mocks.ItemsPersistInterface
+	outbox       events.OutboxPersistInterface
+	store        blob.StoreInterface
 }
 
 func TestItemServiceSuite(t *testing.T) {
@@ -29,11 +41,15 @@ func TestItemServiceSuite(t *testing.T) {
 
 func (s *ItemServiceSuite) SetupTest() {
 	s.daoItemsMock = mocks.ItemsPersistInterface{}
-	s.itemsService = NewItemsService(&s.daoItemsMock)
+	s.outbox = events.NewMemoryOutbox()
+	var err error
+	s.store, err = blob.NewLocalStore(s.T().TempDir())
+	s.Require().Nil(err)
+	s.itemsService = NewItemsService(&s.daoItemsMock, s.outbox, s.store, queries.DefaultTextBoosts, 3)
 }
 
 func (s *ItemServiceSuite) TestCreateOk() {
-	var item = items.Item{}
+	var item = items.Item{Title: "The Hobbit"}
 	s.daoItemsMock.On("Save", mock.IsType(&item)).Return(func(item *items.Item) rest_errors.RestErr {
 		item.Id = "assigned"
 		return nil
@@ -46,10 +62,42 @@ func (s *ItemServiceSuite) TestCreateOk() {
 	assert.Nil(s.T(), err)
 	assert.Equal(s.T(), "assigned", result.Id)
 	assert.True(s.T(), len(result.Id) > 0)
+	assert.Equal(s.T(), items.StatusDraft, result.Status)
+}
+
+func (s *ItemServiceSuite) TestCreateDropsPictures() {
+	s.daoItemsMock.On("Save", mock.IsType(&items.Item{})).Return(nil)
+
+	result, err := s.itemsService.Create(items.Item{Title: "The Hobbit",
+		Pictures: []items.Picture{{Id: 1, Url: "http://elsewhere/1.jpg"}}})
+
+	assert.Nil(s.T(), err)
+	assert.Empty(s.T(), result.Pictures)
+}
+
+func (s *ItemServiceSuite) TestCreateFailedStatus() {
+	result, err := s.itemsService.Create(items.Item{Title: "The Hobbit", Status: items.StatusArchived})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+}
+
+func (s *ItemServiceSuite) TestCreateFailedValidation() {
+	result, err := s.itemsService.Create(items.Item{AvailableQuantity: -1, Video: "ftp://example.com/v"})
+
+	s.daoItemsMock.AssertNotCalled(s.T(), "Save", mock.Anything)
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+	assert.Equal(s.T(), []interface{}{
+		items.Violation{Field: "title", Rule: items.RuleRequired, Message: "title is required"},
+		items.Violation{Field: "available_quantity", Rule: items.RuleMin, Message: "available_quantity must not be less than 0"},
+		items.Violation{Field: "video", Rule: items.RuleUrl, Message: "video must be an http or https url"},
+	}, err.Causes())
 }
 
 func (s *ItemServiceSuite) TestCreateFailedPersist() {
-	var item = items.Item{}
+	var item = items.Item{Title: "The Hobbit"}
 	s.daoItemsMock.On("Save", mock.IsType(&item)).Return(func(item *items.Item) rest_errors.RestErr {
 		return rest_errors.NewInternalServerError("failed", errors.New("save"))
 	})
@@ -88,11 +136,634 @@ func (s *ItemServiceSuite) TestGetNotFound() {
 func (s *ItemServiceSuite) TestSearchOk() {
 	q := queries.EsQuery{}
 
-	s.daoItemsMock.On("Search", mock.IsType(q)).Return([]items.Item{}, nil)
+	s.daoItemsMock.On("Search", mock.MatchedBy(func(q queries.EsQuery) bool {
+		return *q.Visibility == queries.Visibility{Status: items.StatusActive, Owner: 7}
+	})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
 
-	result, err := s.itemsService.Search(q)
+	result, err := s.itemsService.Search(q, 7)
 
 	s.daoItemsMock.AssertExpectations(s.T())
 	assert.Nil(s.T(), err)
 	assert.NotNil(s.T(), result)
 }
+
+func (s *ItemServiceSuite) TestSearchFailedValidation() {
+	q := queries.EsQuery{Sort: []queries.SortField{{Field: "seller"}}}
+
+	result, err := s.itemsService.Search(q, 0)
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+}
+
+func (s *ItemServiceSuite) TestSellerItems() {
+	sort := []queries.SortField{{Field: "price", Order: "desc"}}
+	s.daoItemsMock.On("Search", mock.MatchedBy(func(q queries.EsQuery) bool {
+		return assert.ObjectsAreEqual([]queries.FieldValue{
+			{Field: "seller", Value: int64(7)},
+			{Field: "status", Value: items.StatusPaused},
+		}, q.Equals) &&
+			*q.Visibility == queries.Visibility{Status: items.StatusActive, Owner: 9} &&
+			q.Page == 2 && q.Size == 20 && assert.ObjectsAreEqual(sort, q.Sort)
+	})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+
+	result, err := s.itemsService.SellerItems(7, items.StatusPaused, queries.EsQuery{Page: 2, Size: 20, Sort: sort}, 9)
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.NotNil(s.T(), result)
+}
+
+func (s *ItemServiceSuite) TestSellerItemsVisibility() {
+	service := NewItemsService(items.NewMemoryPersister(), events.NewMemoryOutbox(), s.store, queries.DefaultTextBoosts, 3)
+	for _, it := range []items.Item{
+		{Seller: 7, Title: "active", Status: items.StatusActive, AvailableQuantity: 1},
+		{Seller: 7, Title: "draft"},
+		{Seller: 8, Title: "other seller", Status: items.StatusActive, AvailableQuantity: 1},
+	} {
+		_, err := service.Create(it)
+		assert.Nil(s.T(), err)
+	}
+
+	for _, tc := range []struct {
+		callerId int64
+		status   string
+		expected []string
+	}{
+		{0, "", []string{"active"}},
+		{8, "", []string{"active"}},
+		{7, "", []string{"active", "draft"}},
+		{7, items.StatusDraft, []string{"draft"}},
+		{8, items.StatusDraft, []string{}},
+	} {
+		result, err := service.SellerItems(7, tc.status, queries.EsQuery{Sort: []queries.SortField{{Field: "title"}}}, tc.callerId)
+		assert.Nil(s.T(), err)
+		titles := []string{}
+		for _, it := range result.Items {
+			titles = append(titles, it.Title)
+		}
+		assert.Equal(s.T(), tc.expected, titles, "caller %d status %s", tc.callerId, tc.status)
+	}
+}
+
+func (s *ItemServiceSuite) TestSellerItemsFailedValidation() {
+	for _, tc := range []struct {
+		seller int64
+		status string
+		q      queries.EsQuery
+	}{
+		{0, "", queries.EsQuery{}},
+		{7, "unknown", queries.EsQuery{}},
+		{7, "", queries.EsQuery{Size: queries.MaxPageSize + 1}},
+		{7, "", queries.EsQuery{Sort: []queries.SortField{{Field: "seller"}}}},
+	} {
+		result, err := s.itemsService.SellerItems(tc.seller, tc.status, tc.q, 7)
+		assert.Nil(s.T(), result)
+		assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+	}
+	s.daoItemsMock.AssertNotCalled(s.T(), "Search", mock.Anything)
+}
+
+func (s *ItemServiceSuite) TestSuggestOk() {
+	s.daoItemsMock.On("Suggest", "hob", defaultSuggestSize).Return(&items.SuggestResult{Prefix: "hob"}, nil)
+
+	result, err := s.itemsService.Suggest(" hob ", 0)
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "hob", result.Prefix)
+}
+
+func (s *ItemServiceSuite) TestSuggestFailedValidation() {
+	for _, tc := range []struct {
+		prefix string
+		size   int
+	}{{"", 5}, {"   ", 5}, {strings.Repeat("a", maxSuggestPrefix+1), 5}, {"hob", -1}, {"hob", maxSuggestSize + 1}} {
+		result, err := s.itemsService.Suggest(tc.prefix, tc.size)
+
+		assert.Nil(s.T(), result)
+		assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+	}
+	s.daoItemsMock.AssertExpectations(s.T())
+}
+
+func (s *ItemServiceSuite) TestRelatedOk() {
+	item := items.Item{Id: "1", Seller: 7, Title: "The Hobbit"}
+	related := []items.Item{{Id: "2", Seller: 8, Title: "The Hobbit, illustrated"}}
+
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(&item, nil)
+	s.daoItemsMock.On("Related", item, 3).Return(related, nil)
+
+	result, err := s.itemsService.Related("1")
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), related, result)
+}
+
+func (s *ItemServiceSuite) TestRelatedNotFound() {
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(nil, rest_errors.NewNotFoundError("item not found"))
+
+	result, err := s.itemsService.Related("1")
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusNotFound, err.Status())
+}
+
+func (s *ItemServiceSuite) TestUpdatePartialOk() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Title: "old title", Price: money.New(1000, "USD")}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+	result, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Title: "new title"})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "new title", result.Title)
+	assert.Equal(s.T(), money.New(1000, "USD"), result.Price)
+}
+
+func (s *ItemServiceSuite) TestUpdateFullOk() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Title: "old title", Price: money.New(1000, "USD")}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+	result, err := s.itemsService.Update(false, items.Item{Id: objId, Seller: 1, Title: "new title"})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "new title", result.Title)
+	assert.True(s.T(), result.Price.IsZero())
+}
+
+func (s *ItemServiceSuite) TestUpdateQuantities() {
+	for _, isPartial := range []bool{true, false} {
+		stored := &items.Item{Id: "111", Seller: 1, Title: "The Hobbit", Status: items.StatusActive,
+			AvailableQuantity: 3, SoldQuantity: 5}
+		daoItemsMock := mocks.ItemsPersistInterface{}
+		daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+		daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+		service := NewItemsService(&daoItemsMock, events.NewMemoryOutbox(), s.store, queries.DefaultTextBoosts, 3)
+		result, err := service.Update(isPartial, items.Item{Id: "111", Seller: 1, Title: "The Hobbit",
+			Status: items.StatusActive, SoldQuantity: 50, Sent: map[string]bool{"available_quantity": true}})
+
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), 0, result.AvailableQuantity)
+		assert.Equal(s.T(), 5, result.SoldQuantity)
+	}
+
+	stored := &items.Item{Id: "111", Seller: 1, Title: "The Hobbit", AvailableQuantity: 3}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+	result, err := s.itemsService.Update(true, items.Item{Id: "111", Seller: 1, Title: "new title"})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 3, result.AvailableQuantity)
+}
+
+func (s *ItemServiceSuite) TestUpdateKeepsPictures() {
+	pictures := []items.Picture{{Id: 1, Url: "http://pictures/items/111/pictures/1"}}
+	for _, isPartial := range []bool{true, false} {
+		stored := &items.Item{Id: "111", Seller: 1, Title: "The Hobbit", Pictures: pictures}
+		daoItemsMock := mocks.ItemsPersistInterface{}
+		daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+		daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+		service := NewItemsService(&daoItemsMock, events.NewMemoryOutbox(), s.store, queries.DefaultTextBoosts, 3)
+		result, err := service.Update(isPartial, items.Item{Id: "111", Seller: 1, Title: "The Hobbit", Pictures: []items.Picture{{Id: 2}}})
+
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), pictures, result.Pictures)
+	}
+}
+
+func (s *ItemServiceSuite) TestUpdateSanitizesDescription() {
+	stored := &items.Item{Id: "111", Seller: 1, Title: "The Hobbit", Description: items.Description{PlainText: "old", Html: "<p>old</p>"}}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+	update := items.Item{Id: "111", Seller: 1, Description: items.Description{Html: `<p onclick="x()">new <script>x()</script>text</p>`}}
+	result, err := s.itemsService.Update(true, update)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), items.Description{PlainText: "new text", Html: "<p>new text</p>"}, result.Description)
+}
+
+func (s *ItemServiceSuite) TestUpdateForbidden() {
+	const objId = "111"
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(&items.Item{Id: objId, Seller: 1}, nil)
+
+	result, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 2})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusForbidden, err.Status())
+}
+
+func (s *ItemServiceSuite) TestUpdateNotFound() {
+	const objId = "111"
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(nil, rest_errors.NewNotFoundError(objId))
+
+	result, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusNotFound, err.Status())
+}
+
+func (s *ItemServiceSuite) TestDeleteOk() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Delete", stored).Return(nil)
+
+	err := s.itemsService.Delete(items.Item{Id: objId, Seller: 1})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+}
+
+func (s *ItemServiceSuite) TestDeleteRemovesPictures() {
+	stored := &items.Item{Id: "111", Seller: 1, Pictures: []items.Picture{{Id: 2}}}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Delete", stored).Return(nil)
+	for _, key := range []string{pictures.OriginalKey("111", 2), pictures.ThumbnailKey("111", 2)} {
+		s.Require().Nil(s.store.Put(key, strings.NewReader("picture")))
+	}
+
+	err := s.itemsService.Delete(items.Item{Id: "111", Seller: 1})
+
+	assert.Nil(s.T(), err)
+	for _, key := range []string{pictures.OriginalKey("111", 2), pictures.ThumbnailKey("111", 2)} {
+		_, blobErr := s.store.Get(key)
+		assert.Equal(s.T(), blob.ErrNotFound, blobErr)
+	}
+}
+
+func (s *ItemServiceSuite) TestDeleteForbidden() {
+	const objId = "111"
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(&items.Item{Id: objId, Seller: 1}, nil)
+
+	err := s.itemsService.Delete(items.Item{Id: objId, Seller: 2})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusForbidden, err.Status())
+}
+
+func (s *ItemServiceSuite) TestDeleteStaleVersion() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+
+	err := s.itemsService.Delete(items.Item{Id: objId, Seller: 1, Version: &es.DocVersion{SeqNo: 4, PrimaryTerm: 1}})
+
+	s.daoItemsMock.AssertNotCalled(s.T(), "Delete", mock.Anything)
+	assert.Equal(s.T(), http.StatusPreconditionFailed, err.Status())
+}
+
+func (s *ItemServiceSuite) TestUpdateIfMatch() {
+	const objId = "111"
+	version := &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}
+	stored := &items.Item{Id: objId, Seller: 1, Title: "old title", Version: version}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(func(items.Item) *items.Item {
+		current := *stored
+		return &current
+	}, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil).Once()
+
+	_, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Title: "new", Version: &es.DocVersion{SeqNo: 4, PrimaryTerm: 1}})
+	assert.Equal(s.T(), http.StatusPreconditionFailed, err.Status())
+	s.daoItemsMock.AssertNotCalled(s.T(), "Update", mock.Anything)
+
+	result, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Title: "new", Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "new", result.Title)
+	assert.Equal(s.T(), version, result.Version)
+}
+
+func (s *ItemServiceSuite) TestUpdateLostRace() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Title: "old title", Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(func(items.Item) *items.Item {
+		current := *stored
+		return &current
+	}, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(rest_errors.NewPreconditionFailedError("modified"))
+
+	// a write racing with another one is a conflict unless the caller sent If-Match
+	_, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Title: "new"})
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+
+	_, err = s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Title: "new", Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}})
+	assert.Equal(s.T(), http.StatusPreconditionFailed, err.Status())
+}
+
+func (s *ItemServiceSuite) TestUpdateSoldOut() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Status: items.StatusActive, AvailableQuantity: 2}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", mock.IsType(stored)).Return(nil)
+
+	result, err := s.itemsService.Update(false, items.Item{Id: objId, Seller: 1, Title: "no stock"})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), items.StatusSoldOut, result.Status)
+	assert.Equal(s.T(), []string{events.TypeItemUpdated, events.TypeItemSoldOut}, stagedEventTypes(*result))
+}
+
+func stagedEventTypes(it items.Item) []string {
+	pending, _ := events.PendingOf(it)
+	types := make([]string, len(pending))
+	for idx, e := range pending {
+		types[idx] = e.Type
+	}
+	return types
+}
+
+// the events are saved with the item, the outbox is not written
+func (s *ItemServiceSuite) TestCreateRecordsEvent() {
+	var saved items.Item
+	s.daoItemsMock.On("Save", mock.IsType(&items.Item{})).Run(func(args mock.Arguments) {
+		saved = *args.Get(0).(*items.Item)
+	}).Return(nil)
+	outbox := mocks.OutboxPersistInterface{}
+	service := NewItemsService(&s.daoItemsMock, &outbox, s.store, queries.DefaultTextBoosts, 3)
+
+	result, err := service.Create(items.Item{Seller: 7, Title: "The Hobbit"})
+
+	assert.Nil(s.T(), err)
+	outbox.AssertNotCalled(s.T(), "Append", mock.Anything)
+	pending, _ := events.PendingOf(saved)
+	assert.Len(s.T(), pending, 1)
+	assert.Equal(s.T(), events.TypeItemCreated, pending[0].Type)
+	assert.Equal(s.T(), result.Id, pending[0].ItemId)
+	assert.EqualValues(s.T(), 7, pending[0].Seller)
+	assert.EqualValues(s.T(), 1, pending[0].Version)
+	assert.EqualValues(s.T(), 1, saved.Revision)
+	assert.Equal(s.T(), "The Hobbit", pending[0].Item.Title)
+}
+
+// the unpublished events of the item go to the outbox before the delete
+func (s *ItemServiceSuite) TestDeleteRecordsEvent() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Revision: 5, Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}}
+	updated, _ := events.ItemChanged(stored, *stored, time.Now())
+	events.Stage(stored, updated...)
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Delete", stored).Return(func(it *items.Item) rest_errors.RestErr {
+		it.Version = &es.DocVersion{SeqNo: 6, PrimaryTerm: 1}
+		return nil
+	})
+
+	assert.Nil(s.T(), s.itemsService.Delete(items.Item{Id: objId, Seller: 1}))
+
+	pending, _ := s.outbox.Pending(100)
+	assert.Len(s.T(), pending, 2)
+	assert.Equal(s.T(), events.TypeItemUpdated, pending[0].Type)
+	assert.Equal(s.T(), events.TypeItemDeleted, pending[1].Type)
+	assert.EqualValues(s.T(), 6, pending[1].Version)
+}
+
+func (s *ItemServiceSuite) TestDeleteFailedRemovesEvent() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Version: &es.DocVersion{SeqNo: 5, PrimaryTerm: 1}}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Delete", stored).Return(rest_errors.NewInternalServerError("delete item error", errors.New("down")))
+
+	err := s.itemsService.Delete(items.Item{Id: objId, Seller: 1})
+
+	assert.Equal(s.T(), http.StatusInternalServerError, err.Status())
+	pending, _ := s.outbox.Pending(100)
+	assert.Empty(s.T(), pending)
+}
+
+func (s *ItemServiceSuite) TestUpdateFailedTransition() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Title: "The Hobbit", Status: items.StatusArchived}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+
+	result, err := s.itemsService.Update(true, items.Item{Id: objId, Seller: 1, Status: items.StatusActive})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+}
+
+func (s *ItemServiceSuite) TestTransitionOk() {
+	const objId = "111"
+	stored := &items.Item{Id: objId, Seller: 1, Status: items.StatusDraft, AvailableQuantity: 1}
+	s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+
+	result, err := s.itemsService.Transition(items.Item{Id: objId, Seller: 1, Status: items.StatusActive})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), items.StatusActive, result.Status)
+}
+
+func (s *ItemServiceSuite) TestTransitionFailed() {
+	const objId = "111"
+	for _, tc := range []struct {
+		stored   items.Item
+		status   string
+		expected int
+	}{
+		{items.Item{Status: items.StatusDraft}, items.StatusActive, http.StatusConflict},
+		{items.Item{Status: items.StatusActive, AvailableQuantity: 1}, items.StatusDraft, http.StatusConflict},
+		{items.Item{Status: items.StatusActive, AvailableQuantity: 1}, items.StatusSoldOut, http.StatusBadRequest},
+		{items.Item{Status: items.StatusPaused}, "unknown", http.StatusBadRequest},
+	} {
+		s.SetupTest()
+		stored := tc.stored
+		stored.Id, stored.Seller = objId, 1
+		s.daoItemsMock.On("Get", mock.IsType(items.Item{})).Return(&stored, nil)
+
+		_, err := s.itemsService.Transition(items.Item{Id: objId, Seller: 1, Status: tc.status})
+
+		s.daoItemsMock.AssertNotCalled(s.T(), "Update", mock.Anything)
+		assert.Equal(s.T(), tc.expected, err.Status(), tc.status)
+	}
+}
+
+func (s *ItemServiceSuite) TestImport() {
+	input := `{"title": "a", "status": "active", "available_quantity": 1, "seller": 99, "sold_quantity": 5}
+{"title": "b", "status": "archived"}
+not json
+{"title": "c", "price": {"amount": 100, "currency": "USD"}}
+`
+	reader, _ := items.NewRowReader(items.FormatNdjson, strings.NewReader(input))
+
+	var saved []items.Item
+	s.daoItemsMock.On("SaveAll", mock.IsType([]items.Item{})).Return(func(its []items.Item) []rest_errors.RestErr {
+		saved = append([]items.Item{}, its...)
+		its[0].Id = "id-a"
+		return []rest_errors.RestErr{nil, rest_errors.NewInternalServerError("save item error", errors.New("bulk"))}
+	})
+
+	report, err := s.itemsService.Import(7, reader)
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 4, report.Total)
+	assert.Equal(s.T(), 1, report.Created)
+	assert.Equal(s.T(), 3, report.Failed)
+	assert.Equal(s.T(), "id-a", report.Rows[0].Id)
+	assert.Equal(s.T(), http.StatusBadRequest, report.Rows[1].Error.Status())
+	assert.Equal(s.T(), http.StatusBadRequest, report.Rows[2].Error.Status())
+	assert.Equal(s.T(), http.StatusInternalServerError, report.Rows[3].Error.Status())
+
+	assert.Equal(s.T(), []string{events.TypeItemCreated}, stagedEventTypes(saved[0]))
+	assert.Equal(s.T(), []string{events.TypeItemCreated}, stagedEventTypes(saved[1]))
+
+	assert.Len(s.T(), saved, 2)
+	assert.EqualValues(s.T(), 7, saved[0].Seller)
+	assert.Equal(s.T(), 0, saved[0].SoldQuantity)
+	assert.Equal(s.T(), items.StatusDraft, saved[1].Status)
+}
+
+func (s *ItemServiceSuite) TestExport() {
+	s.daoItemsMock.On("Export", int64(7), mock.Anything).Return(
+		func(seller int64, consume func([]items.Item) rest_errors.RestErr) rest_errors.RestErr {
+			if err := consume([]items.Item{{Id: "1"}, {Id: "2"}}); err != nil {
+				return err
+			}
+			return consume([]items.Item{{Id: "3"}})
+		})
+
+	var buf bytes.Buffer
+	writer, _ := items.NewRowWriter(items.FormatCsv, &buf)
+	err := s.itemsService.Export(7, writer)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 4, strings.Count(buf.String(), "\n"))
+}
+
+func (s *ItemServiceSuite) TestCreateNormalizesIsbn() {
+	s.daoItemsMock.On("Search", mock.IsType(queries.EsQuery{})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+	s.daoItemsMock.On("ClaimIsbn", mock.MatchedBy(func(it items.Item) bool {
+		return it.Id != "" && it.Book.Isbn == "9780306406157"
+	})).Return(nil)
+	s.daoItemsMock.On("Save", mock.IsType(&items.Item{})).Return(nil)
+
+	result, err := s.itemsService.Create(items.Item{Seller: 1, Title: "The Hobbit", Book: items.Book{Isbn: "0-306-40615-2"}})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "9780306406157", result.Book.Isbn)
+}
+
+func (s *ItemServiceSuite) TestCreateDuplicateIsbn() {
+	s.daoItemsMock.On("Search", mock.MatchedBy(func(q queries.EsQuery) bool {
+		return len(q.Equals) == 2 && q.Equals[1].Value == "9780306406157"
+	})).Return(&items.SearchResult{Items: []items.Item{{Id: "listed"}}}, nil)
+
+	result, err := s.itemsService.Create(items.Item{Seller: 1, Title: "The Hobbit", Book: items.Book{Isbn: "9780306406157"}})
+
+	s.daoItemsMock.AssertNotCalled(s.T(), "Save", mock.Anything)
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+}
+
+// the claim rejects a listing of the book created concurrently, not searchable yet
+func (s *ItemServiceSuite) TestCreateClaimedIsbn() {
+	s.daoItemsMock.On("Search", mock.IsType(queries.EsQuery{})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+	s.daoItemsMock.On("ClaimIsbn", mock.IsType(items.Item{})).
+		Return(rest_errors.NewConflictError("isbn 9780306406157 is already listed by the seller"))
+
+	result, err := s.itemsService.Create(items.Item{Seller: 1, Title: "The Hobbit", Book: items.Book{Isbn: "9780306406157"}})
+
+	s.daoItemsMock.AssertNotCalled(s.T(), "Save", mock.Anything)
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+}
+
+func (s *ItemServiceSuite) TestCreateReleasesIsbnOnSaveError() {
+	var claimed items.Item
+	s.daoItemsMock.On("Search", mock.IsType(queries.EsQuery{})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+	s.daoItemsMock.On("ClaimIsbn", mock.IsType(items.Item{})).Run(func(args mock.Arguments) {
+		claimed = args.Get(0).(items.Item)
+	}).Return(nil)
+	s.daoItemsMock.On("Save", mock.IsType(&items.Item{})).Return(rest_errors.NewInternalServerError("save item error", nil))
+	s.daoItemsMock.On("ReleaseIsbn", mock.MatchedBy(func(it items.Item) bool {
+		return it.Id == claimed.Id
+	}), "9780306406157").Return(nil)
+
+	_, err := s.itemsService.Create(items.Item{Seller: 1, Title: "The Hobbit", Book: items.Book{Isbn: "9780306406157"}})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Equal(s.T(), http.StatusInternalServerError, err.Status())
+}
+
+func (s *ItemServiceSuite) TestUpdateIsbnMovesClaim() {
+	current := items.Item{Id: "1", Seller: 1, Title: "The Hobbit", Status: items.StatusActive, Book: items.Book{Isbn: "9780306406157"}}
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(&current, nil)
+	s.daoItemsMock.On("Search", mock.IsType(queries.EsQuery{})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+	s.daoItemsMock.On("ClaimIsbn", mock.MatchedBy(func(it items.Item) bool {
+		return it.Id == "1" && it.Book.Isbn == "9781861972712"
+	})).Return(nil)
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(nil)
+	s.daoItemsMock.On("ReleaseIsbn", mock.MatchedBy(func(it items.Item) bool {
+		return it.Id == "1"
+	}), "9780306406157").Return(nil)
+
+	_, err := s.itemsService.Update(true, items.Item{Id: "1", Seller: 1, Book: items.Book{Isbn: "9781861972712"}})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+}
+
+func (s *ItemServiceSuite) TestCreateInvalidIsbn() {
+	result, err := s.itemsService.Create(items.Item{Seller: 1, Title: "The Hobbit", Book: items.Book{Isbn: "0-306-40615-3"}})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), result)
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+}
+
+func (s *ItemServiceSuite) TestSearchNormalizesIsbn() {
+	q := queries.EsQuery{Equals: []queries.FieldValue{{Field: "book.isbn", Value: "0-306-40615-2"}}}
+	s.daoItemsMock.On("Search", mock.MatchedBy(func(q queries.EsQuery) bool {
+		return q.Equals[0].Value == "9780306406157"
+	})).Return(&items.SearchResult{}, nil)
+
+	_, err := s.itemsService.Search(q, 0)
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+}
+
+func (s *ItemServiceSuite) TestImportDuplicateIsbn() {
+	input := `{"title": "a", "book": {"isbn": "0-306-40615-2"}}
+{"title": "b", "book": {"isbn": "9780306406157"}}
+`
+	reader, _ := items.NewRowReader(items.FormatNdjson, strings.NewReader(input))
+	s.daoItemsMock.On("Search", mock.IsType(queries.EsQuery{})).Return(&items.SearchResult{Items: []items.Item{}}, nil)
+	s.daoItemsMock.On("ClaimIsbn", mock.IsType(items.Item{})).Return(nil).Once()
+	s.daoItemsMock.On("SaveAll", mock.IsType([]items.Item{})).Return([]rest_errors.RestErr{nil})
+
+	report, _ := s.itemsService.Import(1, reader)
+
+	assert.Equal(s.T(), 1, report.Created)
+	assert.Equal(s.T(), http.StatusConflict, report.Rows[1].Error.Status())
+}
+
+func (s *ItemServiceSuite) TestCreateNormalizesPrice() {
+	s.daoItemsMock.On("Save", mock.IsType(&items.Item{})).Return(nil)
+
+	result, err := s.itemsService.Create(items.Item{Title: "The Hobbit", Price: money.New(1999, "eur")})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), money.New(1999, "EUR"), result.Price)
+
+	for _, price := range []money.Money{money.New(100, ""), money.New(100, "XYZ"), money.New(-1, "USD")} {
+		_, err := s.itemsService.Create(items.Item{Title: "The Hobbit", Price: price})
+		assert.Equal(s.T(), http.StatusBadRequest, err.Status(), price.String())
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/blob"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/pictures"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+type PicturesServiceInterface interface {
+	Upload(pictures.Upload) (*items.Item, rest_errors.RestErr)
+	Open(string, int64, bool) (io.ReadCloser, rest_errors.RestErr)
+	Delete(string, int64, int64) (*items.Item, rest_errors.RestErr)
+	Reorder(string, int64, []int64) (*items.Item, rest_errors.RestErr)
+}
+
+type picturesService struct {
+	items         items.ItemsPersistInterface
+	store         blob.StoreInterface
+	baseUrl       string
+	maxSize       int64
+	thumbnailSize int
+}
+
+// NewPicturesService stores pictures in the blob store, baseUrl is the address the pictures are served from.
+// Item changes are recorded in the item pending events
+func NewPicturesService(itemsPersist items.ItemsPersistInterface,
+	store blob.StoreInterface, baseUrl string, maxSize int64, thumbnailSize int) PicturesServiceInterface {
+	return &picturesService{
+		items:         itemsPersist,
+		store:         store,
+		baseUrl:       baseUrl,
+		maxSize:       maxSize,
+		thumbnailSize: thumbnailSize,
+	}
+}
+
+// Upload stores the picture with its thumbnail and appends it to the item pictures
+func (s *picturesService) Upload(up pictures.Upload) (*items.Item, rest_errors.RestErr) {
+	if int64(len(up.Data)) > s.maxSize {
+		return nil, rest_errors.NewBadRequestError(fmt.Sprintf("picture is larger than %d bytes", s.maxSize))
+	}
+	if err := pictures.Check(up.Data, up.ContentType); err != nil {
+		return nil, rest_errors.NewBadRequestError(err.Error())
+	}
+	// the seller is checked before the picture is decoded, the check is repeated with the item update
+	if _, err := s.owned(up.ItemId, up.Seller); err != nil {
+		return nil, err
+	}
+	img, err := pictures.Decode(up.Data, up.ContentType)
+	if err != nil {
+		return nil, rest_errors.NewBadRequestError(err.Error())
+	}
+
+	id, err := pictures.NewId()
+	if err != nil {
+		return nil, rest_errors.NewInternalServerError("upload picture error", err)
+	}
+	var thumbnail bytes.Buffer
+	if err := pictures.EncodeThumbnail(&thumbnail, pictures.Thumbnail(img, s.thumbnailSize)); err != nil {
+		return nil, rest_errors.NewInternalServerError("upload picture error", err)
+	}
+	if err := s.store.Put(pictures.OriginalKey(up.ItemId, id), bytes.NewReader(up.Data)); err != nil {
+		return nil, rest_errors.NewInternalServerError("upload picture error", err)
+	}
+	if err := s.store.Put(pictures.ThumbnailKey(up.ItemId, id), &thumbnail); err != nil {
+		deleteBlobs(s.store, up.ItemId, id)
+		return nil, rest_errors.NewInternalServerError("upload picture error", err)
+	}
+
+	item, restErr := s.updatePictures(up.ItemId, up.Seller, func(it *items.Item) rest_errors.RestErr {
+		if len(it.Pictures) >= items.MaxPictures {
+			return rest_errors.NewBadRequestError(fmt.Sprintf("an item has at most %d pictures", items.MaxPictures))
+		}
+		it.Pictures = append(it.Pictures, s.picture(up.ItemId, id))
+		return nil
+	})
+	if restErr != nil {
+		deleteBlobs(s.store, up.ItemId, id)
+		return nil, restErr
+	}
+	return item, nil
+}
+
+// Open reads the picture of the item or its thumbnail
+func (s *picturesService) Open(itemId string, pictureId int64, thumbnail bool) (io.ReadCloser, rest_errors.RestErr) {
+	item, err := s.items.Get(items.Item{Id: itemId})
+	if err != nil {
+		return nil, err
+	}
+	if pictureIndex(item.Pictures, pictureId) < 0 {
+		return nil, rest_errors.NewNotFoundError("picture not found")
+	}
+
+	key := pictures.OriginalKey(itemId, pictureId)
+	if thumbnail {
+		key = pictures.ThumbnailKey(itemId, pictureId)
+	}
+	r, blobErr := s.store.Get(key)
+	if blobErr != nil {
+		if blobErr == blob.ErrNotFound {
+			return nil, rest_errors.NewNotFoundError("picture not found")
+		}
+		return nil, rest_errors.NewInternalServerError("read picture error", blobErr)
+	}
+	return r, nil
+}
+
+// Delete removes the picture from the item, the files are deleted after
+func (s *picturesService) Delete(itemId string, seller int64, pictureId int64) (*items.Item, rest_errors.RestErr) {
+	item, err := s.updatePictures(itemId, seller, func(it *items.Item) rest_errors.RestErr {
+		idx := pictureIndex(it.Pictures, pictureId)
+		if idx < 0 {
+			return rest_errors.NewNotFoundError("picture not found")
+		}
+		it.Pictures = append(it.Pictures[:idx:idx], it.Pictures[idx+1:]...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleteBlobs(s.store, itemId, pictureId)
+	return item, nil
+}
+
+// Reorder puts the item pictures in the order of ids, every picture must be listed once
+func (s *picturesService) Reorder(itemId string, seller int64, ids []int64) (*items.Item, rest_errors.RestErr) {
+	item, err := s.updatePictures(itemId, seller, func(it *items.Item) rest_errors.RestErr {
+		if len(ids) != len(it.Pictures) {
+			return rest_errors.NewBadRequestError("ids must list every picture of the item once")
+		}
+		ordered := make([]items.Picture, 0, len(ids))
+		seen := make(map[int64]bool, len(ids))
+		for _, id := range ids {
+			idx := pictureIndex(it.Pictures, id)
+			if idx < 0 || seen[id] {
+				return rest_errors.NewBadRequestError("ids must list every picture of the item once")
+			}
+			seen[id] = true
+			ordered = append(ordered, it.Pictures[idx])
+		}
+		it.Pictures = ordered
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// owned reads the item and checks the caller is its seller
+func (s *picturesService) owned(itemId string, seller int64) (*items.Item, rest_errors.RestErr) {
+	item, err := s.items.Get(items.Item{Id: itemId})
+	if err != nil {
+		return nil, err
+	}
+	if item.Seller != seller {
+		return nil, rest_errors.NewForbiddenError("item belongs to another seller")
+	}
+	return item, nil
+}
+
+// updatePictures applies the change to the seller item, re-reading the item when another writer was faster.
+// The item is returned when it has been written, an error means it has not
+func (s *picturesService) updatePictures(itemId string, seller int64, change func(*items.Item) rest_errors.RestErr) (*items.Item, rest_errors.RestErr) {
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		item, err := s.owned(itemId, seller)
+		if err != nil {
+			return nil, err
+		}
+		before := *item
+		if err = change(item); err != nil {
+			return nil, err
+		}
+		if err = stageItemChange(time.Now(), &before, item); err != nil {
+			return nil, err
+		}
+		if err = s.items.Update(item); err == nil {
+			return item, nil
+		}
+		if !isStaleWrite(err) {
+			return nil, err
+		}
+	}
+	return nil, errConcurrentItemWrites()
+}
+
+func (s *picturesService) picture(itemId string, id int64) items.Picture {
+	url := fmt.Sprintf("%s/items/%s/pictures/%d", s.baseUrl, itemId, id)
+	return items.Picture{Id: id, Url: url, ThumbnailUrl: url + "/thumbnail"}
+}
+
+// deleteBlobs removes the picture files, a failure leaves unreferenced files only
+func deleteBlobs(store blob.StoreInterface, itemId string, pictureId int64) {
+	for _, key := range []string{pictures.OriginalKey(itemId, pictureId), pictures.ThumbnailKey(itemId, pictureId)} {
+		if err := store.Delete(key); err != nil {
+			logger.Error("delete picture error", err)
+		}
+	}
+}
+
+func pictureIndex(ls []items.Picture, id int64) int {
+	for idx, p := range ls {
+		if p.Id == id {
+			return idx
+		}
+	}
+	return -1
+}
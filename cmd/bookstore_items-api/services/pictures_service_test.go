@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/client/blob"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/pictures"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+//go:generate mockery --name=PicturesServiceInterface --output ../mocks
+//go:generate mockery --name=StoreInterface --dir=../client/blob --output ../mocks
+
+type PictureServiceSuite struct {
+	suite.Suite
+	daoItemsMock mocks.ItemsPersistInterface
+	store        blob.StoreInterface
+	service      PicturesServiceInterface
+	stored       items.Item
+}
+
+func TestPictureServiceSuite(t *testing.T) {
+	suite.Run(t, new(PictureServiceSuite))
+}
+
+func (s *PictureServiceSuite) SetupTest() {
+	var err error
+	s.daoItemsMock = mocks.ItemsPersistInterface{}
+	s.store, err = blob.NewLocalStore(s.T().TempDir())
+	assert.Nil(s.T(), err)
+	s.service = NewPicturesService(&s.daoItemsMock, s.store, "http://pictures", 1<<20, 50)
+}
+
+func pngPicture(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+// storedItem is the item read by the service
+func (s *PictureServiceSuite) storedItem(its ...items.Picture) {
+	s.stored = items.Item{Id: "1", Seller: 7, Pictures: its}
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(func(items.Item) *items.Item {
+		it := s.stored
+		it.Pictures = append([]items.Picture(nil), s.stored.Pictures...)
+		return &it
+	}, nil)
+}
+
+// acceptUpdates replaces the stored item on update
+func (s *PictureServiceSuite) acceptUpdates() {
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(nil).Run(func(args mock.Arguments) {
+		s.stored = *args.Get(0).(*items.Item)
+	})
+}
+
+func (s *PictureServiceSuite) TestUploadOk() {
+	s.storedItem(items.Picture{Id: 1, Url: "http://elsewhere/1.jpg"})
+	s.acceptUpdates()
+
+	data := pngPicture(s.T(), 300, 100)
+	result, err := s.service.Upload(pictures.Upload{ItemId: "1", Seller: 7, ContentType: "image/png", Data: data})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, len(result.Pictures))
+
+	picture := result.Pictures[1]
+	assert.Equal(s.T(), "http://pictures/items/1/pictures/"+itoa(picture.Id), picture.Url)
+	assert.Equal(s.T(), picture.Url+"/thumbnail", picture.ThumbnailUrl)
+
+	r, err := s.service.Open("1", picture.Id, false)
+	assert.Nil(s.T(), err)
+	stored, _ := ioutil.ReadAll(r)
+	r.Close()
+	assert.Equal(s.T(), data, stored)
+
+	r, err = s.service.Open("1", picture.Id, true)
+	assert.Nil(s.T(), err)
+	thumbnail, _, decodeErr := image.Decode(r)
+	r.Close()
+	assert.Nil(s.T(), decodeErr)
+	assert.Equal(s.T(), image.Rect(0, 0, 50, 16), thumbnail.Bounds())
+}
+
+func (s *PictureServiceSuite) TestUploadFailedValidation() {
+	data := pngPicture(s.T(), 10, 10)
+
+	_, err := s.service.Upload(pictures.Upload{ItemId: "1", Seller: 7, ContentType: "image/jpeg", Data: data})
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+
+	_, err = s.service.Upload(pictures.Upload{ItemId: "1", Seller: 7, ContentType: "image/png", Data: make([]byte, 2<<20)})
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+
+	s.daoItemsMock.AssertExpectations(s.T())
+}
+
+func (s *PictureServiceSuite) TestUploadForbidden() {
+	s.storedItem()
+
+	_, err := s.service.Upload(pictures.Upload{ItemId: "1", Seller: 8, ContentType: "image/png", Data: pngPicture(s.T(), 10, 10)})
+
+	assert.Equal(s.T(), http.StatusForbidden, err.Status())
+	s.daoItemsMock.AssertNotCalled(s.T(), "Update", mock.Anything)
+}
+
+func (s *PictureServiceSuite) TestUploadRemovesFilesOnFailure() {
+	store := new(mocks.StoreInterface)
+	s.service = NewPicturesService(&s.daoItemsMock, store, "http://pictures", 1<<20, 50)
+	s.storedItem()
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(rest_errors.NewInternalServerError("update item error", errors.New("down")))
+	store.On("Put", mock.Anything, mock.Anything).Return(nil)
+	store.On("Delete", mock.Anything).Return(nil)
+
+	_, err := s.service.Upload(pictures.Upload{ItemId: "1", Seller: 7, ContentType: "image/png", Data: pngPicture(s.T(), 10, 10)})
+
+	assert.Equal(s.T(), http.StatusInternalServerError, err.Status())
+	store.AssertNumberOfCalls(s.T(), "Put", 2)
+	store.AssertNumberOfCalls(s.T(), "Delete", 2)
+}
+
+func (s *PictureServiceSuite) TestOpenNotFound() {
+	s.storedItem(items.Picture{Id: 1, Url: "http://elsewhere/1.jpg"})
+
+	_, err := s.service.Open("1", 2, false)
+	assert.Equal(s.T(), http.StatusNotFound, err.Status())
+
+	// a picture hosted elsewhere has no file
+	_, err = s.service.Open("1", 1, false)
+	assert.Equal(s.T(), http.StatusNotFound, err.Status())
+}
+
+func (s *PictureServiceSuite) TestDeleteOk() {
+	s.storedItem(items.Picture{Id: 1}, items.Picture{Id: 2}, items.Picture{Id: 3})
+	s.acceptUpdates()
+	assert.Nil(s.T(), s.store.Put(pictures.OriginalKey("1", 2), bytes.NewReader([]byte("picture"))))
+
+	result, err := s.service.Delete("1", 7, 2)
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), []items.Picture{{Id: 1}, {Id: 3}}, result.Pictures)
+	_, blobErr := s.store.Get(pictures.OriginalKey("1", 2))
+	assert.Equal(s.T(), blob.ErrNotFound, blobErr)
+
+	_, err = s.service.Delete("1", 7, 4)
+	assert.Equal(s.T(), http.StatusNotFound, err.Status())
+}
+
+func (s *PictureServiceSuite) TestReorder() {
+	s.storedItem(items.Picture{Id: 1}, items.Picture{Id: 2}, items.Picture{Id: 3})
+	s.acceptUpdates()
+
+	result, err := s.service.Reorder("1", 7, []int64{3, 1, 2})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), []items.Picture{{Id: 3}, {Id: 1}, {Id: 2}}, result.Pictures)
+
+	for _, ids := range [][]int64{{3, 1}, {3, 1, 1}, {3, 1, 4}} {
+		_, err = s.service.Reorder("1", 7, ids)
+		assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+	}
+}
+
+func (s *PictureServiceSuite) TestReorderRetriesOnConflict() {
+	s.storedItem(items.Picture{Id: 1}, items.Picture{Id: 2})
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(rest_errors.NewPreconditionFailedError("modified")).Once()
+	s.acceptUpdates()
+
+	_, err := s.service.Reorder("1", 7, []int64{2, 1})
+
+	assert.Nil(s.T(), err)
+	s.daoItemsMock.AssertNumberOfCalls(s.T(), "Get", 2)
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
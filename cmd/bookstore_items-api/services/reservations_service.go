@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/reservations"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const (
+	// attempts to apply a stock change racing with other writers of the item
+	maxConflictRetries = 5
+	// expired reservations released per sweep
+	expiredBatchSize = 100
+)
+
+type ReservationsServiceInterface interface {
+	Reserve(reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr)
+	Commit(reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr)
+	Release(reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr)
+	ReleaseExpired() (int, rest_errors.RestErr)
+}
+
+type reservationsService struct {
+	items        items.ItemsPersistInterface
+	reservations reservations.ReservationsPersistInterface
+	ttl          time.Duration
+	now          func() time.Time
+}
+
+// NewReservationsService creates the service, stock changes are recorded in the item pending events as item changes
+func NewReservationsService(itemsPersist items.ItemsPersistInterface,
+	reservationsPersist reservations.ReservationsPersistInterface,
+	ttl time.Duration) ReservationsServiceInterface {
+	return &reservationsService{
+		items:        itemsPersist,
+		reservations: reservationsPersist,
+		ttl:          ttl,
+		now:          time.Now,
+	}
+}
+
+// Reserve takes the quantity out of the available stock and holds it for the buyer until
+// the reservation is committed, released or expired
+func (s *reservationsService) Reserve(r reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.updateStock(r.ItemId, func(item *items.Item) rest_errors.RestErr {
+		if item.Status != items.StatusActive {
+			return rest_errors.NewConflictError("item is not on sale")
+		}
+		if item.AvailableQuantity < r.Quantity {
+			return rest_errors.NewConflictError("not enough items available")
+		}
+		item.AvailableQuantity -= r.Quantity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	r.Status = reservations.StatusPending
+	r.DateCreated = now
+	r.ExpiresAt = now.Add(s.ttl)
+
+	if err := s.reservations.Save(&r); err != nil {
+		s.restoreStock(r)
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Commit moves the reserved quantity into the sold stock. The reservation is claimed first so that
+// a concurrent release or expiry loses, it goes back to pending when the sale is not recorded
+func (s *reservationsService) Commit(r reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr) {
+	current, err := s.claim(r, reservations.StatusCommitted)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.updateStock(current.ItemId, func(item *items.Item) rest_errors.RestErr {
+		item.SoldQuantity += current.Quantity
+		return nil
+	})
+	if err != nil {
+		// the buyer commits again or the expiry sweep releases it
+		current.Status = reservations.StatusPending
+		if rollbackErr := s.reservations.Update(current); rollbackErr != nil {
+			logger.Error(fmt.Sprintf("reservation %s committed, sold quantity of item %s not updated",
+				current.Id, current.ItemId), rollbackErr)
+		}
+		return nil, err
+	}
+	return current, nil
+}
+
+// Release returns the reserved quantity to the available stock
+func (s *reservationsService) Release(r reservations.Reservation) (*reservations.Reservation, rest_errors.RestErr) {
+	current, err := s.claim(r, reservations.StatusReleased)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restoreStock(*current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// ReleaseExpired releases pending reservations past their expiry time
+func (s *reservationsService) ReleaseExpired() (int, rest_errors.RestErr) {
+	expired, err := s.reservations.FindExpired(s.now(), expiredBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for idx := range expired {
+		r := expired[idx]
+		r.Status = reservations.StatusReleased
+		if err := s.reservations.Update(&r); err != nil {
+			// committed or released meanwhile
+			if err.Status() != http.StatusConflict {
+				return released, err
+			}
+			continue
+		}
+		if err := s.restoreStock(r); err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// claim moves a pending reservation into the final status. The versioned update
+// guarantees that only one of concurrent commit, release or expiry wins
+func (s *reservationsService) claim(r reservations.Reservation, status string) (*reservations.Reservation, rest_errors.RestErr) {
+	current, err := s.reservations.Get(r)
+	if err != nil {
+		return nil, err
+	}
+	if current.ItemId != r.ItemId {
+		return nil, rest_errors.NewNotFoundError("reservation not found")
+	}
+	if current.Buyer != r.Buyer {
+		return nil, rest_errors.NewForbiddenError("reservation belongs to another buyer")
+	}
+	if current.Status != reservations.StatusPending {
+		return nil, rest_errors.NewConflictError(fmt.Sprintf("reservation is %s", current.Status))
+	}
+	if status == reservations.StatusCommitted && current.IsExpired(s.now()) {
+		return nil, rest_errors.NewConflictError("reservation is expired")
+	}
+
+	current.Status = status
+	if err := s.reservations.Update(current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+func (s *reservationsService) restoreStock(r reservations.Reservation) rest_errors.RestErr {
+	err := s.updateStock(r.ItemId, func(item *items.Item) rest_errors.RestErr {
+		item.AvailableQuantity += r.Quantity
+		return nil
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("reservation %s released, available quantity of item %s not restored",
+			r.Id, r.ItemId), err)
+	}
+	return err
+}
+
+// updateStock applies the change to the latest item revision, a concurrent write
+// makes the versioned update fail and the change is retried on a fresh read.
+// The item is sold out or back on sale following the new stock
+func (s *reservationsService) updateStock(itemId string, change func(*items.Item) rest_errors.RestErr) rest_errors.RestErr {
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		item, err := s.items.Get(items.Item{Id: itemId})
+		if err != nil {
+			return err
+		}
+		before := *item
+		if err = change(item); err != nil {
+			return err
+		}
+		item.SyncStockStatus()
+		if err = stageItemChange(s.now(), &before, item); err != nil {
+			return err
+		}
+		if err = s.items.Update(item); err == nil {
+			return nil
+		}
+		if !isStaleWrite(err) {
+			return err
+		}
+	}
+	return errConcurrentItemWrites()
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/events"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/reservations"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/mocks"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+//go:generate mockery --name=ReservationsServiceInterface --output ../mocks
+//go:generate mockery --name=ReservationsPersistInterface --dir=../domain/reservations --output ../mocks
+
+var now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+type ReservationServiceSuite struct {
+	suite.Suite
+	service         *reservationsService
+	daoItemsMock    mocks.ItemsPersistInterface
+	daoReservations mocks.ReservationsPersistInterface
+}
+
+func TestReservationServiceSuite(t *testing.T) {
+	suite.Run(t, new(ReservationServiceSuite))
+}
+
+func (s *ReservationServiceSuite) SetupTest() {
+	s.daoItemsMock = mocks.ItemsPersistInterface{}
+	s.daoReservations = mocks.ReservationsPersistInterface{}
+	s.service = NewReservationsService(&s.daoItemsMock, &s.daoReservations, time.Minute).(*reservationsService)
+	s.service.now = func() time.Time { return now }
+}
+
+func (s *ReservationServiceSuite) TestReserveOk() {
+	stored := &items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 5}
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+	s.daoReservations.On("Save", mock.IsType(&reservations.Reservation{})).Return(nil)
+
+	result, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Buyer: 7, Quantity: 2})
+
+	s.daoItemsMock.AssertExpectations(s.T())
+	s.daoReservations.AssertExpectations(s.T())
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 3, stored.AvailableQuantity)
+	assert.Equal(s.T(), reservations.StatusPending, result.Status)
+	assert.Equal(s.T(), now.Add(time.Minute), result.ExpiresAt)
+}
+
+func (s *ReservationServiceSuite) TestReserveSoldOutRecordsEvents() {
+	stored := &items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 2}
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+	s.daoReservations.On("Save", mock.IsType(&reservations.Reservation{})).Return(nil)
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Buyer: 7, Quantity: 2})
+
+	assert.Nil(s.T(), err)
+	pending, _ := events.PendingOf(*stored)
+	assert.Len(s.T(), pending, 2)
+	assert.Equal(s.T(), events.TypeItemUpdated, pending[0].Type)
+	assert.Equal(s.T(), events.TypeItemSoldOut, pending[1].Type)
+	assert.Equal(s.T(), now, pending[1].OccurredAt)
+}
+
+func (s *ReservationServiceSuite) TestReserveRetriesOnConflict() {
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(func(items.Item) *items.Item {
+		return &items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 5}
+	}, nil)
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).
+		Return(rest_errors.NewPreconditionFailedError("modified")).Once()
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(nil).Once()
+	s.daoReservations.On("Save", mock.IsType(&reservations.Reservation{})).Return(nil)
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Quantity: 2})
+
+	assert.Nil(s.T(), err)
+	s.daoItemsMock.AssertNumberOfCalls(s.T(), "Get", 2)
+	s.daoItemsMock.AssertNumberOfCalls(s.T(), "Update", 2)
+}
+
+func (s *ReservationServiceSuite) TestReserveGivesUpAfterRetries() {
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(func(items.Item) *items.Item {
+		return &items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 5}
+	}, nil)
+	s.daoItemsMock.On("Update", mock.IsType(&items.Item{})).Return(rest_errors.NewPreconditionFailedError("modified"))
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Quantity: 2})
+
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+	s.daoItemsMock.AssertNumberOfCalls(s.T(), "Update", maxConflictRetries)
+}
+
+func (s *ReservationServiceSuite) TestReserveNotEnoughStock() {
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(&items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 1}, nil)
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Quantity: 2})
+
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+	s.daoItemsMock.AssertNotCalled(s.T(), "Update", mock.Anything)
+}
+
+func (s *ReservationServiceSuite) TestReserveLastItemSoldOut() {
+	stored := &items.Item{Id: "1", Status: items.StatusActive, AvailableQuantity: 2}
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+	s.daoReservations.On("Save", mock.IsType(&reservations.Reservation{})).Return(nil)
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Quantity: 2})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), items.StatusSoldOut, stored.Status)
+}
+
+func (s *ReservationServiceSuite) TestReserveNotOnSale() {
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(&items.Item{Id: "1", Status: items.StatusPaused, AvailableQuantity: 5}, nil)
+
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1", Quantity: 2})
+
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+	s.daoItemsMock.AssertNotCalled(s.T(), "Update", mock.Anything)
+}
+
+func (s *ReservationServiceSuite) TestReserveBadQuantity() {
+	_, err := s.service.Reserve(reservations.Reservation{ItemId: "1"})
+
+	assert.Equal(s.T(), http.StatusBadRequest, err.Status())
+}
+
+func (s *ReservationServiceSuite) TestCommitOk() {
+	pending := &reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7, Quantity: 2,
+		Status: reservations.StatusPending, ExpiresAt: now.Add(time.Minute)}
+	stored := &items.Item{Id: "1", AvailableQuantity: 3, SoldQuantity: 1}
+	s.daoReservations.On("Get", reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7}).Return(pending, nil)
+	s.daoReservations.On("Update", pending).Return(nil)
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+
+	result, err := s.service.Commit(reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7})
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), reservations.StatusCommitted, result.Status)
+	assert.Equal(s.T(), 3, stored.AvailableQuantity)
+	assert.Equal(s.T(), 3, stored.SoldQuantity)
+}
+
+func (s *ReservationServiceSuite) TestCommitStockFailedRollsBack() {
+	pending := &reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7, Quantity: 2,
+		Status: reservations.StatusPending, ExpiresAt: now.Add(time.Minute)}
+	var statuses []string
+	s.daoReservations.On("Get", mock.IsType(reservations.Reservation{})).Return(pending, nil)
+	s.daoReservations.On("Update", pending).Run(func(args mock.Arguments) {
+		statuses = append(statuses, args.Get(0).(*reservations.Reservation).Status)
+	}).Return(nil)
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(nil, rest_errors.NewInternalServerError("get item error", nil))
+
+	_, err := s.service.Commit(reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7})
+
+	assert.Equal(s.T(), http.StatusInternalServerError, err.Status())
+	assert.Equal(s.T(), []string{reservations.StatusCommitted, reservations.StatusPending}, statuses)
+}
+
+func (s *ReservationServiceSuite) TestCommitExpired() {
+	s.daoReservations.On("Get", mock.IsType(reservations.Reservation{})).Return(&reservations.Reservation{
+		Id: "r", ItemId: "1", Buyer: 7, Status: reservations.StatusPending, ExpiresAt: now}, nil)
+
+	_, err := s.service.Commit(reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7})
+
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+}
+
+func (s *ReservationServiceSuite) TestCommitForbidden() {
+	s.daoReservations.On("Get", mock.IsType(reservations.Reservation{})).Return(&reservations.Reservation{
+		Id: "r", ItemId: "1", Buyer: 7, Status: reservations.StatusPending}, nil)
+
+	_, err := s.service.Commit(reservations.Reservation{Id: "r", ItemId: "1", Buyer: 8})
+
+	assert.Equal(s.T(), http.StatusForbidden, err.Status())
+}
+
+func (s *ReservationServiceSuite) TestReleaseAlreadyCommitted() {
+	s.daoReservations.On("Get", mock.IsType(reservations.Reservation{})).Return(&reservations.Reservation{
+		Id: "r", ItemId: "1", Buyer: 7, Status: reservations.StatusCommitted}, nil)
+
+	_, err := s.service.Release(reservations.Reservation{Id: "r", ItemId: "1", Buyer: 7})
+
+	assert.Equal(s.T(), http.StatusConflict, err.Status())
+	s.daoItemsMock.AssertNotCalled(s.T(), "Get", mock.Anything)
+}
+
+func (s *ReservationServiceSuite) TestReleaseExpired() {
+	expired := []reservations.Reservation{
+		{Id: "a", ItemId: "1", Quantity: 2, Status: reservations.StatusPending},
+		{Id: "b", ItemId: "1", Quantity: 3, Status: reservations.StatusPending},
+	}
+	stored := &items.Item{Id: "1", Status: items.StatusSoldOut}
+	s.daoReservations.On("FindExpired", now, expiredBatchSize).Return(expired, nil)
+	s.daoReservations.On("Update", mock.MatchedBy(func(r *reservations.Reservation) bool { return r.Id == "a" })).
+		Return(rest_errors.NewConflictError("committed meanwhile"))
+	s.daoReservations.On("Update", mock.MatchedBy(func(r *reservations.Reservation) bool { return r.Id == "b" })).
+		Return(nil)
+	s.daoItemsMock.On("Get", items.Item{Id: "1"}).Return(stored, nil)
+	s.daoItemsMock.On("Update", stored).Return(nil)
+
+	released, err := s.service.ReleaseExpired()
+
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 1, released)
+	assert.Equal(s.T(), 3, stored.AvailableQuantity)
+	assert.Equal(s.T(), items.StatusActive, stored.Status)
+}
@@ -8,8 +8,6 @@ import (
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/app"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/config"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/controllers"
-	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
-	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
 	"github.com/google/wire"
 )
@@ -24,8 +22,20 @@ func inject(appConfig *config.Config) *app.Application {
 			wire.Bind(new(oauth.OAuthInterface), new(*oauth.OAuthClient)),
 
 			controllers.NewItemController,
-			services.NewItemsService,
-			items.NewItemPersister,
+			app.ProvideItemsService,
+			app.ProvideItemsPersister,
+
+			controllers.NewReservationController,
+			app.ProvideReservationsService,
+			app.ProvideReservationsPersister,
+
+			controllers.NewPictureController,
+			app.ProvidePicturesService,
+			app.ProvideBlobStore,
+
+			app.ProvideEventsService,
+			app.ProvideOutbox,
+			app.ProvidePublisher,
 
 			wire.Bind(new(oauth.HttpClientInterface), new(*http.Client)),
 			wire.Value(http.DefaultClient),
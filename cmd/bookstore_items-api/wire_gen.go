@@ -9,8 +9,6 @@ import (
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/app"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/config"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/controllers"
-	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/domain/items"
-	"github.com/chernyshev-alex/bookstore/cmd/bookstore_items-api/services"
 	"net/http"
 )
 
@@ -19,10 +17,19 @@ import (
 func inject(appConfig *config.Config) *app.Application {
 	client := _wireClientValue
 	oAuthClient := app.ProvideOAuthClient(client, appConfig)
-	itemsPersistInterface := items.NewItemPersister()
-	itemsServiceInterface := services.NewItemsService(itemsPersistInterface)
+	itemsPersistInterface := app.ProvideItemsPersister(appConfig)
+	outboxPersistInterface := app.ProvideOutbox(appConfig)
+	storeInterface := app.ProvideBlobStore(appConfig)
+	itemsServiceInterface := app.ProvideItemsService(appConfig, itemsPersistInterface, outboxPersistInterface, storeInterface)
 	itemControllerInterface := controllers.NewItemController(oAuthClient, itemsServiceInterface)
-	application := app.NewApp(appConfig, itemControllerInterface)
+	reservationsPersistInterface := app.ProvideReservationsPersister(appConfig)
+	reservationsServiceInterface := app.ProvideReservationsService(appConfig, itemsPersistInterface, reservationsPersistInterface)
+	reservationControllerInterface := controllers.NewReservationController(oAuthClient, reservationsServiceInterface)
+	picturesServiceInterface := app.ProvidePicturesService(appConfig, itemsPersistInterface, storeInterface)
+	pictureControllerInterface := controllers.NewPictureController(oAuthClient, picturesServiceInterface)
+	publisherInterface := app.ProvidePublisher(appConfig)
+	eventsServiceInterface := app.ProvideEventsService(appConfig, outboxPersistInterface, itemsPersistInterface, publisherInterface)
+	application := app.NewApp(appConfig, itemControllerInterface, reservationControllerInterface, pictureControllerInterface, reservationsServiceInterface, eventsServiceInterface)
 	return application
 }
 
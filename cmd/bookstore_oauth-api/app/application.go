@@ -1,6 +1,8 @@
 package app
 
 import (
+	"os"
+
 	"github.com/chernyshev-alex/bookstore_oauth-api/http"
 	"github.com/chernyshev-alex/bookstore_oauth-api/repository/db"
 	"github.com/chernyshev-alex/bookstore_oauth-api/repository/rest"
@@ -18,8 +20,15 @@ func StartApplication() {
 
 	atHandler := http.NewHandler(atService)
 
+	secret := os.Getenv("OAUTH_INTERNAL_SECRET")
+	if secret == "" {
+		panic("OAUTH_INTERNAL_SECRET is not set, use the value of the users api")
+	}
+
 	router.GET("/oauth/access_token/:access_token_id", atHandler.GetById)
 	router.POST("/oauth/access_token", atHandler.Create)
+	router.DELETE("/oauth/users/:user_id/access_tokens",
+		http.InternalOnly(secret), atHandler.DeleteByUserId)
 
 	router.Run(":8080")
 }
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/chernyshev-alex/bookstore_oauth-api/domain/access_token"
@@ -10,9 +12,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const headerXInternalSecret = "X-Internal-Secret"
+
 type AccessTokenHandler interface {
 	GetById(*gin.Context)
 	Create(ctx *gin.Context)
+	DeleteByUserId(ctx *gin.Context)
 }
 
 type accessTokenHandler struct {
@@ -52,3 +57,31 @@ func (h *accessTokenHandler) Create(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusCreated, accessToken)
 }
+
+// DeleteByUserId ends all the sessions of the user, the users api calls it after a password reset
+func (h *accessTokenHandler) DeleteByUserId(ctx *gin.Context) {
+	userId, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		restErr := rest_errors.NewBadRequestError("user id should be a number")
+		ctx.JSON(restErr.Status, restErr)
+		return
+	}
+	if err := h.service.DeleteByUserId(userId); err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// InternalOnly lets through the calls carrying the shared secret of the internal endpoints,
+// an empty secret refuses every call
+func InternalOnly(secret string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		got := ctx.GetHeader(headerXInternalSecret)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.Next()
+	}
+}
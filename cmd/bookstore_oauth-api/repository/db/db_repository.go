@@ -11,12 +11,15 @@ const (
 	getAccessToken    = "select access_token, user_id, cclient_id, expires from access_tokens where sccess_token=?;"
 	createAccessToken = "insert into access_tokens(access_token, user_id, cclient_id, expires) values(?,?,?,?);"
 	updateExpires     = "updte access_tokens set expires=? where  access_token=?;"
+	getUserTokens     = "select access_token from access_tokens where user_id=?;"
+	deleteAccessToken = "delete from access_tokens where access_token=?;"
 )
 
 type DbRepository interface {
 	GetById(string) (*access_token.AccessToken, rest_errors.RestErr)
 	Create(access_token.AccessToken) rest_errors.RestErr
 	UpdateExpirationTime(access_token.AccessToken) rest_errors.RestErr
+	DeleteByUserId(int64) rest_errors.RestErr
 }
 
 type dbRepository struct {
@@ -63,3 +66,25 @@ func (r *dbRepository) UpdateExpirationTime(at access_token.AccessToken) rest_er
 	}
 	return nil
 }
+
+// DeleteByUserId deletes the tokens of the user, user_id has a secondary index
+func (r *dbRepository) DeleteByUserId(userId int64) rest_errors.RestErr {
+	ss := cassandra.GetSession()
+
+	var tokens []string
+	var token string
+	iter := ss.Query(getUserTokens, userId).Iter()
+	for iter.Scan(&token) {
+		tokens = append(tokens, token)
+	}
+	if err := iter.Close(); err != nil {
+		return rest_errors.NewInternalServerError(err.Error())
+	}
+
+	for _, token := range tokens {
+		if err := ss.Query(deleteAccessToken, token).Exec(); err != nil {
+			return rest_errors.NewInternalServerError(err.Error())
+		}
+	}
+	return nil
+}
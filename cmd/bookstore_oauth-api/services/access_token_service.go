@@ -13,6 +13,7 @@ type Service interface {
 	GetById(string) (*access_token.AccessToken, rest_errors.RestErr)
 	Create(access_token.AccessTokenRequest) (*access_token.AccessToken, rest_errors.RestErr)
 	UpdateExpirationTime(access_token.AccessToken) rest_errors.RestErr
+	DeleteByUserId(int64) rest_errors.RestErr
 }
 
 type service struct {
@@ -64,3 +65,10 @@ func (s *service) UpdateExpirationTime(at access_token.AccessToken) rest_errors.
 	}
 	return s.dbRepo.UpdateExpirationTime(at)
 }
+
+func (s *service) DeleteByUserId(userId int64) rest_errors.RestErr {
+	if userId <= 0 {
+		return rest_errors.NewBadRequestError("bad user id")
+	}
+	return s.dbRepo.DeleteByUserId(userId)
+}
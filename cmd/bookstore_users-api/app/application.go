@@ -1,8 +1,16 @@
 package app
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/conf"
 	c "github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/controllers"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/mailer"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/passwords"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/services/user_services"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/sql/migrations"
 	oauth "github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/gin-gonic/gin"
@@ -13,15 +21,17 @@ type Application struct {
 	pingController *c.PingController
 	userController *c.UserController
 	appConfig      *conf.Config
+	db             *sql.DB
 }
 
 func ProvideApp(appConfig *conf.Config, pingController *c.PingController,
-	userController *c.UserController) Application {
+	userController *c.UserController, db *sql.DB) Application {
 	return Application{
 		router:         gin.Default(),
 		pingController: pingController,
 		userController: userController,
 		appConfig:      appConfig,
+		db:             db,
 	}
 }
 
@@ -29,6 +39,54 @@ func NewOAuthClient(httpClient oauth.HttpClientInterface, appConf *conf.Config)
 	return oauth.NewAuthClient(httpClient, appConf.OAuth.URL)
 }
 
+// NewTokenRevoker fails without the shared secret, the oauth api refuses every revocation then
+func NewTokenRevoker(httpClient oauth.HttpDoInterface, appConf *conf.Config) *oauth.TokenRevokerClient {
+	if appConf.OAuth.Secret == "" {
+		panic("oauth.secret (OAUTH_INTERNAL_SECRET) is not set, use the value of the oauth api")
+	}
+	return oauth.NewTokenRevoker(httpClient, appConf.OAuth.URL, appConf.OAuth.Secret)
+}
+
+func NewPasswordHasher(appConf *conf.Config) *passwords.Hasher {
+	hasher, err := passwords.NewHasher(passwords.Params{
+		Algorithm:     appConf.Passwords.Algorithm,
+		BcryptCost:    appConf.Passwords.BcryptCost,
+		Argon2Memory:  appConf.Passwords.Argon2Memory,
+		Argon2Time:    appConf.Passwords.Argon2Time,
+		Argon2Threads: appConf.Passwords.Argon2Threads,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return hasher
+}
+
+func NewMailer(appConf *conf.Config) mailer.Mailer {
+	if appConf.Mailer.Type == conf.MailerSmtp {
+		return mailer.NewSmtpMailer(appConf.Mailer.SmtpHost, appConf.Mailer.SmtpPort,
+			appConf.Mailer.SmtpUsername, appConf.Mailer.SmtpPassword, appConf.Mailer.From)
+	}
+	m, err := mailer.NewFileMailer(appConf.Mailer.File, appConf.Mailer.From)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func NewVerification(appConf *conf.Config) user_services.Verification {
+	return user_services.Verification{
+		TTL:  appConf.Verification.TTL,
+		Link: appConf.Verification.Link,
+	}
+}
+
+func NewPasswordReset(appConf *conf.Config) user_services.PasswordReset {
+	return user_services.PasswordReset{
+		TTL:  appConf.PasswordReset.TTL,
+		Link: appConf.PasswordReset.Link,
+	}
+}
+
 func (app *Application) mapUrls() {
 	app.router.GET("/ping", app.pingController.Ping)
 	app.router.POST("/users", app.userController.Create)
@@ -38,10 +96,32 @@ func (app *Application) mapUrls() {
 	app.router.DELETE("/users/:user_id", app.userController.Delete)
 	app.router.GET("/internal/users/search", app.userController.Search)
 	app.router.POST("/users/login", app.userController.Login)
+	app.router.POST("/users/verify", app.userController.Verify)
+	app.router.POST("/users/verify/resend", app.userController.ResendVerification)
+	app.router.POST("/users/password/forgot", app.userController.ForgotPassword)
+	app.router.POST("/users/password/reset", app.userController.ResetPassword)
 }
 
 func (app *Application) StartApp() {
+	if app.appConfig.Database.Migrate {
+		app.migrate()
+	}
 	app.mapUrls()
 	logger.Info("listening on port  " + app.appConfig.Server.Port)
 	app.router.Run(":" + app.appConfig.Server.Port)
 }
+
+// migrate applies the pending schema migrations, instances started together wait for the first one
+func (app *Application) migrate() {
+	ls, err := migrations.Embedded()
+	if err != nil {
+		panic(err)
+	}
+	applied, err := migrations.NewMigrator(app.db, ls).Up(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range applied {
+		logger.Info(fmt.Sprintf("migration %d_%s applied", m.Version, m.Name))
+	}
+}
@@ -3,24 +3,61 @@ package conf
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 // Application configuration
 
+const (
+	MailerFile = "file"
+	MailerSmtp = "smtp"
+)
+
 type Config struct {
 	Database struct {
 		Host   string `yaml:"host" env:"DB_HOST" env-description:"db host"`
 		Port   string `yaml:"port" env:"DB_PORT"`
 		Schema string `yaml:"schema" env:"SCHEMA"`
 		Uname  string `yaml:"uname" env:"USER_NAME"`
+		// Migrate applies the pending schema migrations at startup
+		Migrate bool `yaml:"migrate" env:"DB_MIGRATE" env-default:"false" env-description:"apply schema migrations at startup"`
 	} `yaml:"database"`
 
 	OAuth struct {
-		URL string `yaml:"url" env:"OAUT_URL" env-default:"http://127.0.0.1:8082"`
+		URL    string `yaml:"url" env:"OAUT_URL" env-default:"http://127.0.0.1:8082"`
+		Secret string `yaml:"secret" env:"OAUTH_INTERNAL_SECRET" env-description:"shared secret of the oauth internal endpoints, required, the same value as the oauth api OAUTH_INTERNAL_SECRET"`
 	} `yaml:"oauth"`
 
+	Passwords struct {
+		Algorithm     string `yaml:"algorithm" env:"PASSWORD_ALGORITHM" env-default:"argon2id" env-description:"password hash : argon2id or bcrypt"`
+		BcryptCost    int    `yaml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST" env-default:"12"`
+		Argon2Memory  uint32 `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY" env-default:"65536" env-description:"argon2id memory in KiB"`
+		Argon2Time    uint32 `yaml:"argon2_time" env:"PASSWORD_ARGON2_TIME" env-default:"3"`
+		Argon2Threads uint8  `yaml:"argon2_threads" env:"PASSWORD_ARGON2_THREADS" env-default:"4"`
+	} `yaml:"passwords"`
+
+	Mailer struct {
+		Type         string `yaml:"type" env:"MAILER_TYPE" env-default:"file" env-description:"mail delivery : file or smtp"`
+		File         string `yaml:"file" env:"MAILER_FILE" env-default:"data/mails.log" env-description:"file the mails are appended to"`
+		From         string `yaml:"from" env:"MAILER_FROM" env-default:"no-reply@bookstore.local"`
+		SmtpHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+		SmtpPort     string `yaml:"smtp_port" env:"SMTP_PORT" env-default:"587"`
+		SmtpUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+		SmtpPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	} `yaml:"mailer"`
+
+	Verification struct {
+		TTL  time.Duration `yaml:"ttl" env:"VERIFICATION_TTL" env-default:"24h" env-description:"lifetime of a verification token"`
+		Link string        `yaml:"link" env:"VERIFICATION_LINK" env-default:"http://localhost:3000/verify?token=" env-description:"mailed link, the token is appended"`
+	} `yaml:"verification"`
+
+	PasswordReset struct {
+		TTL  time.Duration `yaml:"ttl" env:"PASSWORD_RESET_TTL" env-default:"1h" env-description:"lifetime of a password reset token"`
+		Link string        `yaml:"link" env:"PASSWORD_RESET_LINK" env-default:"http://localhost:3000/password/reset?token=" env-description:"mailed link, the token is appended"`
+	} `yaml:"password_reset"`
+
 	Server struct {
 		Host string `yaml:"host" env:"SRV_HOST,HOST" env-default:"localhost"`
 		Port string `yaml:"port" env:"SRV_PORT,PORT" env-default:"8081"`
@@ -111,13 +111,74 @@ func (uc UserController) Delete(c *gin.Context) {
 }
 
 func (uc UserController) Search(c *gin.Context) {
-	status := c.Query("status")
-	users, err := uc.srv.SearchUsersByStatus(status)
+	var q models.UserSearch
+	if err := c.ShouldBindQuery(&q); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid search parameters")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	page, err := uc.srv.SearchUsers(q)
 	if err != nil {
 		c.JSON(err.Status(), err)
 		return
 	}
-	c.JSON(http.StatusOK, users[0].Marshall(c.GetHeader("X-Public") == "true"))
+	c.JSON(http.StatusOK, page.Marshall(c.GetHeader("X-Public") == "true"))
+}
+
+func (uc UserController) Verify(c *gin.Context) {
+	var req models.VerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	if err := uc.srv.VerifyUser(req.Token); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": models.STATUS_ACTIVE})
+}
+
+func (uc UserController) ResendVerification(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	if err := uc.srv.ResendVerification(req.Email); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]string{"status": "accepted"})
+}
+
+func (uc UserController) ForgotPassword(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	if err := uc.srv.ForgotPassword(req.Email); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]string{"status": "accepted"})
+}
+
+func (uc UserController) ResetPassword(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		restErr := rest_errors.NewBadRequestError("invalid json body")
+		c.JSON(restErr.Status(), restErr)
+		return
+	}
+	if err := uc.srv.ResetPassword(req); err != nil {
+		c.JSON(err.Status(), err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "password changed"})
 }
 
 func (uc UserController) Login(c *gin.Context) {
@@ -214,14 +214,35 @@ func (s *UCServiceSuite) TestRemoveUserServiceError() {
 }
 
 func (s *UCServiceSuite) TestSearchUserOk() {
-	s.requestWithQuery(http.MethodGet, "/?status=active")
+	s.requestWithQuery(http.MethodGet, "/?status=active&email=jo&name=doe&sort=-email&limit=5&cursor=abc")
 
-	result := []models.User{{Id: 1, FirstName: "fname"}}
-	s.mockedUserService.On("SearchUsersByStatus", mock.AnythingOfType("string")).Return(result, nil)
+	q := models.UserSearch{Status: "active", EmailPrefix: "jo", Name: "doe", Sort: "-email", Limit: 5, Cursor: "abc"}
+	result := &models.UserPage{Users: models.Users{{Id: 1, FirstName: "fname"}}, NextCursor: "next"}
+	s.mockedUserService.On("SearchUsers", q).Return(result, nil)
 
 	s.userController.Search(s.ctx)
 	s.mockedUserService.AssertExpectations(s.T())
 	assert.EqualValues(s.T(), http.StatusOK, s.ctx.Writer.Status())
+	assert.JSONEq(s.T(), `{"results":[{"id":1,"first_name":"fname","last_name":"","email":"","date_created":"","status":""}],
+		"next_cursor":"next"}`, s.response.Body.String())
+}
+
+func (s *UCServiceSuite) TestSearchUserEmpty() {
+	s.requestWithQuery(http.MethodGet, "/?status=active")
+
+	s.mockedUserService.On("SearchUsers", mock.AnythingOfType("models.UserSearch")).Return(&models.UserPage{}, nil)
+
+	s.userController.Search(s.ctx)
+	assert.EqualValues(s.T(), http.StatusOK, s.ctx.Writer.Status())
+	assert.JSONEq(s.T(), `{"results":[]}`, s.response.Body.String())
+}
+
+func (s *UCServiceSuite) TestSearchUserBadRequest() {
+	s.requestWithQuery(http.MethodGet, "/?limit=ten")
+
+	s.userController.Search(s.ctx)
+	s.mockedUserService.AssertNotCalled(s.T(), "SearchUsers", mock.Anything)
+	assert.EqualValues(s.T(), http.StatusBadRequest, s.ctx.Writer.Status())
 }
 
 func (s *UCServiceSuite) TestLoginOk() {
@@ -246,6 +267,64 @@ func (s *UCServiceSuite) TestLoginFailed() {
 	assert.EqualValues(s.T(), http.StatusUnauthorized, s.ctx.Writer.Status())
 }
 
+func (s *UCServiceSuite) TestVerifyOk() {
+	s.requestWithJson(http.MethodPost, `{"token": "abc"}`)
+	s.mockedUserService.On("VerifyUser", "abc").Return(nil)
+
+	s.userController.Verify(s.ctx)
+
+	s.mockedUserService.AssertExpectations(s.T())
+	assert.EqualValues(s.T(), http.StatusOK, s.ctx.Writer.Status())
+	assert.JSONEq(s.T(), `{"status":"active"}`, s.response.Body.String())
+}
+
+func (s *UCServiceSuite) TestVerifyFailed() {
+	s.requestWithJson(http.MethodPost, `{"token": "abc"}`)
+	s.mockedUserService.On("VerifyUser", "abc").Return(rest_errors.NewBadRequestError("invalid or expired verification token"))
+
+	s.userController.Verify(s.ctx)
+	assert.EqualValues(s.T(), http.StatusBadRequest, s.ctx.Writer.Status())
+}
+
+func (s *UCServiceSuite) TestResendVerificationOk() {
+	s.requestWithJson(http.MethodPost, `{"email": "jo@x.com"}`)
+	s.mockedUserService.On("ResendVerification", "jo@x.com").Return(nil)
+
+	s.userController.ResendVerification(s.ctx)
+
+	s.mockedUserService.AssertExpectations(s.T())
+	assert.EqualValues(s.T(), http.StatusAccepted, s.ctx.Writer.Status())
+}
+
+func (s *UCServiceSuite) TestForgotPasswordOk() {
+	s.requestWithJson(http.MethodPost, `{"email": "jo@x.com"}`)
+	s.mockedUserService.On("ForgotPassword", "jo@x.com").Return(nil)
+
+	s.userController.ForgotPassword(s.ctx)
+
+	s.mockedUserService.AssertExpectations(s.T())
+	assert.EqualValues(s.T(), http.StatusAccepted, s.ctx.Writer.Status())
+}
+
+func (s *UCServiceSuite) TestResetPasswordOk() {
+	s.requestWithJson(http.MethodPost, `{"token": "abc", "password": "new"}`)
+	s.mockedUserService.On("ResetPassword", models.PasswordResetRequest{Token: "abc", Password: "new"}).Return(nil)
+
+	s.userController.ResetPassword(s.ctx)
+
+	s.mockedUserService.AssertExpectations(s.T())
+	assert.EqualValues(s.T(), http.StatusOK, s.ctx.Writer.Status())
+}
+
+func (s *UCServiceSuite) TestResetPasswordFailed() {
+	s.requestWithJson(http.MethodPost, `{"token": "abc", "password": "new"}`)
+	s.mockedUserService.On("ResetPassword", mock.Anything).
+		Return(rest_errors.NewBadRequestError("invalid or expired password reset token"))
+
+	s.userController.ResetPassword(s.ctx)
+	assert.EqualValues(s.T(), http.StatusBadRequest, s.ctx.Writer.Status())
+}
+
 // helpers
 
 func (s *UCServiceSuite) requestWithUserAndParams(httpMethod string, u *models.User, params gin.Params) {
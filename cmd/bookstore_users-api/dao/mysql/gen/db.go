@@ -22,21 +22,66 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.deletePasswordResetStmt, err = db.PrepareContext(ctx, deletePasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePasswordReset: %w", err)
+	}
 	if q.deleteUserStmt, err = db.PrepareContext(ctx, deleteUser); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteUser: %w", err)
 	}
-	if q.findByEMailAndPswStmt, err = db.PrepareContext(ctx, findByEMailAndPsw); err != nil {
-		return nil, fmt.Errorf("error preparing query FindByEMailAndPsw: %w", err)
+	if q.deleteUserPasswordResetsStmt, err = db.PrepareContext(ctx, deleteUserPasswordResets); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserPasswordResets: %w", err)
+	}
+	if q.deleteUserVerificationsStmt, err = db.PrepareContext(ctx, deleteUserVerifications); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUserVerifications: %w", err)
+	}
+	if q.deleteVerificationStmt, err = db.PrepareContext(ctx, deleteVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteVerification: %w", err)
+	}
+	if q.findByEmailStmt, err = db.PrepareContext(ctx, findByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query FindByEmail: %w", err)
 	}
 	if q.findByStatusStmt, err = db.PrepareContext(ctx, findByStatus); err != nil {
 		return nil, fmt.Errorf("error preparing query FindByStatus: %w", err)
 	}
+	if q.findPasswordResetStmt, err = db.PrepareContext(ctx, findPasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query FindPasswordReset: %w", err)
+	}
 	if q.findUserStmt, err = db.PrepareContext(ctx, findUser); err != nil {
 		return nil, fmt.Errorf("error preparing query FindUser: %w", err)
 	}
+	if q.findVerificationStmt, err = db.PrepareContext(ctx, findVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query FindVerification: %w", err)
+	}
+	if q.insertPasswordResetStmt, err = db.PrepareContext(ctx, insertPasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertPasswordReset: %w", err)
+	}
 	if q.insertUserStmt, err = db.PrepareContext(ctx, insertUser); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertUser: %w", err)
 	}
+	if q.insertVerificationStmt, err = db.PrepareContext(ctx, insertVerification); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertVerification: %w", err)
+	}
+	if q.replacePendingUserStmt, err = db.PrepareContext(ctx, replacePendingUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ReplacePendingUser: %w", err)
+	}
+	if q.searchUsersByCreatedStmt, err = db.PrepareContext(ctx, searchUsersByCreated); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsersByCreated: %w", err)
+	}
+	if q.searchUsersByCreatedDescStmt, err = db.PrepareContext(ctx, searchUsersByCreatedDesc); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsersByCreatedDesc: %w", err)
+	}
+	if q.searchUsersByEmailStmt, err = db.PrepareContext(ctx, searchUsersByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsersByEmail: %w", err)
+	}
+	if q.searchUsersByEmailDescStmt, err = db.PrepareContext(ctx, searchUsersByEmailDesc); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsersByEmailDesc: %w", err)
+	}
+	if q.updatePasswordStmt, err = db.PrepareContext(ctx, updatePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdatePassword: %w", err)
+	}
+	if q.updateStatusStmt, err = db.PrepareContext(ctx, updateStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateStatus: %w", err)
+	}
 	if q.updateUserStmt, err = db.PrepareContext(ctx, updateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
 	}
@@ -45,14 +90,34 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 
 func (q *Queries) Close() error {
 	var err error
+	if q.deletePasswordResetStmt != nil {
+		if cerr := q.deletePasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePasswordResetStmt: %w", cerr)
+		}
+	}
 	if q.deleteUserStmt != nil {
 		if cerr := q.deleteUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteUserStmt: %w", cerr)
 		}
 	}
-	if q.findByEMailAndPswStmt != nil {
-		if cerr := q.findByEMailAndPswStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing findByEMailAndPswStmt: %w", cerr)
+	if q.deleteUserPasswordResetsStmt != nil {
+		if cerr := q.deleteUserPasswordResetsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserPasswordResetsStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserVerificationsStmt != nil {
+		if cerr := q.deleteUserVerificationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserVerificationsStmt: %w", cerr)
+		}
+	}
+	if q.deleteVerificationStmt != nil {
+		if cerr := q.deleteVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteVerificationStmt: %w", cerr)
+		}
+	}
+	if q.findByEmailStmt != nil {
+		if cerr := q.findByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findByEmailStmt: %w", cerr)
 		}
 	}
 	if q.findByStatusStmt != nil {
@@ -60,16 +125,71 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findByStatusStmt: %w", cerr)
 		}
 	}
+	if q.findPasswordResetStmt != nil {
+		if cerr := q.findPasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findPasswordResetStmt: %w", cerr)
+		}
+	}
 	if q.findUserStmt != nil {
 		if cerr := q.findUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findUserStmt: %w", cerr)
 		}
 	}
+	if q.findVerificationStmt != nil {
+		if cerr := q.findVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findVerificationStmt: %w", cerr)
+		}
+	}
+	if q.insertPasswordResetStmt != nil {
+		if cerr := q.insertPasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertPasswordResetStmt: %w", cerr)
+		}
+	}
 	if q.insertUserStmt != nil {
 		if cerr := q.insertUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing insertUserStmt: %w", cerr)
 		}
 	}
+	if q.insertVerificationStmt != nil {
+		if cerr := q.insertVerificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertVerificationStmt: %w", cerr)
+		}
+	}
+	if q.replacePendingUserStmt != nil {
+		if cerr := q.replacePendingUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing replacePendingUserStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersByCreatedStmt != nil {
+		if cerr := q.searchUsersByCreatedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersByCreatedStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersByCreatedDescStmt != nil {
+		if cerr := q.searchUsersByCreatedDescStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersByCreatedDescStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersByEmailStmt != nil {
+		if cerr := q.searchUsersByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersByEmailStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersByEmailDescStmt != nil {
+		if cerr := q.searchUsersByEmailDescStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersByEmailDescStmt: %w", cerr)
+		}
+	}
+	if q.updatePasswordStmt != nil {
+		if cerr := q.updatePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updatePasswordStmt: %w", cerr)
+		}
+	}
+	if q.updateStatusStmt != nil {
+		if cerr := q.updateStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateStatusStmt: %w", cerr)
+		}
+	}
 	if q.updateUserStmt != nil {
 		if cerr := q.updateUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
@@ -112,25 +232,55 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                    DBTX
-	tx                    *sql.Tx
-	deleteUserStmt        *sql.Stmt
-	findByEMailAndPswStmt *sql.Stmt
-	findByStatusStmt      *sql.Stmt
-	findUserStmt          *sql.Stmt
-	insertUserStmt        *sql.Stmt
-	updateUserStmt        *sql.Stmt
+	db                           DBTX
+	tx                           *sql.Tx
+	deletePasswordResetStmt      *sql.Stmt
+	deleteUserStmt               *sql.Stmt
+	deleteUserPasswordResetsStmt *sql.Stmt
+	deleteUserVerificationsStmt  *sql.Stmt
+	deleteVerificationStmt       *sql.Stmt
+	findByEmailStmt              *sql.Stmt
+	findByStatusStmt             *sql.Stmt
+	findPasswordResetStmt        *sql.Stmt
+	findUserStmt                 *sql.Stmt
+	findVerificationStmt         *sql.Stmt
+	insertPasswordResetStmt      *sql.Stmt
+	insertUserStmt               *sql.Stmt
+	insertVerificationStmt       *sql.Stmt
+	replacePendingUserStmt       *sql.Stmt
+	searchUsersByCreatedStmt     *sql.Stmt
+	searchUsersByCreatedDescStmt *sql.Stmt
+	searchUsersByEmailStmt       *sql.Stmt
+	searchUsersByEmailDescStmt   *sql.Stmt
+	updatePasswordStmt           *sql.Stmt
+	updateStatusStmt             *sql.Stmt
+	updateUserStmt               *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                    tx,
-		tx:                    tx,
-		deleteUserStmt:        q.deleteUserStmt,
-		findByEMailAndPswStmt: q.findByEMailAndPswStmt,
-		findByStatusStmt:      q.findByStatusStmt,
-		findUserStmt:          q.findUserStmt,
-		insertUserStmt:        q.insertUserStmt,
-		updateUserStmt:        q.updateUserStmt,
+		db:                           tx,
+		tx:                           tx,
+		deletePasswordResetStmt:      q.deletePasswordResetStmt,
+		deleteUserStmt:               q.deleteUserStmt,
+		deleteUserPasswordResetsStmt: q.deleteUserPasswordResetsStmt,
+		deleteUserVerificationsStmt:  q.deleteUserVerificationsStmt,
+		deleteVerificationStmt:       q.deleteVerificationStmt,
+		findByEmailStmt:              q.findByEmailStmt,
+		findByStatusStmt:             q.findByStatusStmt,
+		findPasswordResetStmt:        q.findPasswordResetStmt,
+		findUserStmt:                 q.findUserStmt,
+		findVerificationStmt:         q.findVerificationStmt,
+		insertPasswordResetStmt:      q.insertPasswordResetStmt,
+		insertUserStmt:               q.insertUserStmt,
+		insertVerificationStmt:       q.insertVerificationStmt,
+		replacePendingUserStmt:       q.replacePendingUserStmt,
+		searchUsersByCreatedStmt:     q.searchUsersByCreatedStmt,
+		searchUsersByCreatedDescStmt: q.searchUsersByCreatedDescStmt,
+		searchUsersByEmailStmt:       q.searchUsersByEmailStmt,
+		searchUsersByEmailDescStmt:   q.searchUsersByEmailDescStmt,
+		updatePasswordStmt:           q.updatePasswordStmt,
+		updateStatusStmt:             q.updateStatusStmt,
+		updateUserStmt:               q.updateUserStmt,
 	}
 }
@@ -7,6 +7,12 @@ import (
 	"time"
 )
 
+type PasswordReset struct {
+	TokenHash string
+	UserID    int32
+	ExpiresAt time.Time
+}
+
 type User struct {
 	ID          int32
 	FirstName   sql.NullString
@@ -16,3 +22,9 @@ type User struct {
 	Status      sql.NullString
 	Password    sql.NullString
 }
+
+type UserVerification struct {
+	TokenHash string
+	UserID    int32
+	ExpiresAt time.Time
+}
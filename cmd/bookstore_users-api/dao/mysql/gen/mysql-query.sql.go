@@ -9,6 +9,14 @@ import (
 	"time"
 )
 
+const deletePasswordReset = `-- name: DeletePasswordReset :execresult
+DELETE FROM password_resets WHERE token_hash=?
+`
+
+func (q *Queries) DeletePasswordReset(ctx context.Context, tokenHash string) (sql.Result, error) {
+	return q.exec(ctx, q.deletePasswordResetStmt, deletePasswordReset, tokenHash)
+}
+
 const deleteUser = `-- name: DeleteUser :execresult
 DELETE FROM users WHERE id=?
 `
@@ -17,28 +25,52 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) (sql.Result, error)
 	return q.exec(ctx, q.deleteUserStmt, deleteUser, id)
 }
 
-const findByEMailAndPsw = `-- name: FindByEMailAndPsw :one
-SELECT id, first_name,last_name,email,date_created, status FROM users WHERE email=? and password=? and status=?
+const deleteUserPasswordResets = `-- name: DeleteUserPasswordResets :execresult
+DELETE FROM password_resets WHERE user_id=?
 `
 
-type FindByEMailAndPswParams struct {
-	Email    string
-	Password sql.NullString
-	Status   sql.NullString
+func (q *Queries) DeleteUserPasswordResets(ctx context.Context, userID int32) (sql.Result, error) {
+	return q.exec(ctx, q.deleteUserPasswordResetsStmt, deleteUserPasswordResets, userID)
+}
+
+const deleteUserVerifications = `-- name: DeleteUserVerifications :execresult
+DELETE FROM user_verifications WHERE user_id=?
+`
+
+func (q *Queries) DeleteUserVerifications(ctx context.Context, userID int32) (sql.Result, error) {
+	return q.exec(ctx, q.deleteUserVerificationsStmt, deleteUserVerifications, userID)
+}
+
+const deleteVerification = `-- name: DeleteVerification :execresult
+DELETE FROM user_verifications WHERE token_hash=?
+`
+
+func (q *Queries) DeleteVerification(ctx context.Context, tokenHash string) (sql.Result, error) {
+	return q.exec(ctx, q.deleteVerificationStmt, deleteVerification, tokenHash)
+}
+
+const findByEmail = `-- name: FindByEmail :one
+SELECT id, first_name,last_name,email,date_created, status, password FROM users WHERE email=? and status=?
+`
+
+type FindByEmailParams struct {
+	Email  string
+	Status sql.NullString
 }
 
-type FindByEMailAndPswRow struct {
+type FindByEmailRow struct {
 	ID          int32
 	FirstName   sql.NullString
 	LastName    sql.NullString
 	Email       string
 	DateCreated time.Time
 	Status      sql.NullString
+	Password    sql.NullString
 }
 
-func (q *Queries) FindByEMailAndPsw(ctx context.Context, arg FindByEMailAndPswParams) (FindByEMailAndPswRow, error) {
-	row := q.queryRow(ctx, q.findByEMailAndPswStmt, findByEMailAndPsw, arg.Email, arg.Password, arg.Status)
-	var i FindByEMailAndPswRow
+func (q *Queries) FindByEmail(ctx context.Context, arg FindByEmailParams) (FindByEmailRow, error) {
+	row := q.queryRow(ctx, q.findByEmailStmt, findByEmail, arg.Email, arg.Status)
+	var i FindByEmailRow
 	err := row.Scan(
 		&i.ID,
 		&i.FirstName,
@@ -46,6 +78,7 @@ func (q *Queries) FindByEMailAndPsw(ctx context.Context, arg FindByEMailAndPswPa
 		&i.Email,
 		&i.DateCreated,
 		&i.Status,
+		&i.Password,
 	)
 	return i, err
 }
@@ -93,6 +126,21 @@ func (q *Queries) FindByStatus(ctx context.Context, status sql.NullString) ([]Fi
 	return items, nil
 }
 
+const findPasswordReset = `-- name: FindPasswordReset :one
+SELECT token_hash, user_id, expires_at FROM password_resets WHERE token_hash=? FOR UPDATE
+`
+
+func (q *Queries) FindPasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	row := q.queryRow(ctx, q.findPasswordResetStmt, findPasswordReset, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
 const findUser = `-- name: FindUser :one
 SELECT id, first_name,last_name,email, date_created, status FROM users WHERE id = ?
 `
@@ -120,6 +168,35 @@ func (q *Queries) FindUser(ctx context.Context, id int32) (FindUserRow, error) {
 	return i, err
 }
 
+const findVerification = `-- name: FindVerification :one
+SELECT token_hash, user_id, expires_at FROM user_verifications WHERE token_hash=? FOR UPDATE
+`
+
+func (q *Queries) FindVerification(ctx context.Context, tokenHash string) (UserVerification, error) {
+	row := q.queryRow(ctx, q.findVerificationStmt, findVerification, tokenHash)
+	var i UserVerification
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const insertPasswordReset = `-- name: InsertPasswordReset :execresult
+INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, ?, ?)
+`
+
+type InsertPasswordResetParams struct {
+	TokenHash string
+	UserID    int32
+	ExpiresAt time.Time
+}
+
+func (q *Queries) InsertPasswordReset(ctx context.Context, arg InsertPasswordResetParams) (sql.Result, error) {
+	return q.exec(ctx, q.insertPasswordResetStmt, insertPasswordReset, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+}
+
 const insertUser = `-- name: InsertUser :execresult
 INSERT INTO users (first_name,last_name,email,date_created, status, password) VALUES (?, ?, ?, ?, ?, ?)
 `
@@ -144,6 +221,362 @@ func (q *Queries) InsertUser(ctx context.Context, arg InsertUserParams) (sql.Res
 	)
 }
 
+const insertVerification = `-- name: InsertVerification :execresult
+INSERT INTO user_verifications (token_hash, user_id, expires_at) VALUES (?, ?, ?)
+`
+
+type InsertVerificationParams struct {
+	TokenHash string
+	UserID    int32
+	ExpiresAt time.Time
+}
+
+func (q *Queries) InsertVerification(ctx context.Context, arg InsertVerificationParams) (sql.Result, error) {
+	return q.exec(ctx, q.insertVerificationStmt, insertVerification, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+}
+
+const replacePendingUser = `-- name: ReplacePendingUser :execresult
+UPDATE users SET first_name=?, last_name=?, date_created=?, password=? WHERE id=? AND status=?
+`
+
+type ReplacePendingUserParams struct {
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	DateCreated time.Time
+	Password    sql.NullString
+	ID          int32
+	Status      sql.NullString
+}
+
+func (q *Queries) ReplacePendingUser(ctx context.Context, arg ReplacePendingUserParams) (sql.Result, error) {
+	return q.exec(ctx, q.replacePendingUserStmt, replacePendingUser,
+		arg.FirstName,
+		arg.LastName,
+		arg.DateCreated,
+		arg.Password,
+		arg.ID,
+		arg.Status,
+	)
+}
+
+const searchUsersByCreated = `-- name: SearchUsersByCreated :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (? = '' OR status = ?)
+  AND email LIKE ?
+  AND CONCAT_WS(' ', first_name, last_name) LIKE ?
+  AND date_created >= ? AND date_created < ?
+  AND (? = 0 OR date_created > ? OR (date_created = ? AND id > ?))
+ORDER BY date_created, id
+LIMIT ?
+`
+
+type SearchUsersByCreatedParams struct {
+	Status       sql.NullString
+	EmailPattern string
+	NamePattern  string
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	AfterID      int32
+	AfterCreated time.Time
+	RowLimit     int32
+}
+
+type SearchUsersByCreatedRow struct {
+	ID          int32
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	Email       string
+	DateCreated time.Time
+	Status      sql.NullString
+}
+
+func (q *Queries) SearchUsersByCreated(ctx context.Context, arg SearchUsersByCreatedParams) ([]SearchUsersByCreatedRow, error) {
+	rows, err := q.query(ctx, q.searchUsersByCreatedStmt, searchUsersByCreated,
+		arg.Status,
+		arg.Status,
+		arg.EmailPattern,
+		arg.NamePattern,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.AfterID,
+		arg.AfterCreated,
+		arg.AfterCreated,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersByCreatedRow
+	for rows.Next() {
+		var i SearchUsersByCreatedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.DateCreated,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsersByCreatedDesc = `-- name: SearchUsersByCreatedDesc :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (? = '' OR status = ?)
+  AND email LIKE ?
+  AND CONCAT_WS(' ', first_name, last_name) LIKE ?
+  AND date_created >= ? AND date_created < ?
+  AND (? = 0 OR date_created < ? OR (date_created = ? AND id < ?))
+ORDER BY date_created DESC, id DESC
+LIMIT ?
+`
+
+type SearchUsersByCreatedDescParams struct {
+	Status       sql.NullString
+	EmailPattern string
+	NamePattern  string
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	AfterID      int32
+	AfterCreated time.Time
+	RowLimit     int32
+}
+
+type SearchUsersByCreatedDescRow struct {
+	ID          int32
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	Email       string
+	DateCreated time.Time
+	Status      sql.NullString
+}
+
+func (q *Queries) SearchUsersByCreatedDesc(ctx context.Context, arg SearchUsersByCreatedDescParams) ([]SearchUsersByCreatedDescRow, error) {
+	rows, err := q.query(ctx, q.searchUsersByCreatedDescStmt, searchUsersByCreatedDesc,
+		arg.Status,
+		arg.Status,
+		arg.EmailPattern,
+		arg.NamePattern,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.AfterID,
+		arg.AfterCreated,
+		arg.AfterCreated,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersByCreatedDescRow
+	for rows.Next() {
+		var i SearchUsersByCreatedDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.DateCreated,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsersByEmail = `-- name: SearchUsersByEmail :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (? = '' OR status = ?)
+  AND email LIKE ?
+  AND CONCAT_WS(' ', first_name, last_name) LIKE ?
+  AND date_created >= ? AND date_created < ?
+  AND (? = 0 OR email > ? OR (email = ? AND id > ?))
+ORDER BY email, id
+LIMIT ?
+`
+
+type SearchUsersByEmailParams struct {
+	Status       sql.NullString
+	EmailPattern string
+	NamePattern  string
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	AfterID      int32
+	AfterEmail   string
+	RowLimit     int32
+}
+
+type SearchUsersByEmailRow struct {
+	ID          int32
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	Email       string
+	DateCreated time.Time
+	Status      sql.NullString
+}
+
+func (q *Queries) SearchUsersByEmail(ctx context.Context, arg SearchUsersByEmailParams) ([]SearchUsersByEmailRow, error) {
+	rows, err := q.query(ctx, q.searchUsersByEmailStmt, searchUsersByEmail,
+		arg.Status,
+		arg.Status,
+		arg.EmailPattern,
+		arg.NamePattern,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.AfterID,
+		arg.AfterEmail,
+		arg.AfterEmail,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersByEmailRow
+	for rows.Next() {
+		var i SearchUsersByEmailRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.DateCreated,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsersByEmailDesc = `-- name: SearchUsersByEmailDesc :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (? = '' OR status = ?)
+  AND email LIKE ?
+  AND CONCAT_WS(' ', first_name, last_name) LIKE ?
+  AND date_created >= ? AND date_created < ?
+  AND (? = 0 OR email < ? OR (email = ? AND id < ?))
+ORDER BY email DESC, id DESC
+LIMIT ?
+`
+
+type SearchUsersByEmailDescParams struct {
+	Status       sql.NullString
+	EmailPattern string
+	NamePattern  string
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	AfterID      int32
+	AfterEmail   string
+	RowLimit     int32
+}
+
+type SearchUsersByEmailDescRow struct {
+	ID          int32
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	Email       string
+	DateCreated time.Time
+	Status      sql.NullString
+}
+
+func (q *Queries) SearchUsersByEmailDesc(ctx context.Context, arg SearchUsersByEmailDescParams) ([]SearchUsersByEmailDescRow, error) {
+	rows, err := q.query(ctx, q.searchUsersByEmailDescStmt, searchUsersByEmailDesc,
+		arg.Status,
+		arg.Status,
+		arg.EmailPattern,
+		arg.NamePattern,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.AfterID,
+		arg.AfterEmail,
+		arg.AfterEmail,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersByEmailDescRow
+	for rows.Next() {
+		var i SearchUsersByEmailDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.DateCreated,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePassword = `-- name: UpdatePassword :execresult
+UPDATE users SET password=? WHERE id=?
+`
+
+type UpdatePasswordParams struct {
+	Password sql.NullString
+	ID       int32
+}
+
+func (q *Queries) UpdatePassword(ctx context.Context, arg UpdatePasswordParams) (sql.Result, error) {
+	return q.exec(ctx, q.updatePasswordStmt, updatePassword, arg.Password, arg.ID)
+}
+
+const updateStatus = `-- name: UpdateStatus :execresult
+UPDATE users SET status=? WHERE id=?
+`
+
+type UpdateStatusParams struct {
+	Status sql.NullString
+	ID     int32
+}
+
+func (q *Queries) UpdateStatus(ctx context.Context, arg UpdateStatusParams) (sql.Result, error) {
+	return q.exec(ctx, q.updateStatusStmt, updateStatus, arg.Status, arg.ID)
+}
+
 const updateUser = `-- name: UpdateUser :execresult
 UPDATE users SET first_name=?,last_name=?,email=? WHERE id = ?
 `
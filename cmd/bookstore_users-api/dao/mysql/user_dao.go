@@ -3,14 +3,31 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/user_dao"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+	"github.com/go-sql-driver/mysql"
 )
 
+// errDuplicateEntry is the mysql error number of a unique key violation
+const errDuplicateEntry = 1062
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
+
+// errEmailTaken does not tell whether the email is pending or active
+func errEmailTaken() rest_errors.RestErr {
+	return rest_errors.NewConflictError("user can not be registered with this email")
+}
+
 var _ user_dao.UserDaoIntf = (*UserDao)(nil)
 
 type UserDao struct {
@@ -41,6 +58,9 @@ func (d *UserDao) Get(id int64) (*gen.FindUserRow, rest_errors.RestErr) {
 func (d *UserDao) Save(u gen.InsertUserParams) (int64, rest_errors.RestErr) {
 	result, err := d.dbq.InsertUser(context.Background(), u)
 	if err != nil {
+		if isDuplicate(err) {
+			return -1, errEmailTaken()
+		}
 		logger.Error("save user", err)
 		return -1, rest_errors.NewInternalServerError("db error", err)
 	}
@@ -53,6 +73,50 @@ func (d *UserDao) Save(u gen.InsertUserParams) (int64, rest_errors.RestErr) {
 	return userId, nil
 }
 
+// ReplacePendingUser registers again the pending user of the email, with new names and password.
+// An active or unknown email is taken
+func (d *UserDao) ReplacePendingUser(u gen.InsertUserParams) (int64, rest_errors.RestErr) {
+	ctx := context.Background()
+	tx, err := d.SqlClient.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("replace pending user", err)
+		return -1, rest_errors.NewInternalServerError("db error", err)
+	}
+	defer tx.Rollback()
+
+	q := d.dbq.WithTx(tx)
+	pending := nillableStr(models.STATUS_PENDING)
+	found, err := q.FindByEmail(ctx, gen.FindByEmailParams{Email: u.Email, Status: pending})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, errEmailTaken()
+		}
+		logger.Error("replace pending user", err)
+		return -1, rest_errors.NewInternalServerError("db error", err)
+	}
+	result, err := q.ReplacePendingUser(ctx, gen.ReplacePendingUserParams{
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		DateCreated: u.DateCreated,
+		Password:    u.Password,
+		ID:          found.ID,
+		Status:      pending,
+	})
+	if err != nil {
+		logger.Error("replace pending user", err)
+		return -1, rest_errors.NewInternalServerError("db error", err)
+	}
+	// the user was activated in between
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return -1, errEmailTaken()
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("replace pending user", err)
+		return -1, rest_errors.NewInternalServerError("db error", err)
+	}
+	return int64(found.ID), nil
+}
+
 func (d *UserDao) Update(u gen.UpdateUserParams) rest_errors.RestErr {
 	_, err := d.dbq.UpdateUser(context.Background(), u)
 	if err != nil {
@@ -90,11 +154,228 @@ func (d *UserDao) FindByStatus(status string) ([]gen.FindByStatusRow, rest_error
 	return result, nil
 }
 
-func (d *UserDao) FindByEmailAndPsw(arg gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr) {
-	result, err := d.dbq.FindByEMailAndPsw(context.Background(), arg)
+func (d *UserDao) FindByEmail(arg gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+	result, err := d.dbq.FindByEmail(context.Background(), arg)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return result, rest_errors.NewNotFoundError("user not found")
+		}
 		logger.Error("find user", err)
 		return result, rest_errors.NewInternalServerError("find user error", err)
 	}
 	return result, nil
 }
+
+func (d *UserDao) UpdatePassword(arg gen.UpdatePasswordParams) rest_errors.RestErr {
+	_, err := d.dbq.UpdatePassword(context.Background(), arg)
+	if err != nil {
+		logger.Error("update password", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	return nil
+}
+
+// Search runs the query of the sort order, each order has its own keyset
+func (d *UserDao) Search(p user_dao.SearchParams) ([]user_dao.SearchRow, rest_errors.RestErr) {
+	var result []user_dao.SearchRow
+	var err error
+	ctx := context.Background()
+
+	switch p.Sort {
+	case models.SortCreatedDesc:
+		var rows []gen.SearchUsersByCreatedDescRow
+		rows, err = d.dbq.SearchUsersByCreatedDesc(ctx, gen.SearchUsersByCreatedDescParams{
+			Status: nillableStr(p.Status), EmailPattern: p.EmailPattern, NamePattern: p.NamePattern,
+			CreatedFrom: p.CreatedFrom, CreatedTo: p.CreatedTo,
+			AfterID: int32(p.AfterID), AfterCreated: p.AfterCreated, RowLimit: int32(p.Limit),
+		})
+		for _, row := range rows {
+			result = append(result, user_dao.SearchRow(row))
+		}
+	case models.SortEmail:
+		var rows []gen.SearchUsersByEmailRow
+		rows, err = d.dbq.SearchUsersByEmail(ctx, gen.SearchUsersByEmailParams{
+			Status: nillableStr(p.Status), EmailPattern: p.EmailPattern, NamePattern: p.NamePattern,
+			CreatedFrom: p.CreatedFrom, CreatedTo: p.CreatedTo,
+			AfterID: int32(p.AfterID), AfterEmail: p.AfterEmail, RowLimit: int32(p.Limit),
+		})
+		for _, row := range rows {
+			result = append(result, user_dao.SearchRow(row))
+		}
+	case models.SortEmailDesc:
+		var rows []gen.SearchUsersByEmailDescRow
+		rows, err = d.dbq.SearchUsersByEmailDesc(ctx, gen.SearchUsersByEmailDescParams{
+			Status: nillableStr(p.Status), EmailPattern: p.EmailPattern, NamePattern: p.NamePattern,
+			CreatedFrom: p.CreatedFrom, CreatedTo: p.CreatedTo,
+			AfterID: int32(p.AfterID), AfterEmail: p.AfterEmail, RowLimit: int32(p.Limit),
+		})
+		for _, row := range rows {
+			result = append(result, user_dao.SearchRow(row))
+		}
+	default:
+		var rows []gen.SearchUsersByCreatedRow
+		rows, err = d.dbq.SearchUsersByCreated(ctx, gen.SearchUsersByCreatedParams{
+			Status: nillableStr(p.Status), EmailPattern: p.EmailPattern, NamePattern: p.NamePattern,
+			CreatedFrom: p.CreatedFrom, CreatedTo: p.CreatedTo,
+			AfterID: int32(p.AfterID), AfterCreated: p.AfterCreated, RowLimit: int32(p.Limit),
+		})
+		for _, row := range rows {
+			result = append(result, user_dao.SearchRow(row))
+		}
+	}
+	if err != nil {
+		logger.Error("search users", err)
+		return nil, rest_errors.NewInternalServerError("db error", err)
+	}
+	return result, nil
+}
+
+// CreateVerification replaces the verification tokens of the user
+func (d *UserDao) CreateVerification(arg gen.InsertVerificationParams) rest_errors.RestErr {
+	ctx := context.Background()
+	tx, err := d.SqlClient.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("create verification", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	defer tx.Rollback()
+
+	q := d.dbq.WithTx(tx)
+	if _, err := q.DeleteUserVerifications(ctx, arg.UserID); err != nil {
+		logger.Error("create verification", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	if _, err := q.InsertVerification(ctx, arg); err != nil {
+		logger.Error("create verification", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("create verification", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	return nil
+}
+
+// ActivateUser consumes the verification token and activates its user in one transaction.
+// Unknown, used and expired tokens are a bad request
+func (d *UserDao) ActivateUser(tokenHash string, now time.Time) (int64, rest_errors.RestErr) {
+	ctx := context.Background()
+	tx, err := d.SqlClient.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("activate user", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	defer tx.Rollback()
+
+	q := d.dbq.WithTx(tx)
+	v, err := q.FindVerification(ctx, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errInvalidVerification()
+		}
+		logger.Error("activate user", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	if _, err := q.DeleteVerification(ctx, tokenHash); err != nil {
+		logger.Error("activate user", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	expired := !now.Before(v.ExpiresAt)
+	if !expired {
+		if _, err := q.UpdateStatus(ctx, gen.UpdateStatusParams{
+			Status: nillableStr(models.STATUS_ACTIVE),
+			ID:     v.UserID,
+		}); err != nil {
+			logger.Error("activate user", err)
+			return 0, rest_errors.NewInternalServerError("db error", err)
+		}
+	}
+	// an expired token is removed as well
+	if err := tx.Commit(); err != nil {
+		logger.Error("activate user", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	if expired {
+		return 0, errInvalidVerification()
+	}
+	return int64(v.UserID), nil
+}
+
+func errInvalidVerification() rest_errors.RestErr {
+	return rest_errors.NewBadRequestError("invalid or expired verification token")
+}
+
+// CreatePasswordReset replaces the password reset tokens of the user
+func (d *UserDao) CreatePasswordReset(arg gen.InsertPasswordResetParams) rest_errors.RestErr {
+	ctx := context.Background()
+	tx, err := d.SqlClient.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("create password reset", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	defer tx.Rollback()
+
+	q := d.dbq.WithTx(tx)
+	if _, err := q.DeleteUserPasswordResets(ctx, arg.UserID); err != nil {
+		logger.Error("create password reset", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	if _, err := q.InsertPasswordReset(ctx, arg); err != nil {
+		logger.Error("create password reset", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("create password reset", err)
+		return rest_errors.NewInternalServerError("db error", err)
+	}
+	return nil
+}
+
+// ResetPassword consumes the reset token and stores the password hash of its user in one transaction.
+// Unknown, used and expired tokens are a bad request
+func (d *UserDao) ResetPassword(tokenHash string, password string, now time.Time) (int64, rest_errors.RestErr) {
+	ctx := context.Background()
+	tx, err := d.SqlClient.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("reset password", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	defer tx.Rollback()
+
+	q := d.dbq.WithTx(tx)
+	r, err := q.FindPasswordReset(ctx, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errInvalidPasswordReset()
+		}
+		logger.Error("reset password", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	if _, err := q.DeletePasswordReset(ctx, tokenHash); err != nil {
+		logger.Error("reset password", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	expired := !now.Before(r.ExpiresAt)
+	if !expired {
+		if _, err := q.UpdatePassword(ctx, gen.UpdatePasswordParams{
+			Password: nillableStr(password),
+			ID:       r.UserID,
+		}); err != nil {
+			logger.Error("reset password", err)
+			return 0, rest_errors.NewInternalServerError("db error", err)
+		}
+	}
+	// an expired token is removed as well
+	if err := tx.Commit(); err != nil {
+		logger.Error("reset password", err)
+		return 0, rest_errors.NewInternalServerError("db error", err)
+	}
+	if expired {
+		return 0, errInvalidPasswordReset()
+	}
+	return int64(r.UserID), nil
+}
+
+func errInvalidPasswordReset() rest_errors.RestErr {
+	return rest_errors.NewBadRequestError("invalid or expired password reset token")
+}
@@ -1,6 +1,9 @@
 package user_dao
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
 )
@@ -8,8 +11,40 @@ import (
 type UserDaoIntf interface {
 	Get(int64) (*gen.FindUserRow, rest_errors.RestErr)
 	Save(gen.InsertUserParams) (int64, rest_errors.RestErr)
+	ReplacePendingUser(gen.InsertUserParams) (int64, rest_errors.RestErr)
 	Update(gen.UpdateUserParams) rest_errors.RestErr
 	Delete(userId int64) rest_errors.RestErr
 	FindByStatus(status string) ([]gen.FindByStatusRow, rest_errors.RestErr)
-	FindByEmailAndPsw(gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr)
+	FindByEmail(gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr)
+	UpdatePassword(gen.UpdatePasswordParams) rest_errors.RestErr
+	Search(SearchParams) ([]SearchRow, rest_errors.RestErr)
+	CreateVerification(gen.InsertVerificationParams) rest_errors.RestErr
+	ActivateUser(tokenHash string, now time.Time) (int64, rest_errors.RestErr)
+	CreatePasswordReset(gen.InsertPasswordResetParams) rest_errors.RestErr
+	ResetPassword(tokenHash string, password string, now time.Time) (int64, rest_errors.RestErr)
+}
+
+// SearchParams filters a page of users, the patterns are LIKE patterns.
+// The page starts after the row AfterID and AfterCreated or AfterEmail, AfterID 0 is the first page
+type SearchParams struct {
+	Status       string
+	EmailPattern string
+	NamePattern  string
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	Sort         string
+	AfterID      int64
+	AfterCreated time.Time
+	AfterEmail   string
+	Limit        int
+}
+
+// SearchRow is a user found by Search, the password hash is not read
+type SearchRow struct {
+	ID          int32
+	FirstName   sql.NullString
+	LastName    sql.NullString
+	Email       string
+	DateCreated time.Time
+	Status      sql.NullString
 }
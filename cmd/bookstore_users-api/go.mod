@@ -10,4 +10,10 @@ require (
 	github.com/google/wire v0.5.0 // indirect
 	github.com/ilyakaznacheev/cleanenv v1.2.5
 	github.com/stretchr/testify v1.7.0 // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+)
+
+replace (
+	github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go => ../../pkg/bookstore-oauth-go
+	github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go => ../../pkg/bookstore_utils_go
 )
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"io/ioutil"
+	"net/smtp"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	text, err := format("no-reply@bookstore.local", Message{To: "jo@x.com", Subject: "Vérification", Body: "hello"}, date)
+	assert.Nil(t, err)
+	assert.Equal(t, "From: no-reply@bookstore.local\r\n"+
+		"To: jo@x.com\r\n"+
+		"Subject: =?utf-8?q?V=C3=A9rification?=\r\n"+
+		"Date: Tue, 01 Jun 2021 10:00:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"hello\r\n", string(text))
+
+	_, err = format("from", Message{To: "jo@x.com\r\nBcc: all@x.com", Subject: "s"}, date)
+	assert.Equal(t, errInvalidHeader, err)
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mails", "mails.log")
+	m, err := NewFileMailer(path, "from@x.com")
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.Send(Message{To: "a@x.com", Subject: "first", Body: "1"}))
+	assert.Nil(t, m.Send(Message{To: "b@x.com", Subject: "second", Body: "2"}))
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "From: from@x.com"))
+	assert.Contains(t, string(content), "To: b@x.com")
+}
+
+func TestSmtpMailer(t *testing.T) {
+	m := NewSmtpMailer("smtp.x.com", "587", "user", "psw", "from@x.com").(*smtpMailer)
+	var addr string
+	var to []string
+	m.send = func(a string, auth smtp.Auth, from string, rcpt []string, msg []byte) error {
+		addr, to = a, rcpt
+		assert.NotNil(t, auth)
+		assert.Equal(t, "from@x.com", from)
+		return nil
+	}
+
+	assert.Nil(t, m.Send(Message{To: "a@x.com", Subject: "s", Body: "b"}))
+	assert.Equal(t, "smtp.x.com:587", addr)
+	assert.Equal(t, []string{"a@x.com"}, to)
+
+	assert.Nil(t, NewSmtpMailer("localhost", "25", "", "", "from@x.com").(*smtpMailer).auth)
+}
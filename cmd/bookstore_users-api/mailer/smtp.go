@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// smtpMailer sends through a submission server, STARTTLS is used when the server offers it
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSmtpMailer authenticates with PLAIN when the username is set
+func NewSmtpMailer(host string, port string, username string, password string, from string) Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+		send: smtp.SendMail,
+	}
+}
+
+func (m *smtpMailer) Send(msg Message) error {
+	text, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return m.send(m.addr, m.auth, m.from, []string{msg.To}, text)
+}
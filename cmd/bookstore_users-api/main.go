@@ -13,7 +13,7 @@ type Args struct {
 	ConfigPath string
 }
 
-func ProcessArgs(conf conf.Config) Args {
+func ProcessArgs(conf conf.Config, arguments []string) Args {
 	var args Args
 
 	flags := flag.NewFlagSet("bookstore users api", 1)
@@ -26,12 +26,16 @@ func ProcessArgs(conf conf.Config) Args {
 		fmt.Fprintln(flags.Output())
 		fmt.Fprintln(flags.Output(), help)
 	}
-	flags.Parse(os.Args[1:])
+	flags.Parse(arguments)
 	return args
 }
 
 func main() {
-	args := ProcessArgs(conf.Config{})
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	args := ProcessArgs(conf.Config{}, os.Args[1:])
 	conf, err := conf.LoadConfigFromFile(args.ConfigPath)
 
 	if err != nil {
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/conf"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/sql/migrations"
+)
+
+const migrateUsage = `usage : migrate [-c conf/app.yml] up | down [-steps 1] | status`
+
+// runMigrate applies, reverts or lists the schema migrations and returns the exit code
+func runMigrate(arguments []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := flags.String("c", "conf/app.yml", "Path to config file")
+	steps := flags.Int("steps", 1, "migrations reverted by down")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(arguments)
+	command := flags.Arg(0)
+	if flags.NArg() > 1 {
+		flags.Parse(flags.Args()[1:])
+	}
+	if command != "up" && command != "down" && command != "status" {
+		flags.Usage()
+		return 2
+	}
+
+	appConf, err := conf.LoadConfigFromFile(*configPath)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	ls, err := migrations.Embedded()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	db := mysql.NewSqlClient(mysql.MakeConfig(appConf))
+	defer db.Close()
+	migrator := migrations.NewMigrator(db, ls)
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		for _, st := range status {
+			state := "pending"
+			switch {
+			case st.Dirty:
+				state = "dirty"
+			case st.Applied:
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+	}
+	return 0
+}
@@ -54,6 +54,22 @@ func (_m *UserService) DeleteUser(_a0 int64) rest_errors.RestErr {
 	return r0
 }
 
+// ForgotPassword provides a mock function with given fields: _a0
+func (_m *UserService) ForgotPassword(_a0 string) rest_errors.RestErr {
+	ret := _m.Called(_a0)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(string) rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
 // GetUser provides a mock function with given fields: _a0
 func (_m *UserService) GetUser(_a0 int64) (*models.User, rest_errors.RestErr) {
 	ret := _m.Called(_a0)
@@ -104,21 +120,53 @@ func (_m *UserService) LoginUser(_a0 models.LoginRequest) (*models.User, rest_er
 	return r0, r1
 }
 
-// SearchUsersByStatus provides a mock function with given fields: _a0
-func (_m *UserService) SearchUsersByStatus(_a0 string) ([]models.User, rest_errors.RestErr) {
+// ResendVerification provides a mock function with given fields: _a0
+func (_m *UserService) ResendVerification(_a0 string) rest_errors.RestErr {
+	ret := _m.Called(_a0)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(string) rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: _a0
+func (_m *UserService) ResetPassword(_a0 models.PasswordResetRequest) rest_errors.RestErr {
 	ret := _m.Called(_a0)
 
-	var r0 []models.User
-	if rf, ok := ret.Get(0).(func(string) []models.User); ok {
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(models.PasswordResetRequest) rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
+
+// SearchUsers provides a mock function with given fields: _a0
+func (_m *UserService) SearchUsers(_a0 models.UserSearch) (*models.UserPage, rest_errors.RestErr) {
+	ret := _m.Called(_a0)
+
+	var r0 *models.UserPage
+	if rf, ok := ret.Get(0).(func(models.UserSearch) *models.UserPage); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.User)
+			r0 = ret.Get(0).(*models.UserPage)
 		}
 	}
 
 	var r1 rest_errors.RestErr
-	if rf, ok := ret.Get(1).(func(string) rest_errors.RestErr); ok {
+	if rf, ok := ret.Get(1).(func(models.UserSearch) rest_errors.RestErr); ok {
 		r1 = rf(_a0)
 	} else {
 		if ret.Get(1) != nil {
@@ -153,3 +201,19 @@ func (_m *UserService) UpdateUser(_a0 bool, _a1 models.User) (*models.User, rest
 
 	return r0, r1
 }
+
+// VerifyUser provides a mock function with given fields: _a0
+func (_m *UserService) VerifyUser(_a0 string) rest_errors.RestErr {
+	ret := _m.Called(_a0)
+
+	var r0 rest_errors.RestErr
+	if rf, ok := ret.Get(0).(func(string) rest_errors.RestErr); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rest_errors.RestErr)
+		}
+	}
+
+	return r0
+}
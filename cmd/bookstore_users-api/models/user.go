@@ -8,7 +8,8 @@ import (
 )
 
 const (
-	STATUS_ACTIVE = "active"
+	STATUS_ACTIVE  = "active"
+	STATUS_PENDING = "pending"
 )
 
 type User struct {
@@ -27,7 +28,7 @@ func (user *User) Validate() rest_errors.RestErr {
 
 	user.FirstName = strings.TrimSpace(user.FirstName)
 	user.LastName = strings.TrimSpace(user.LastName)
-	user.Email = strings.Trim(strings.ToLower(user.Email), "")
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
 
 	if user.Email == "" {
 		return rest_errors.NewBadRequestError("invalid email")
@@ -82,3 +83,16 @@ type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+type VerificationRequest struct {
+	Token string `json:"token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
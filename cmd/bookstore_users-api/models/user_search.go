@@ -0,0 +1,47 @@
+package models
+
+const (
+	SortCreated     = "date_created"
+	SortCreatedDesc = "-date_created"
+	SortEmail       = "email"
+	SortEmailDesc   = "-email"
+
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// UserSearch filters users : status is exact, email is a prefix, name is a substring of the first and last name.
+// created_from and created_to are dates (2006-01-02) or RFC 3339 times, a date includes the whole day.
+// cursor is the next_cursor of the previous page, sent with the same filters and sort
+type UserSearch struct {
+	Status      string `form:"status"`
+	EmailPrefix string `form:"email"`
+	Name        string `form:"name"`
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	Sort        string `form:"sort"`
+	Limit       int    `form:"limit"`
+	Cursor      string `form:"cursor"`
+}
+
+// UserPage is a page of users, NextCursor is empty on the last page
+type UserPage struct {
+	Users      Users
+	NextCursor string
+}
+
+type userPageJson struct {
+	Results    []interface{} `json:"results"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (p *UserPage) Marshall(isPublic bool) interface{} {
+	results := p.Users.Marshall(isPublic)
+	if results == nil {
+		results = []interface{}{}
+	}
+	return userPageJson{
+		Results:    results,
+		NextCursor: p.NextCursor,
+	}
+}
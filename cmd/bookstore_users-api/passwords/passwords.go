@@ -0,0 +1,188 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Stored passwords are self describing, the scheme and its parameters are part of the value :
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>   argon2id in the PHC string format
+//	$2a$12$...                                      bcrypt
+//	anything else                                   plaintext of the users stored before hashing
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+type Params struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Memory  uint32 // KiB
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+type Hasher struct {
+	params Params
+	// hash of a random password with the params
+	dummy string
+}
+
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case Argon2id:
+		if params.Argon2Memory == 0 || params.Argon2Time == 0 || params.Argon2Threads == 0 {
+			return nil, errors.New("argon2id memory, time and threads are required")
+		}
+	case Bcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost %d out of [%d, %d]", params.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	h := &Hasher{params: params}
+	password := make([]byte, saltLength)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+	dummy, err := h.Hash(base64.RawStdEncoding.EncodeToString(password))
+	if err != nil {
+		return nil, err
+	}
+	h.dummy = dummy
+	return h, nil
+}
+
+// Dummy returns a hash no password matches. Verifying against it takes as long as a real check,
+// a caller without a stored hash spends the same time as with one
+func (h *Hasher) Dummy() string {
+	return h.dummy
+}
+
+// Hash returns the salted hash of the password with the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := argon2Params{memory: h.params.Argon2Memory, time: h.params.Argon2Time, threads: h.params.Argon2Threads}
+	return p.encode(salt, p.key([]byte(password), salt, keyLength)), nil
+}
+
+// Verify compares the password with the stored one.
+// rehash reports a matching password stored as plaintext, with another algorithm or weaker parameters,
+// the caller replaces it with a fresh Hash
+func (h *Hasher) Verify(stored string, password string) (ok bool, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(stored, "$"+Argon2id+"$"):
+		p, salt, key, err := decodeArgon2(stored)
+		if err != nil {
+			return false, false, err
+		}
+		ok = subtle.ConstantTimeCompare(key, p.key([]byte(password), salt, uint32(len(key)))) == 1
+		return ok, ok && h.weaker(Argon2id, p), nil
+
+	case isBcrypt(stored):
+		cost, err := bcrypt.Cost([]byte(stored))
+		if err != nil {
+			return false, false, ErrMalformedHash
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+			if err == bcrypt.ErrMismatchedHashAndPassword {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		return true, h.weaker(Bcrypt, cost), nil
+	}
+
+	ok = stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	return ok, ok, nil
+}
+
+// weaker tells whether a stored hash is below the configured algorithm and parameters
+func (h *Hasher) weaker(algorithm string, params interface{}) bool {
+	if algorithm != h.params.Algorithm {
+		return true
+	}
+	switch p := params.(type) {
+	case argon2Params:
+		return p.memory < h.params.Argon2Memory || p.time < h.params.Argon2Time || p.threads < h.params.Argon2Threads
+	case int:
+		return p < h.params.BcryptCost
+	}
+	return false
+}
+
+func isBcrypt(stored string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func (p argon2Params) key(password []byte, salt []byte, length uint32) []byte {
+	return argon2.IDKey(password, salt, p.time, p.memory, p.threads, length)
+}
+
+func (p argon2Params) encode(salt []byte, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, argon2.Version,
+		p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2(stored string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,106 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func argon2Hasher(t *testing.T, memory uint32) *Hasher {
+	h, err := NewHasher(Params{Algorithm: Argon2id, Argon2Memory: memory, Argon2Time: 1, Argon2Threads: 1})
+	assert.Nil(t, err)
+	return h
+}
+
+func TestNewHasherInvalid(t *testing.T) {
+	_, err := NewHasher(Params{Algorithm: "md5"})
+	assert.Equal(t, ErrUnknownAlgorithm, err)
+
+	_, err = NewHasher(Params{Algorithm: Bcrypt, BcryptCost: 1})
+	assert.NotNil(t, err)
+
+	_, err = NewHasher(Params{Algorithm: Argon2id})
+	assert.NotNil(t, err)
+}
+
+func TestArgon2id(t *testing.T) {
+	h := argon2Hasher(t, 1024)
+
+	stored, err := h.Hash("secret")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(stored, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	again, _ := h.Hash("secret")
+	assert.NotEqual(t, stored, again, "salted")
+
+	ok, rehash, err := h.Verify(stored, "secret")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify(stored, "Secret")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, _, err = h.Verify("$argon2id$v=19$m=1024$x$y", "secret")
+	assert.Equal(t, ErrMalformedHash, err)
+}
+
+func TestBcrypt(t *testing.T) {
+	h, err := NewHasher(Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost})
+	assert.Nil(t, err)
+
+	stored, err := h.Hash("secret")
+	assert.Nil(t, err)
+
+	ok, rehash, err := h.Verify(stored, "secret")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify(stored, "other")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyRehash(t *testing.T) {
+	h := argon2Hasher(t, 2048)
+
+	ok, rehash, err := h.Verify("secret", "secret")
+	assert.Nil(t, err)
+	assert.True(t, ok, "plaintext")
+	assert.True(t, rehash)
+
+	ok, rehash, _ = h.Verify("secret", "other")
+	assert.False(t, ok)
+	assert.False(t, rehash, "only matching passwords are rehashed")
+
+	ok, _, _ = h.Verify("", "")
+	assert.False(t, ok, "missing password")
+
+	weak, _ := argon2Hasher(t, 1024).Hash("secret")
+	ok, rehash, _ = h.Verify(weak, "secret")
+	assert.True(t, ok)
+	assert.True(t, rehash, "weaker parameters")
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	ok, rehash, _ = h.Verify(string(bcryptHash), "secret")
+	assert.True(t, ok)
+	assert.True(t, rehash, "other algorithm")
+}
+
+func TestDummy(t *testing.T) {
+	h := argon2Hasher(t, 1024)
+	assert.True(t, strings.HasPrefix(h.Dummy(), "$argon2id$v=19$m=1024,t=1,p=1$"))
+	ok, rehash, err := h.Verify(h.Dummy(), "")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	b, _ := NewHasher(Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost})
+	cost, err := bcrypt.Cost([]byte(b.Dummy()))
+	assert.Nil(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+}
@@ -0,0 +1,91 @@
+package user_services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/mailer"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// PasswordReset configures the password reset, Link is followed by the token in the mail
+type PasswordReset struct {
+	TTL  time.Duration
+	Link string
+}
+
+// ForgotPassword mails a reset token to an active user.
+// The answer is the same for unknown, pending and active emails, the mail is sent in the background
+func (s *UsersService) ForgotPassword(email string) rest_errors.RestErr {
+	result, err := s.userDao.FindByEmail(gen.FindByEmailParams{
+		Email:  strings.ToLower(strings.TrimSpace(email)),
+		Status: nillableStr(models.STATUS_ACTIVE),
+	})
+	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	s.async(func() { s.sendPasswordReset(int64(result.ID), result.Email) })
+	return nil
+}
+
+// ResetPassword consumes the reset token, stores the new password and ends the sessions of the user
+func (s *UsersService) ResetPassword(rq models.PasswordResetRequest) rest_errors.RestErr {
+	token := strings.TrimSpace(rq.Token)
+	if token == "" {
+		return rest_errors.NewBadRequestError("empty password reset token")
+	}
+	password := strings.TrimSpace(rq.Password)
+	if password == "" {
+		return rest_errors.NewBadRequestError("empty password")
+	}
+
+	hash, hashErr := s.hasher.Hash(password)
+	if hashErr != nil {
+		return rest_errors.NewInternalServerError("hash password error", hashErr)
+	}
+	userId, err := s.userDao.ResetPassword(hashToken(token), hash, s.now())
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokens.RevokeUserTokens(userId); err != nil {
+		logger.Error(fmt.Sprintf("revoke the tokens of user %d", userId), err)
+		return rest_errors.NewInternalServerError("password changed, the sessions could not be ended", err)
+	}
+	return nil
+}
+
+// sendPasswordReset stores a new token and mails it, failures are logged: the user asks for another one
+func (s *UsersService) sendPasswordReset(userId int64, email string) {
+	token, hash, err := newToken()
+	if err != nil {
+		logger.Error("password reset token", err)
+		return
+	}
+	expires := s.now().Add(s.reset.TTL)
+	if err := s.userDao.CreatePasswordReset(gen.InsertPasswordResetParams{
+		TokenHash: hash,
+		UserID:    int32(userId),
+		ExpiresAt: expires,
+	}); err != nil {
+		return
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Reset your bookstore password",
+		Body: fmt.Sprintf("Open %s%s to choose a new password.\r\nThe link expires at %s, ignore this mail if you didn't ask for it.",
+			s.reset.Link, token, expires.UTC().Format(time.RFC1123)),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		logger.Error("send password reset mail", err)
+	}
+}
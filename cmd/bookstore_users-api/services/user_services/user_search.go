@@ -0,0 +1,152 @@
+package user_services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/user_dao"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/date_utils"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	// the datetime range of mysql
+	minCreated = time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxCreated = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// searchCursor is the sort key of the last user of a page
+type searchCursor struct {
+	Sort    string    `json:"s"`
+	ID      int64     `json:"id"`
+	Created time.Time `json:"c,omitempty"`
+	Email   string    `json:"e,omitempty"`
+}
+
+// SearchUsers returns a page of users, pages are read with the cursor of the previous one (keyset pagination)
+func (s *UsersService) SearchUsers(q models.UserSearch) (*models.UserPage, rest_errors.RestErr) {
+	params, err := searchParams(q)
+	if err != nil {
+		return nil, err
+	}
+	limit := params.Limit
+	// one more row tells whether a next page exists
+	params.Limit++
+
+	result, err := s.userDao.Search(params)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.UserPage{Users: make(models.Users, 0, len(result))}
+	if len(result) > limit {
+		result = result[:limit]
+		last := result[limit-1]
+		page.NextCursor = encodeCursor(searchCursor{
+			Sort:    params.Sort,
+			ID:      int64(last.ID),
+			Created: last.DateCreated,
+			Email:   last.Email,
+		})
+	}
+	for _, rec := range result {
+		page.Users = append(page.Users, models.User{
+			Id:          int64(rec.ID),
+			FirstName:   rec.FirstName.String,
+			LastName:    rec.LastName.String,
+			Email:       rec.Email,
+			DateCreated: date_utils.Time2String(rec.DateCreated),
+			Status:      rec.Status.String,
+		})
+	}
+	return page, nil
+}
+
+func searchParams(q models.UserSearch) (user_dao.SearchParams, rest_errors.RestErr) {
+	p := user_dao.SearchParams{
+		Status:       strings.TrimSpace(q.Status),
+		EmailPattern: likeEscaper.Replace(strings.ToLower(strings.TrimSpace(q.EmailPrefix))) + "%",
+		NamePattern:  "%" + likeEscaper.Replace(strings.TrimSpace(q.Name)) + "%",
+		CreatedFrom:  minCreated,
+		CreatedTo:    maxCreated,
+		Sort:         q.Sort,
+		Limit:        q.Limit,
+	}
+
+	switch p.Sort {
+	case "":
+		p.Sort = models.SortCreated
+	case models.SortCreated, models.SortCreatedDesc, models.SortEmail, models.SortEmailDesc:
+	default:
+		return p, rest_errors.NewBadRequestError("invalid sort, expected date_created, -date_created, email or -email")
+	}
+
+	if p.Limit == 0 {
+		p.Limit = models.DefaultSearchLimit
+	}
+	if p.Limit < 0 || p.Limit > models.MaxSearchLimit {
+		return p, rest_errors.NewBadRequestError("invalid limit, expected 1 to 100")
+	}
+
+	var err rest_errors.RestErr
+	if q.CreatedFrom != "" {
+		if p.CreatedFrom, _, err = parseCreated("created_from", q.CreatedFrom); err != nil {
+			return p, err
+		}
+	}
+	if q.CreatedTo != "" {
+		var isDate bool
+		if p.CreatedTo, isDate, err = parseCreated("created_to", q.CreatedTo); err != nil {
+			return p, err
+		}
+		if isDate {
+			p.CreatedTo = p.CreatedTo.AddDate(0, 0, 1)
+		} else {
+			p.CreatedTo = p.CreatedTo.Add(time.Second)
+		}
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return p, err
+		}
+		if c.Sort != p.Sort {
+			return p, rest_errors.NewBadRequestError("cursor belongs to another sort")
+		}
+		p.AfterID, p.AfterCreated, p.AfterEmail = c.ID, c.Created, c.Email
+	}
+	return p, nil
+}
+
+// parseCreated reads a date or a RFC 3339 time, isDate reports a date
+func parseCreated(name string, value string) (time.Time, bool, rest_errors.RestErr) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	return time.Time{}, false, rest_errors.NewBadRequestError("invalid " + name + ", expected 2006-01-02 or RFC 3339")
+}
+
+func encodeCursor(c searchCursor) string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(cursor string) (searchCursor, rest_errors.RestErr) {
+	var c searchCursor
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(js, &c) != nil || c.ID <= 0 {
+		return c, rest_errors.NewBadRequestError("invalid cursor")
+	}
+	return c, nil
+}
@@ -2,12 +2,18 @@ package user_services
 
 import (
 	"database/sql"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/user_dao"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/mailer"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/passwords"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore-oauth-go/oauth"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/date_utils"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
 )
 
@@ -16,12 +22,28 @@ var (
 )
 
 type UsersService struct {
-	userDao user_dao.UserDaoIntf
+	userDao      user_dao.UserDaoIntf
+	hasher       *passwords.Hasher
+	mailer       mailer.Mailer
+	verification Verification
+	reset        PasswordReset
+	tokens       oauth.TokenRevoker
+	now          func() time.Time
+	// async runs the work done for a known email only, the answer returns as fast as for an unknown one
+	async func(func())
 }
 
-func NewService(userDao user_dao.UserDaoIntf) *UsersService {
+func NewService(userDao user_dao.UserDaoIntf, hasher *passwords.Hasher, mailer mailer.Mailer,
+	verification Verification, reset PasswordReset, tokens oauth.TokenRevoker) *UsersService {
 	return &UsersService{
-		userDao: userDao,
+		userDao:      userDao,
+		hasher:       hasher,
+		mailer:       mailer,
+		verification: verification,
+		reset:        reset,
+		tokens:       tokens,
+		now:          time.Now,
+		async:        func(f func()) { go f() },
 	}
 }
 
@@ -40,26 +62,39 @@ func (s *UsersService) GetUser(userId int64) (*models.User, rest_errors.RestErr)
 	}, nil
 }
 
+// CreateUser stores a pending user and mails the verification token, the user is active once verified
 func (s *UsersService) CreateUser(u models.User) (*models.User, rest_errors.RestErr) {
 	if err := u.Validate(); err != nil {
 		return nil, err
 	}
+	u.Status = models.STATUS_PENDING
+
+	hash, hashErr := s.hasher.Hash(u.Password)
+	if hashErr != nil {
+		return nil, rest_errors.NewInternalServerError("hash password error", hashErr)
+	}
 
 	insertUser := gen.InsertUserParams{
 		FirstName:   nillableStr(u.FirstName),
 		LastName:    nillableStr(u.LastName),
 		Email:       u.Email,
-		DateCreated: time.Now(),
+		DateCreated: s.now(),
 		Status:      nillableStr(u.Status),
-		Password:    nillableStr(u.Password),
+		Password:    nillableStr(hash),
 	}
 
 	userId, err := s.userDao.Save(insertUser)
+	if err != nil && err.Status() == http.StatusConflict {
+		// a pending email is registered again, its old password and token are replaced
+		userId, err = s.userDao.ReplacePendingUser(insertUser)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	u.Id = userId
+	u.Password = ""
+	s.async(func() { s.sendVerification(userId, u.Email) })
 	return &u, nil
 }
 
@@ -106,38 +141,36 @@ func (s *UsersService) DeleteUser(userId int64) rest_errors.RestErr {
 	return s.userDao.Delete(userId)
 }
 
-func (s *UsersService) SearchUsersByStatus(status string) ([]models.User, rest_errors.RestErr) {
-	result, err := s.userDao.FindByStatus(status)
-	if err != nil {
-		return nil, err
-	}
-	ls := make([]models.User, 0, len(result))
-	for _, rec := range result {
-		u := models.User{
-			Id:        int64(rec.ID),
-			FirstName: rec.FirstName.String,
-			LastName:  rec.LastName.String,
-			Email:     rec.Email,
-			//	DateCreated: date_utils.rec.DateCreated,  // TODO
-			Status: rec.Status.String,
-		}
-		ls = append(ls, u)
-	}
-	return ls, nil
-}
-
+// LoginUser checks the password against the stored hash. Passwords stored as plaintext,
+// with another algorithm or weaker parameters are hashed again after a successful login
 func (s *UsersService) LoginUser(rq models.LoginRequest) (*models.User, rest_errors.RestErr) {
-	input := gen.FindByEMailAndPswParams{
-		Email:    rq.Email,
-		Password: nillableStr(rq.Password),
-		Status:   nillableStr(models.STATUS_ACTIVE),
+	input := gen.FindByEmailParams{
+		Email:  strings.ToLower(strings.TrimSpace(rq.Email)),
+		Status: nillableStr(models.STATUS_ACTIVE),
 	}
 
-	result, err := s.userDao.FindByEmailAndPsw(input)
+	result, err := s.userDao.FindByEmail(input)
 	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			// an unknown email takes as long as a wrong password
+			s.hasher.Verify(s.hasher.Dummy(), rq.Password)
+			return nil, errInvalidCredentials()
+		}
 		return nil, err
 	}
 
+	ok, rehash, verifyErr := s.hasher.Verify(result.Password.String, rq.Password)
+	if verifyErr != nil {
+		logger.Error("verify password", verifyErr)
+		return nil, rest_errors.NewInternalServerError("verify password error", verifyErr)
+	}
+	if !ok {
+		return nil, errInvalidCredentials()
+	}
+	if rehash {
+		s.rehashPassword(int64(result.ID), rq.Password)
+	}
+
 	u := models.User{
 		Id:          int64(result.ID),
 		FirstName:   result.FirstName.String,
@@ -149,6 +182,26 @@ func (s *UsersService) LoginUser(rq models.LoginRequest) (*models.User, rest_err
 	return &u, nil
 }
 
+// rehashPassword upgrades the stored password, the login succeeds when it fails
+func (s *UsersService) rehashPassword(userId int64, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		logger.Error("rehash password", err)
+		return
+	}
+	if err := s.userDao.UpdatePassword(gen.UpdatePasswordParams{
+		Password: nillableStr(hash),
+		ID:       int32(userId),
+	}); err != nil {
+		logger.Error("rehash password", err)
+	}
+}
+
+// errInvalidCredentials is the same for an unknown email and a wrong password
+func errInvalidCredentials() rest_errors.RestErr {
+	return rest_errors.NewNotFoundError("invalid user credentials")
+}
+
 func nillableStr(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
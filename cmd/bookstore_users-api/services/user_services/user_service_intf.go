@@ -10,6 +10,10 @@ type UserServiceIntf interface {
 	CreateUser(models.User) (*models.User, rest_errors.RestErr)
 	UpdateUser(bool, models.User) (*models.User, rest_errors.RestErr)
 	DeleteUser(int64) rest_errors.RestErr
-	SearchUsersByStatus(string) ([]models.User, rest_errors.RestErr)
+	SearchUsers(models.UserSearch) (*models.UserPage, rest_errors.RestErr)
 	LoginUser(models.LoginRequest) (*models.User, rest_errors.RestErr)
+	VerifyUser(string) rest_errors.RestErr
+	ResendVerification(string) rest_errors.RestErr
+	ForgotPassword(string) rest_errors.RestErr
+	ResetPassword(models.PasswordResetRequest) rest_errors.RestErr
 }
@@ -4,11 +4,15 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/user_dao"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/mailer"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/passwords"
 	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,10 +20,17 @@ import (
 type userDaoMock struct {
 	getFn     func(int64) (*gen.FindUserRow, rest_errors.RestErr)
 	saveFn    func(gen.InsertUserParams) (int64, rest_errors.RestErr)
+	replaceFn func(gen.InsertUserParams) (int64, rest_errors.RestErr)
 	updateFn  func(gen.UpdateUserParams) rest_errors.RestErr
 	deleteFn  func(int64) rest_errors.RestErr
 	findFn    func(string) ([]gen.FindByStatusRow, rest_errors.RestErr)
-	findGetFn func(gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr)
+	findGetFn func(gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr)
+	pswFn     func(gen.UpdatePasswordParams) rest_errors.RestErr
+	searchFn  func(user_dao.SearchParams) ([]user_dao.SearchRow, rest_errors.RestErr)
+	verifyFn  func(gen.InsertVerificationParams) rest_errors.RestErr
+	activeFn  func(string, time.Time) (int64, rest_errors.RestErr)
+	resetFn   func(gen.InsertPasswordResetParams) rest_errors.RestErr
+	consumeFn func(string, string, time.Time) (int64, rest_errors.RestErr)
 }
 
 func (m userDaoMock) Get(userId int64) (*gen.FindUserRow, rest_errors.RestErr) {
@@ -28,6 +39,9 @@ func (m userDaoMock) Get(userId int64) (*gen.FindUserRow, rest_errors.RestErr) {
 func (m userDaoMock) Save(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
 	return m.saveFn(p)
 }
+func (m userDaoMock) ReplacePendingUser(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+	return m.replaceFn(p)
+}
 func (m userDaoMock) Update(p gen.UpdateUserParams) rest_errors.RestErr {
 	return m.updateFn(p)
 }
@@ -37,16 +51,81 @@ func (m userDaoMock) Delete(userId int64) rest_errors.RestErr {
 func (m userDaoMock) FindByStatus(status string) ([]gen.FindByStatusRow, rest_errors.RestErr) {
 	return m.findFn(status)
 }
-func (m userDaoMock) FindByEmailAndPsw(p gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr) {
+func (m userDaoMock) FindByEmail(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
 	return m.findGetFn(p)
 }
+func (m userDaoMock) UpdatePassword(p gen.UpdatePasswordParams) rest_errors.RestErr {
+	return m.pswFn(p)
+}
+func (m userDaoMock) Search(p user_dao.SearchParams) ([]user_dao.SearchRow, rest_errors.RestErr) {
+	return m.searchFn(p)
+}
+func (m userDaoMock) CreateVerification(p gen.InsertVerificationParams) rest_errors.RestErr {
+	return m.verifyFn(p)
+}
+func (m userDaoMock) ActivateUser(tokenHash string, now time.Time) (int64, rest_errors.RestErr) {
+	return m.activeFn(tokenHash, now)
+}
+func (m userDaoMock) CreatePasswordReset(p gen.InsertPasswordResetParams) rest_errors.RestErr {
+	return m.resetFn(p)
+}
+func (m userDaoMock) ResetPassword(tokenHash string, password string, now time.Time) (int64, rest_errors.RestErr) {
+	return m.consumeFn(tokenHash, password, now)
+}
+
+type tokensMock struct {
+	revoked []int64
+	err     rest_errors.RestErr
+}
+
+func (m *tokensMock) RevokeUserTokens(userId int64) rest_errors.RestErr {
+	m.revoked = append(m.revoked, userId)
+	return m.err
+}
+
+type mailerMock struct {
+	messages []mailer.Message
+	err      error
+}
+
+func (m *mailerMock) Send(msg mailer.Message) error {
+	m.messages = append(m.messages, msg)
+	return m.err
+}
 
 // helpers
 
+// cheap parameters keep the tests fast
+var hasher, _ = passwords.NewHasher(passwords.Params{
+	Algorithm: passwords.Argon2id, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1,
+})
+
+var (
+	now          = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	verification = Verification{TTL: time.Hour, Link: "http://localhost:3000/verify?token="}
+	reset        = PasswordReset{TTL: 15 * time.Minute, Link: "http://localhost:3000/password/reset?token="}
+)
+
 func withMock(configFn func(*userDaoMock)) UserServiceIntf {
+	return withMailer(configFn, &mailerMock{})
+}
+
+func withMailer(configFn func(*userDaoMock), m mailer.Mailer) UserServiceIntf {
+	return withTokens(configFn, m, &tokensMock{})
+}
+
+func withTokens(configFn func(*userDaoMock), m mailer.Mailer, tokens *tokensMock) UserServiceIntf {
 	userDaoMock := new(userDaoMock)
 	configFn(userDaoMock)
-	return NewService(userDaoMock)
+	s := NewService(userDaoMock, hasher, m, verification, reset, tokens)
+	s.now = func() time.Time { return now }
+	s.async = func(f func()) { f() }
+	return s
+}
+
+func hashed(password string) sql.NullString {
+	hash, _ := hasher.Hash(password)
+	return nillableStr(hash)
 }
 
 // tests
@@ -87,17 +166,103 @@ func TestGetUserFailed(t *testing.T) {
 }
 
 func TestCreateUserOk(t *testing.T) {
-	usersService := withMock(func(mock *userDaoMock) {
+	var saved gen.InsertUserParams
+	var token gen.InsertVerificationParams
+	mails := &mailerMock{}
+	usersService := withMailer(func(mock *userDaoMock) {
 		mock.saveFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			saved = p
 			return int64(1), nil
 		}
-	})
+		mock.verifyFn = func(p gen.InsertVerificationParams) rest_errors.RestErr {
+			token = p
+			return nil
+		}
+	}, mails)
 
-	original := models.User{Email: "xxx@xxx.com", Password: "111"}
+	original := models.User{Email: " XXX@xxx.com ", Password: "111", Status: models.STATUS_ACTIVE}
 	created, err := usersService.CreateUser(original)
 
 	assert.Nil(t, err)
 	assert.EqualValues(t, created.Id, 1)
+	assert.Equal(t, "xxx@xxx.com", saved.Email, "login trims the email too")
+	assert.Empty(t, created.Password)
+	assert.EqualValues(t, models.STATUS_PENDING, created.Status)
+	assert.EqualValues(t, models.STATUS_PENDING, saved.Status.String)
+
+	ok, rehash, _ := hasher.Verify(saved.Password.String, "111")
+	assert.NotEqual(t, "111", saved.Password.String)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	assert.EqualValues(t, 1, token.UserID)
+	assert.Equal(t, now.Add(time.Hour), token.ExpiresAt)
+	assert.EqualValues(t, 1, len(mails.messages))
+	assert.Equal(t, "xxx@xxx.com", mails.messages[0].To)
+	link := strings.Fields(mails.messages[0].Body)[1]
+	assert.True(t, strings.HasPrefix(link, verification.Link))
+	assert.Equal(t, token.TokenHash, hashToken(strings.TrimPrefix(link, verification.Link)), "the hash is stored")
+}
+
+func TestCreateUserMailFailed(t *testing.T) {
+	usersService := withMailer(func(mock *userDaoMock) {
+		mock.saveFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			return int64(1), nil
+		}
+		mock.verifyFn = func(p gen.InsertVerificationParams) rest_errors.RestErr {
+			return nil
+		}
+	}, &mailerMock{err: errors.New("smtp error")})
+
+	created, err := usersService.CreateUser(models.User{Email: "xxx@xxx.com", Password: "111"})
+	assert.Nil(t, err, "the user asks for another mail")
+	assert.EqualValues(t, 1, created.Id)
+}
+
+func TestCreateUserReplacesPending(t *testing.T) {
+	var replaced gen.InsertUserParams
+	var token gen.InsertVerificationParams
+	mails := &mailerMock{}
+	usersService := withMailer(func(mock *userDaoMock) {
+		mock.saveFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			return -1, rest_errors.NewConflictError("user can not be registered with this email")
+		}
+		mock.replaceFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			replaced = p
+			return int64(7), nil
+		}
+		mock.verifyFn = func(p gen.InsertVerificationParams) rest_errors.RestErr {
+			token = p
+			return nil
+		}
+	}, mails)
+
+	created, err := usersService.CreateUser(models.User{Email: "xxx@xxx.com", Password: "222"})
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, 7, created.Id)
+	ok, _, _ := hasher.Verify(replaced.Password.String, "222")
+	assert.True(t, ok, "the new password replaces the pending one")
+	assert.EqualValues(t, 7, token.UserID, "a new token replaces the pending one")
+	assert.EqualValues(t, 1, len(mails.messages))
+}
+
+func TestCreateUserEmailTaken(t *testing.T) {
+	mails := &mailerMock{}
+	usersService := withMailer(func(mock *userDaoMock) {
+		mock.saveFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			return -1, rest_errors.NewConflictError("user can not be registered with this email")
+		}
+		mock.replaceFn = func(p gen.InsertUserParams) (int64, rest_errors.RestErr) {
+			return -1, rest_errors.NewConflictError("user can not be registered with this email")
+		}
+	}, mails)
+
+	created, err := usersService.CreateUser(models.User{Email: "xxx@xxx.com", Password: "222"})
+
+	assert.Nil(t, created)
+	assert.EqualValues(t, http.StatusConflict, err.Status())
+	assert.Empty(t, mails.messages)
 }
 
 func TestCreateUserFailedMandatoryFieldsRequired(t *testing.T) {
@@ -178,26 +343,101 @@ func TestDeleteFailed(t *testing.T) {
 }
 
 func TestSearchUserOk(t *testing.T) {
+	created := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	var searched []user_dao.SearchParams
 	usersService := withMock(func(mock *userDaoMock) {
-		mock.findFn = func(status string) ([]gen.FindByStatusRow, rest_errors.RestErr) {
-			return []gen.FindByStatusRow{{
-				ID: 1, FirstName: nillableStr("fname"),
-			}}, nil
+		mock.searchFn = func(p user_dao.SearchParams) ([]user_dao.SearchRow, rest_errors.RestErr) {
+			searched = append(searched, p)
+			rows := []user_dao.SearchRow{
+				{ID: 1, Email: "a@x.com", FirstName: nillableStr("fname"), DateCreated: created},
+				{ID: 2, Email: "b@x.com", DateCreated: created},
+				{ID: 3, Email: "c@x.com", DateCreated: created},
+			}
+			if p.AfterID > 0 {
+				return rows[2:], nil
+			}
+			return rows, nil
+		}
+	})
+
+	q := models.UserSearch{Status: models.STATUS_ACTIVE, EmailPrefix: " A_b ", Name: "100%",
+		CreatedFrom: "2021-06-01", CreatedTo: "2021-06-30", Sort: models.SortEmailDesc, Limit: 2}
+	page, err := usersService.SearchUsers(q)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, len(page.Users))
+	assert.EqualValues(t, "fname", page.Users[0].FirstName)
+	assert.NotEmpty(t, page.NextCursor)
+
+	assert.Equal(t, user_dao.SearchParams{
+		Status:       models.STATUS_ACTIVE,
+		EmailPattern: `a\_b%`,
+		NamePattern:  `%100\%%`,
+		CreatedFrom:  time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		CreatedTo:    time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		Sort:         models.SortEmailDesc,
+		Limit:        3,
+	}, searched[0])
+
+	q.Cursor = page.NextCursor
+	page, err = usersService.SearchUsers(q)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(page.Users))
+	assert.Empty(t, page.NextCursor, "last page")
+	assert.EqualValues(t, 2, searched[1].AfterID)
+	assert.EqualValues(t, "b@x.com", searched[1].AfterEmail)
+	assert.True(t, created.Equal(searched[1].AfterCreated))
+}
+
+func TestSearchUserDefaults(t *testing.T) {
+	var searched user_dao.SearchParams
+	usersService := withMock(func(mock *userDaoMock) {
+		mock.searchFn = func(p user_dao.SearchParams) ([]user_dao.SearchRow, rest_errors.RestErr) {
+			searched = p
+			return nil, nil
 		}
 	})
-	ls, err := usersService.SearchUsersByStatus(models.STATUS_ACTIVE)
+	page, err := usersService.SearchUsers(models.UserSearch{CreatedTo: "2021-06-30T10:00:00+02:00"})
 	assert.Nil(t, err)
-	assert.EqualValues(t, 1, len(ls))
+	assert.Empty(t, page.Users)
+	assert.Empty(t, page.NextCursor)
+	assert.EqualValues(t, models.SortCreated, searched.Sort)
+	assert.EqualValues(t, models.DefaultSearchLimit+1, searched.Limit)
+	assert.EqualValues(t, "%", searched.EmailPattern)
+	assert.EqualValues(t, time.Date(2021, 6, 30, 8, 0, 1, 0, time.UTC), searched.CreatedTo)
+}
+
+func TestSearchUserFailedValidation(t *testing.T) {
+	usersService := withMock(func(mock *userDaoMock) {})
+
+	otherSort := encodeCursor(searchCursor{Sort: models.SortEmail, ID: 1})
+	for _, q := range []models.UserSearch{
+		{Sort: "first_name"},
+		{Limit: -1},
+		{Limit: models.MaxSearchLimit + 1},
+		{CreatedFrom: "01/06/2021"},
+		{CreatedTo: "yesterday"},
+		{Cursor: "not a cursor"},
+		{Cursor: otherSort},
+	} {
+		page, err := usersService.SearchUsers(q)
+		assert.Nil(t, page)
+		assert.EqualValues(t, http.StatusBadRequest, err.Status(), q)
+	}
 }
 
 func TestLoginUserOk(t *testing.T) {
 	usersService := withMock(func(mock *userDaoMock) {
-		mock.findGetFn = func(p gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr) {
-			return gen.FindByEMailAndPswRow{
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			return gen.FindByEmailRow{
 				ID:        1,
 				FirstName: nillableStr("fname"),
+				Password:  hashed("111"),
 			}, nil
 		}
+		mock.pswFn = func(p gen.UpdatePasswordParams) rest_errors.RestErr {
+			t.Fatal("current hashes are kept")
+			return nil
+		}
 	})
 	lrq := models.LoginRequest{Email: "xxx@xxx.com", Password: "111"}
 	u, err := usersService.LoginUser(lrq)
@@ -205,10 +445,46 @@ func TestLoginUserOk(t *testing.T) {
 	assert.EqualValues(t, 1, u.Id)
 }
 
+func TestLoginUserRehash(t *testing.T) {
+	var updated gen.UpdatePasswordParams
+	usersService := withMock(func(mock *userDaoMock) {
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			return gen.FindByEmailRow{ID: 1, Password: nillableStr("111")}, nil
+		}
+		mock.pswFn = func(p gen.UpdatePasswordParams) rest_errors.RestErr {
+			updated = p
+			return rest_errors.NewInternalServerError("failed", errors.New("db error"))
+		}
+	})
+	lrq := models.LoginRequest{Email: "xxx@xxx.com", Password: "111"}
+	u, err := usersService.LoginUser(lrq)
+
+	assert.Nil(t, err, "a failed upgrade keeps the login")
+	assert.EqualValues(t, 1, u.Id)
+	assert.EqualValues(t, 1, updated.ID)
+	ok, rehash, _ := hasher.Verify(updated.Password.String, "111")
+	assert.True(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	usersService := withMock(func(mock *userDaoMock) {
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			return gen.FindByEmailRow{ID: 1, Password: hashed("111")}, nil
+		}
+	})
+	lrq := models.LoginRequest{Email: "xxx@xxx.com", Password: "222"}
+	u, err := usersService.LoginUser(lrq)
+
+	assert.Nil(t, u)
+	assert.EqualValues(t, http.StatusNotFound, err.Status())
+	assert.EqualValues(t, "invalid user credentials", err.Message())
+}
+
 func TestLoginNotFound(t *testing.T) {
 	usersService := withMock(func(mock *userDaoMock) {
-		mock.findGetFn = func(p gen.FindByEMailAndPswParams) (gen.FindByEMailAndPswRow, rest_errors.RestErr) {
-			return gen.FindByEMailAndPswRow{
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			return gen.FindByEmailRow{
 				ID: 0,
 			}, rest_errors.NewNotFoundError("not found")
 		}
@@ -219,3 +495,129 @@ func TestLoginNotFound(t *testing.T) {
 	assert.Nil(t, u)
 	assert.EqualValues(t, err.Status(), http.StatusNotFound)
 }
+
+func TestVerifyUserOk(t *testing.T) {
+	var consumed string
+	usersService := withMock(func(mock *userDaoMock) {
+		mock.activeFn = func(tokenHash string, at time.Time) (int64, rest_errors.RestErr) {
+			consumed = tokenHash
+			assert.Equal(t, now, at)
+			return 1, nil
+		}
+	})
+	assert.Nil(t, usersService.VerifyUser(" token "))
+	assert.Equal(t, hashToken("token"), consumed)
+}
+
+func TestVerifyUserFailed(t *testing.T) {
+	usersService := withMock(func(mock *userDaoMock) {
+		mock.activeFn = func(tokenHash string, at time.Time) (int64, rest_errors.RestErr) {
+			return 0, rest_errors.NewBadRequestError("invalid or expired verification token")
+		}
+	})
+	assert.EqualValues(t, http.StatusBadRequest, usersService.VerifyUser("").Status())
+	assert.EqualValues(t, http.StatusBadRequest, usersService.VerifyUser("used").Status())
+}
+
+func TestResendVerification(t *testing.T) {
+	mails := &mailerMock{}
+	var replaced int
+	usersService := withMailer(func(mock *userDaoMock) {
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			assert.EqualValues(t, models.STATUS_PENDING, p.Status.String)
+			if p.Email != "pending@x.com" {
+				return gen.FindByEmailRow{}, rest_errors.NewNotFoundError("user not found")
+			}
+			return gen.FindByEmailRow{ID: 2, Email: p.Email}, nil
+		}
+		mock.verifyFn = func(p gen.InsertVerificationParams) rest_errors.RestErr {
+			replaced++
+			return nil
+		}
+	}, mails)
+
+	assert.Nil(t, usersService.ResendVerification("unknown@x.com"))
+	assert.Empty(t, mails.messages)
+
+	assert.Nil(t, usersService.ResendVerification(" Pending@x.com"))
+	assert.EqualValues(t, 1, replaced)
+	assert.EqualValues(t, 1, len(mails.messages))
+}
+
+func TestForgotPassword(t *testing.T) {
+	mails := &mailerMock{}
+	var token gen.InsertPasswordResetParams
+	usersService := withMailer(func(mock *userDaoMock) {
+		mock.findGetFn = func(p gen.FindByEmailParams) (gen.FindByEmailRow, rest_errors.RestErr) {
+			assert.EqualValues(t, models.STATUS_ACTIVE, p.Status.String)
+			if p.Email != "jo@x.com" {
+				return gen.FindByEmailRow{}, rest_errors.NewNotFoundError("user not found")
+			}
+			return gen.FindByEmailRow{ID: 3, Email: p.Email}, nil
+		}
+		mock.resetFn = func(p gen.InsertPasswordResetParams) rest_errors.RestErr {
+			token = p
+			return nil
+		}
+	}, mails)
+
+	assert.Nil(t, usersService.ForgotPassword("unknown@x.com"))
+	assert.Empty(t, mails.messages)
+
+	assert.Nil(t, usersService.ForgotPassword(" Jo@x.com"))
+	assert.EqualValues(t, 3, token.UserID)
+	assert.Equal(t, now.Add(15*time.Minute), token.ExpiresAt)
+	assert.EqualValues(t, 1, len(mails.messages))
+	assert.Equal(t, "jo@x.com", mails.messages[0].To)
+	link := strings.Fields(mails.messages[0].Body)[1]
+	assert.True(t, strings.HasPrefix(link, reset.Link))
+	assert.Equal(t, token.TokenHash, hashToken(strings.TrimPrefix(link, reset.Link)), "the hash is stored")
+}
+
+func TestResetPasswordOk(t *testing.T) {
+	var consumed, stored string
+	tokens := &tokensMock{}
+	usersService := withTokens(func(mock *userDaoMock) {
+		mock.consumeFn = func(tokenHash string, password string, at time.Time) (int64, rest_errors.RestErr) {
+			consumed, stored = tokenHash, password
+			assert.Equal(t, now, at)
+			return 3, nil
+		}
+	}, &mailerMock{}, tokens)
+
+	err := usersService.ResetPassword(models.PasswordResetRequest{Token: "token", Password: " new "})
+	assert.Nil(t, err)
+	assert.Equal(t, hashToken("token"), consumed)
+	ok, _, _ := hasher.Verify(stored, "new")
+	assert.True(t, ok)
+	assert.Equal(t, []int64{3}, tokens.revoked)
+}
+
+func TestResetPasswordInvalid(t *testing.T) {
+	tokens := &tokensMock{}
+	usersService := withTokens(func(mock *userDaoMock) {
+		mock.consumeFn = func(tokenHash string, password string, at time.Time) (int64, rest_errors.RestErr) {
+			return 0, rest_errors.NewBadRequestError("invalid or expired password reset token")
+		}
+	}, &mailerMock{}, tokens)
+
+	err := usersService.ResetPassword(models.PasswordResetRequest{Token: " ", Password: "new"})
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	err = usersService.ResetPassword(models.PasswordResetRequest{Token: "token", Password: ""})
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	err = usersService.ResetPassword(models.PasswordResetRequest{Token: "used", Password: "new"})
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	assert.Empty(t, tokens.revoked)
+}
+
+func TestResetPasswordRevokeFailed(t *testing.T) {
+	tokens := &tokensMock{err: rest_errors.NewInternalServerError("timeout error", errors.New("oauth down"))}
+	usersService := withTokens(func(mock *userDaoMock) {
+		mock.consumeFn = func(tokenHash string, password string, at time.Time) (int64, rest_errors.RestErr) {
+			return 3, nil
+		}
+	}, &mailerMock{}, tokens)
+
+	err := usersService.ResetPassword(models.PasswordResetRequest{Token: "token", Password: "new"})
+	assert.EqualValues(t, http.StatusInternalServerError, err.Status())
+}
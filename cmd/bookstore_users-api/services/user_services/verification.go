@@ -0,0 +1,93 @@
+package user_services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/mailer"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/logger"
+	"github.com/chernyshev-alex/bookstore/pkg/bookstore_utils_go/rest_errors"
+)
+
+// Verification configures the email verification, Link is followed by the token in the mail
+type Verification struct {
+	TTL  time.Duration
+	Link string
+}
+
+// VerifyUser activates the user of a verification token, a token is used once
+func (s *UsersService) VerifyUser(token string) rest_errors.RestErr {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return rest_errors.NewBadRequestError("empty verification token")
+	}
+	_, err := s.userDao.ActivateUser(hashToken(token), s.now())
+	return err
+}
+
+// ResendVerification replaces the token of a pending user and mails it again.
+// The answer is the same for unknown, active and pending emails, the mail is sent in the background
+func (s *UsersService) ResendVerification(email string) rest_errors.RestErr {
+	result, err := s.userDao.FindByEmail(gen.FindByEmailParams{
+		Email:  strings.ToLower(strings.TrimSpace(email)),
+		Status: nillableStr(models.STATUS_PENDING),
+	})
+	if err != nil {
+		if err.Status() == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	s.async(func() { s.sendVerification(int64(result.ID), result.Email) })
+	return nil
+}
+
+// sendVerification stores a new token and mails it, failures are logged: the user asks for another one
+func (s *UsersService) sendVerification(userId int64, email string) {
+	token, hash, err := newToken()
+	if err != nil {
+		logger.Error("verification token", err)
+		return
+	}
+	expires := s.now().Add(s.verification.TTL)
+	if err := s.userDao.CreateVerification(gen.InsertVerificationParams{
+		TokenHash: hash,
+		UserID:    int32(userId),
+		ExpiresAt: expires,
+	}); err != nil {
+		return
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your bookstore account",
+		Body: fmt.Sprintf("Open %s%s to activate your account.\r\nThe link expires at %s.",
+			s.verification.Link, token, expires.UTC().Format(time.RFC1123)),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		logger.Error("send verification mail", err)
+	}
+}
+
+// newToken returns a random url safe token and the hash stored in its place
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,3 @@
+-- hashed passwords are longer than 20 characters, they are cleared to fit, those users have to reset the password
+UPDATE `users` SET `password` = NULL WHERE CHAR_LENGTH(`password`) > 20;
+ALTER TABLE `users` MODIFY `password` varchar(20) DEFAULT NULL;
@@ -0,0 +1,2 @@
+-- argon2id and bcrypt hashes do not fit varchar(20)
+ALTER TABLE `users` MODIFY `password` varchar(255) DEFAULT NULL;
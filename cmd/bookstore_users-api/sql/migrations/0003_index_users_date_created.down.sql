@@ -0,0 +1 @@
+DROP INDEX `date_created_id` ON `users`;
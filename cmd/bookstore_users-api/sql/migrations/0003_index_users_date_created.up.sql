@@ -0,0 +1,2 @@
+-- keyset pages of the user search ordered by creation
+CREATE INDEX `date_created_id` ON `users` (`date_created`, `id`);
@@ -0,0 +1,9 @@
+-- single use tokens of the email verification, the token is kept as its sha-256
+CREATE TABLE `user_verifications` (
+  `token_hash` char(64) NOT NULL,
+  `user_id` int NOT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`token_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `user_verifications_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,290 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are numbered sql files applied in order, NNNN_name.up.sql changes the schema
+// and NNNN_name.down.sql reverts it. A statement ends with a semicolon at the end of a line.
+// Applied versions are kept in schema_migrations, a named lock serializes concurrent instances
+
+//go:embed *.sql
+var files embed.FS
+
+const (
+	lockName    = "bookstore_users_api.schema_migrations"
+	lockTimeout = 60 // seconds
+
+	createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  dirty tinyint(1) NOT NULL DEFAULT 0,
+  applied_at datetime NOT NULL,
+  PRIMARY KEY (version)
+)`
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Embedded returns the migrations shipped with the binary
+func Embedded() ([]Migration, error) {
+	return Load(files)
+}
+
+// Load reads the migrations of a directory ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		match := fileName.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("migration %s : expected NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names : %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	ls := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up", m.Version, m.Name)
+		}
+		ls = append(ls, *m)
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Version < ls[j].Version })
+	return ls, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up applies the pending migrations in order and returns them.
+// A failed migration stays dirty, the schema is fixed by hand and its schema_migrations row deleted
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkClean(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, 1, ?)",
+				migration.Version, migration.Name, time.Now().UTC()); err != nil {
+				return err
+			}
+			if err := run(ctx, conn, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s up : %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"UPDATE schema_migrations SET dirty=0 WHERE version=?", migration.Version); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, the latest first, and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkClean(applied); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down", migration.Version, migration.Name)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"UPDATE schema_migrations SET dirty=1 WHERE version=?", migration.Version); err != nil {
+				return err
+			}
+			if err := run(ctx, conn, migration.Down); err != nil {
+				return fmt.Errorf("migration %d_%s down : %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"DELETE FROM schema_migrations WHERE version=?", migration.Version); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists the known migrations with their state in the database
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var ls []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			st := Status{Migration: migration}
+			if a, ok := applied[migration.Version]; ok {
+				st.Applied, st.Dirty, st.AppliedAt = true, a.Dirty, a.AppliedAt
+			}
+			ls = append(ls, st)
+		}
+		return nil
+	})
+	return ls, err
+}
+
+// withLock runs fn on one connection holding the migrations lock, the lock belongs to the session
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("migrations lock %s is not acquired in %ds", lockName, lockTimeout)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]Status, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]Status{}
+	for rows.Next() {
+		var st Status
+		if err := rows.Scan(&st.Version, &st.Name, &st.Dirty, &st.AppliedAt); err != nil {
+			return nil, err
+		}
+		st.Applied = true
+		applied[st.Version] = st
+	}
+	return applied, rows.Err()
+}
+
+func checkClean(applied map[int64]Status) error {
+	for _, st := range applied {
+		if st.Dirty {
+			return fmt.Errorf("migration %d_%s is dirty, fix the schema and delete its schema_migrations row",
+				st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+func run(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range statements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statements splits a script on the semicolons ending a line, comment lines between statements are dropped
+func statements(script string) []string {
+	var ls []string
+	var stmt strings.Builder
+	flush := func() {
+		if s := strings.TrimSuffix(strings.TrimSpace(stmt.String()), ";"); s != "" {
+			ls = append(ls, s)
+		}
+		stmt.Reset()
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if stmt.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return ls
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	ls, err := Load(fstest.MapFS{
+		"0010_add_index.up.sql":    {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0002_create_t.up.sql":     {Data: []byte("CREATE TABLE t (c int);")},
+		"0002_create_t.down.sql":   {Data: []byte("DROP TABLE t;")},
+		"0010_add_index.down.sql":  {Data: []byte("DROP INDEX i ON t;")},
+		"0011_without_down.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 2, Name: "create_t", Up: "CREATE TABLE t (c int);", Down: "DROP TABLE t;"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX i ON t (c);", Down: "DROP INDEX i ON t;"},
+		{Version: 11, Name: "without_down", Up: "SELECT 1;"},
+	}, ls)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := Load(fstest.MapFS{"create_t.sql": {Data: []byte("CREATE TABLE t (c int);")}})
+	assert.NotNil(t, err, "unnumbered")
+
+	_, err = Load(fstest.MapFS{"0001_create_t.down.sql": {Data: []byte("DROP TABLE t;")}})
+	assert.NotNil(t, err, "without up")
+
+	_, err = Load(fstest.MapFS{
+		"0001_create_t.up.sql": {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_create_u.up.sql": {Data: []byte("CREATE TABLE u (c int);")},
+	})
+	assert.NotNil(t, err, "same version")
+}
+
+func TestEmbedded(t *testing.T) {
+	ls, err := Embedded()
+	assert.Nil(t, err)
+	assert.True(t, len(ls) > 0)
+	for i, m := range ls {
+		assert.NotEmpty(t, m.Down, m.Name)
+		if i > 0 {
+			assert.True(t, ls[i-1].Version < m.Version)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- widen
+UPDATE users SET password = NULL
+  WHERE CHAR_LENGTH(password) > 20;
+
+-- then
+ALTER TABLE users MODIFY password varchar(20) DEFAULT 'a;b';
+SELECT 1`
+
+	assert.Equal(t, []string{
+		"UPDATE users SET password = NULL\n  WHERE CHAR_LENGTH(password) > 20",
+		"ALTER TABLE users MODIFY password varchar(20) DEFAULT 'a;b'",
+		"SELECT 1",
+	}, statements(script))
+}
@@ -14,5 +14,78 @@ DELETE FROM users WHERE id=?;
 -- name: FindByStatus :many
 SELECT id, first_name,last_name,email,date_created, status FROM users WHERE status=?;
 
--- name: FindByEMailAndPsw :one
-SELECT id, first_name,last_name,email,date_created, status FROM users WHERE email=? and password=? and status=?;
\ No newline at end of file
+-- name: FindByEmail :one
+SELECT id, first_name,last_name,email,date_created, status, password FROM users WHERE email=? and status=?;
+
+-- name: UpdatePassword :execresult
+UPDATE users SET password=? WHERE id=?;
+
+-- name: ReplacePendingUser :execresult
+UPDATE users SET first_name=?, last_name=?, date_created=?, password=? WHERE id=? AND status=?;
+
+-- name: SearchUsersByCreated :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (sqlc.arg(status) = '' OR status = sqlc.arg(status))
+  AND email LIKE sqlc.arg(email_pattern)
+  AND CONCAT_WS(' ', first_name, last_name) LIKE sqlc.arg(name_pattern)
+  AND date_created >= sqlc.arg(created_from) AND date_created < sqlc.arg(created_to)
+  AND (sqlc.arg(after_id) = 0 OR date_created > sqlc.arg(after_created) OR (date_created = sqlc.arg(after_created) AND id > sqlc.arg(after_id)))
+ORDER BY date_created, id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SearchUsersByCreatedDesc :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (sqlc.arg(status) = '' OR status = sqlc.arg(status))
+  AND email LIKE sqlc.arg(email_pattern)
+  AND CONCAT_WS(' ', first_name, last_name) LIKE sqlc.arg(name_pattern)
+  AND date_created >= sqlc.arg(created_from) AND date_created < sqlc.arg(created_to)
+  AND (sqlc.arg(after_id) = 0 OR date_created < sqlc.arg(after_created) OR (date_created = sqlc.arg(after_created) AND id < sqlc.arg(after_id)))
+ORDER BY date_created DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: SearchUsersByEmail :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (sqlc.arg(status) = '' OR status = sqlc.arg(status))
+  AND email LIKE sqlc.arg(email_pattern)
+  AND CONCAT_WS(' ', first_name, last_name) LIKE sqlc.arg(name_pattern)
+  AND date_created >= sqlc.arg(created_from) AND date_created < sqlc.arg(created_to)
+  AND (sqlc.arg(after_id) = 0 OR email > sqlc.arg(after_email) OR (email = sqlc.arg(after_email) AND id > sqlc.arg(after_id)))
+ORDER BY email, id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SearchUsersByEmailDesc :many
+SELECT id, first_name, last_name, email, date_created, status FROM users
+WHERE (sqlc.arg(status) = '' OR status = sqlc.arg(status))
+  AND email LIKE sqlc.arg(email_pattern)
+  AND CONCAT_WS(' ', first_name, last_name) LIKE sqlc.arg(name_pattern)
+  AND date_created >= sqlc.arg(created_from) AND date_created < sqlc.arg(created_to)
+  AND (sqlc.arg(after_id) = 0 OR email < sqlc.arg(after_email) OR (email = sqlc.arg(after_email) AND id < sqlc.arg(after_id)))
+ORDER BY email DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpdateStatus :execresult
+UPDATE users SET status=? WHERE id=?;
+
+-- name: InsertVerification :execresult
+INSERT INTO user_verifications (token_hash, user_id, expires_at) VALUES (?, ?, ?);
+
+-- name: DeleteUserVerifications :execresult
+DELETE FROM user_verifications WHERE user_id=?;
+
+-- name: FindVerification :one
+SELECT token_hash, user_id, expires_at FROM user_verifications WHERE token_hash=? FOR UPDATE;
+
+-- name: DeleteVerification :execresult
+DELETE FROM user_verifications WHERE token_hash=?;
+
+-- name: InsertPasswordReset :execresult
+INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, ?, ?);
+
+-- name: DeleteUserPasswordResets :execresult
+DELETE FROM password_resets WHERE user_id=?;
+
+-- name: FindPasswordReset :one
+SELECT token_hash, user_id, expires_at FROM password_resets WHERE token_hash=? FOR UPDATE;
+
+-- name: DeletePasswordReset :execresult
+DELETE FROM password_resets WHERE token_hash=?;
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -11,6 +12,8 @@ import (
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/mysql/gen"
 	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/dao/user_dao"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/models"
+	"github.com/chernyshev-alex/bookstore/cmd/bookstore_users_api/sql/migrations"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,7 +23,7 @@ const (
 
 var (
 	db *sql.DB
-	dq user_dao.UserDao
+	dq user_dao.UserDaoIntf
 )
 
 func TestMain(m *testing.M) {
@@ -38,13 +41,22 @@ func setupTest() {
 	mysqlConf := mysql.MakeConfig(conf)
 	db = mysql.NewSqlClient(mysqlConf)
 	dq = mysql.NewUserDao(db)
+
+	ls, err := migrations.Embedded()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := migrations.NewMigrator(db, ls).Up(context.Background()); err != nil {
+		panic(err)
+	}
 }
 
 func cleanUpDB() {
 	if db == nil {
 		return
 	}
-	stmt, err := db.Prepare("truncate table users_db.users;")
+	// the foreign keys to users prevent a truncate
+	stmt, err := db.Prepare("delete from users_db.users;")
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -77,16 +89,30 @@ func TestDaoSQLC(t *testing.T) {
 	}
 	assert.NotNil(t, err, "same Email isn't allowed")
 
-	findByEMailAndPswParams := gen.FindByEMailAndPswParams{
-		Email:    u.Email,
-		Password: u.Password,
-		Status:   u.Status,
+	findByEmailParams := gen.FindByEmailParams{
+		Email:  u.Email,
+		Status: u.Status,
 	}
-	findResult, err := dq.FindByEmailAndPsw(findByEMailAndPswParams)
+	findResult, err := dq.FindByEmail(findByEmailParams)
 	if err != nil {
 		t.Fatal(err)
 	}
 	assert.EqualValues(t, u.FirstName, findResult.FirstName)
+	assert.EqualValues(t, u.Password, findResult.Password)
+
+	err = dq.UpdatePassword(gen.UpdatePasswordParams{Password: nillableStr("rehashed_psw"), ID: int32(userId)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findResult, err = dq.FindByEmail(findByEmailParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, "rehashed_psw", findResult.Password.String)
+
+	findByEmailParams.Email = "unknown@domain.com"
+	_, err = dq.FindByEmail(findByEmailParams)
+	assert.EqualValues(t, 404, err.Status())
 
 	users, err := dq.FindByStatus(u.Status.String)
 	if err != nil {
@@ -94,6 +120,43 @@ func TestDaoSQLC(t *testing.T) {
 	}
 	assert.True(t, len(users) == 1)
 
+	found, err := dq.Search(user_dao.SearchParams{
+		Status:       u.Status.String,
+		EmailPattern: "email@%",
+		NamePattern:  "%name%",
+		CreatedFrom:  u.DateCreated.Add(-time.Hour),
+		CreatedTo:    u.DateCreated.Add(time.Hour),
+		Sort:         models.SortEmailDesc,
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, len(found) == 1)
+
+	err = dq.CreateVerification(gen.InsertVerificationParams{
+		TokenHash: "hash", UserID: int32(userId), ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	activated, err := dq.ActivateUser("hash", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, userId, activated)
+	_, err = dq.ActivateUser("hash", time.Now())
+	assert.EqualValues(t, 400, err.Status(), "single use")
+
+	err = dq.CreatePasswordReset(gen.InsertPasswordResetParams{
+		TokenHash: "reset", UserID: int32(userId), ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dq.ResetPassword("reset", "hash", time.Now())
+	assert.EqualValues(t, 400, err.Status(), "expired")
+
 	u.FirstName = nillableStr("changed")
 	updateParams := gen.UpdateUserParams{
 		FirstName: u.FirstName,
@@ -125,3 +188,22 @@ func TestDaoSQLC(t *testing.T) {
 func nillableStr(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
+
+func TestMigrations(t *testing.T) {
+	ls, err := migrations.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrations.NewMigrator(db, ls)
+
+	applied, err := migrator.Up(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, applied, "applied by TestMain")
+
+	status, err := migrator.Status(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(ls), len(status))
+	for _, st := range status {
+		assert.True(t, st.Applied && !st.Dirty, st.Name)
+	}
+}
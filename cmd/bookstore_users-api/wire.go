@@ -24,12 +24,19 @@ func inject(conf *conf.Config) app.Application {
 
 		app.NewOAuthClient,
 		wire.Bind(new(oauth.OAuthInterface), new(*oauth.OAuthClient)),
+		app.NewTokenRevoker,
+		wire.Bind(new(oauth.TokenRevoker), new(*oauth.TokenRevokerClient)),
 
 		user_services.NewService,
+		app.NewPasswordHasher,
+		app.NewMailer,
+		app.NewVerification,
+		app.NewPasswordReset,
 
 		mysql.NewUserDao,
 
 		wire.Bind(new(oauth.HttpClientInterface), new(*http.Client)),
+		wire.Bind(new(oauth.HttpDoInterface), new(*http.Client)),
 		mysql.MakeConfig,
 		mysql.NewSqlClient,
 
@@ -21,11 +21,16 @@ func inject(conf2 *conf.Config) app.Application {
 	config := mysql.MakeConfig(conf2)
 	db := mysql.NewSqlClient(config)
 	userDao := mysql.NewUserDao(db)
-	userService := user_services.NewService(userDao)
+	hasher := app.NewPasswordHasher(conf2)
+	mailerMailer := app.NewMailer(conf2)
+	verification := app.NewVerification(conf2)
+	passwordReset := app.NewPasswordReset(conf2)
 	client := _wireClientValue
+	tokenRevokerClient := app.NewTokenRevoker(client, conf2)
+	userService := user_services.NewService(userDao, hasher, mailerMailer, verification, passwordReset, tokenRevokerClient)
 	oAuthClient := app.NewOAuthClient(client, conf2)
 	userController := controllers.ProvideUserController(userService, oAuthClient)
-	application := app.ProvideApp(conf2, pingController, userController)
+	application := app.ProvideApp(conf2, pingController, userController, db)
 	return application
 }
 
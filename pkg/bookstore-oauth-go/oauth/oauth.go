@@ -16,6 +16,8 @@ const (
 	headerXClientId = "X-Client-Id"
 	headerXCallerId = "X-Caller-Id"
 
+	headerXInternalSecret = "X-Internal-Secret"
+
 	paramAccessToken = "access_token"
 )
 
@@ -33,6 +35,16 @@ type OAuthInterface interface {
 type HttpClientInterface interface {
 	Get(url string) (resp *http.Response, err error)
 }
+
+// HttpDoInterface sends the requests of the internal endpoints
+type HttpDoInterface interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// TokenRevoker ends the sessions of a user, after a password change
+type TokenRevoker interface {
+	RevokeUserTokens(userId int64) rest_errors.RestErr
+}
 type OAuthClient struct {
 	baseURL    string
 	httpClient HttpClientInterface
@@ -42,6 +54,17 @@ func NewAuthClient(httpClient HttpClientInterface, baseURL string) *OAuthClient
 	return &OAuthClient{httpClient: httpClient, baseURL: baseURL}
 }
 
+// TokenRevokerClient calls the internal endpoints of the oauth api with their shared secret
+type TokenRevokerClient struct {
+	baseURL    string
+	httpClient HttpDoInterface
+	secret     string
+}
+
+func NewTokenRevoker(httpClient HttpDoInterface, baseURL string, secret string) *TokenRevokerClient {
+	return &TokenRevokerClient{httpClient: httpClient, baseURL: baseURL, secret: secret}
+}
+
 func (oa OAuthClient) IsPublic(req *http.Request) bool {
 	if req == nil {
 		return true
@@ -133,6 +156,27 @@ func (oa OAuthClient) GetAccessToken(accessTokenId string) (*accessToken, rest_e
 	return &token, nil
 }
 
+// RevokeUserTokens deletes all the access tokens of the user
+func (tr TokenRevokerClient) RevokeUserTokens(userId int64) rest_errors.RestErr {
+	req, err := http.NewRequest(http.MethodDelete,
+		makeURL(tr.baseURL, "oauth/users", strconv.FormatInt(userId, 10), "access_tokens"), nil)
+	if err != nil {
+		return rest_errors.NewInternalServerError("revoke request failed", err)
+	}
+	req.Header.Set(headerXInternalSecret, tr.secret)
+	resp, err := tr.httpClient.Do(req)
+	if err != nil {
+		return rest_errors.NewInternalServerError("timeout error", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return rest_errors.NewInternalServerError("revoke tokens failed",
+			fmt.Errorf("oauth api status %d", resp.StatusCode))
+	}
+	return nil
+}
+
 func makeURL(base_url string, parts ...string) string {
 	var sb strings.Builder
 	sb.Grow(255)
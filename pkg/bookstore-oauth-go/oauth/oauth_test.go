@@ -26,6 +26,12 @@ func (m MockHttpClient) Get(url string) (resp *http.Response, err error) {
 	return m.getFn(url)
 }
 
+type MockHttpDo func(req *http.Request) (*http.Response, error)
+
+func (m MockHttpDo) Do(req *http.Request) (*http.Response, error) {
+	return m(req)
+}
+
 type OAuthTestSuite struct {
 	suite.Suite
 	httpClient  *MockHttpClient
@@ -38,13 +44,14 @@ func TestOAuthTestSuite(t *testing.T) {
 
 func (s *OAuthTestSuite) SetupTest() {
 	s.httpClient = new(MockHttpClient)
-	s.oauthClient = ProvideOAuthClient(s.httpClient, "")
+	s.oauthClient = NewAuthClient(s.httpClient, "")
 }
 
 func (s *OAuthTestSuite) TestOauthConstants() {
 	assert.EqualValues(s.T(), "X-Public", headerXPublic)
 	assert.EqualValues(s.T(), "X-Client-Id", headerXClientId)
 	assert.EqualValues(s.T(), "X-Caller-Id", headerXCallerId)
+	assert.EqualValues(s.T(), "X-Internal-Secret", headerXInternalSecret)
 	assert.EqualValues(s.T(), "access_token", paramAccessToken)
 }
 
@@ -209,6 +216,37 @@ func (s *OAuthTestSuite) TestGetAccessTokenBadBodyResponse() {
 	assert.EqualValues(s.T(), http.StatusInternalServerError, err.Status())
 }
 
+func (s *OAuthTestSuite) TestRevokeUserTokensOk() {
+	client := NewTokenRevoker(MockHttpDo(func(req *http.Request) (*http.Response, error) {
+		assert.EqualValues(s.T(), http.MethodDelete, req.Method)
+		assert.EqualValues(s.T(), "http://oauth/oauth/users/7/access_tokens", req.URL.String())
+		assert.EqualValues(s.T(), "s3cret", req.Header.Get("X-Internal-Secret"))
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}), "http://oauth", "s3cret")
+
+	assert.Nil(s.T(), client.RevokeUserTokens(7))
+}
+
+func (s *OAuthTestSuite) TestRevokeUserTokensFailed() {
+	client := NewTokenRevoker(MockHttpDo(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}), "http://oauth", "s3cret")
+	err := client.RevokeUserTokens(7)
+	assert.NotNil(s.T(), err)
+	assert.EqualValues(s.T(), http.StatusInternalServerError, err.Status())
+
+	client = NewTokenRevoker(MockHttpDo(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("bad response")
+	}), "http://oauth", "s3cret")
+	assert.NotNil(s.T(), client.RevokeUserTokens(7))
+}
+
 // helpers
 
 func serialize(token accessToken) string {
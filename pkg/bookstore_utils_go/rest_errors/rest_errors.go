@@ -54,6 +54,16 @@ func NewBadRequestError(msg string) RestErr {
 	}
 }
 
+// NewValidationError is a bad request listing every rule the request breaks as causes
+func NewValidationError(msg string, causes []interface{}) RestErr {
+	return restErr{
+		FMessage: msg,
+		FStatus:  http.StatusBadRequest,
+		FError:   "bad request",
+		FCauses:  causes,
+	}
+}
+
 func NewAuthorizationError(msg string) RestErr {
 	return restErr{
 		FMessage: msg,
@@ -62,6 +72,14 @@ func NewAuthorizationError(msg string) RestErr {
 	}
 }
 
+func NewForbiddenError(msg string) RestErr {
+	return restErr{
+		FMessage: msg,
+		FStatus:  http.StatusForbidden,
+		FError:   "forbidden",
+	}
+}
+
 func NewNotFoundError(msg string) RestErr {
 	return restErr{
 		FMessage: msg,
@@ -70,6 +88,23 @@ func NewNotFoundError(msg string) RestErr {
 	}
 }
 
+func NewConflictError(msg string) RestErr {
+	return restErr{
+		FMessage: msg,
+		FStatus:  http.StatusConflict,
+		FError:   "conflict",
+	}
+}
+
+// NewPreconditionFailedError reports a conditional write against a stale revision
+func NewPreconditionFailedError(msg string) RestErr {
+	return restErr{
+		FMessage: msg,
+		FStatus:  http.StatusPreconditionFailed,
+		FError:   "precondition failed",
+	}
+}
+
 func NewInternalServerError(msg string, err error) RestErr {
 	return restErr{
 		FMessage: msg,
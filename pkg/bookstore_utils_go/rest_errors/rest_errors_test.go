@@ -21,8 +21,34 @@ func TestNewBadRequestError(t *testing.T) {
 	assert.EqualValues(t, http.StatusBadRequest, err.Status())
 }
 
+func TestNewValidationError(t *testing.T) {
+	err := NewValidationError("msg", []interface{}{"first", "second"})
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, err.Status())
+	assert.EqualValues(t, 2, len(err.Causes()))
+}
+
 func TestNewNotFoundError(t *testing.T) {
 	err := NewNotFoundError("msg")
 	assert.NotNil(t, err)
 	assert.EqualValues(t, http.StatusNotFound, err.Status())
 }
+
+func TestNewForbiddenError(t *testing.T) {
+	err := NewForbiddenError("msg")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusForbidden, err.Status())
+}
+
+func TestNewConflictError(t *testing.T) {
+	err := NewConflictError("msg")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusConflict, err.Status())
+}
+
+func TestNewPreconditionFailedError(t *testing.T) {
+	err := NewPreconditionFailedError("msg")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusPreconditionFailed, err.Status())
+	assert.EqualValues(t, "precondition failed", err.(restErr).FError)
+}